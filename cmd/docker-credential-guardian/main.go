@@ -0,0 +1,31 @@
+// docker-credential-guardian is the binary docker looks for when configured with
+// "credsStore": "guardian". It is equivalent to guardian docker-credential
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/dockercredential"
+	"github.com/RogueTeam/guardian/credentials"
+)
+
+func main() {
+	root := *dockercredential.DockerCredentialCommand
+	root.Name = "docker-credential-guardian"
+
+	result, err := root.Run(os.Args[1:])
+	if err != nil {
+		// Docker reads errors from stdout and matches the missing credentials message exactly
+		if errors.Is(err, credentials.ErrCredentialsNotFound) {
+			err = credentials.ErrCredentialsNotFound
+		}
+		fmt.Fprintln(os.Stdout, err)
+		os.Exit(1)
+	}
+
+	if result != nil {
+		fmt.Fprint(os.Stdout, result)
+	}
+}
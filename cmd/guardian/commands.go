@@ -1,16 +1,77 @@
 package main
 
 import (
+	"github.com/RogueTeam/guardian/cmd/guardian/config"
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/dockercredential"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/exports"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/files"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/gitcredential"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/identity"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/imports"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/keyfile"
 	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/mount"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/recipients"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/recovery"
 	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/secrets"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/signers"
+	"github.com/RogueTeam/guardian/cmd/guardian/subcommands/ssh"
 	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
 )
 
+// Format of the results and errors, set by the setup of root
+var output = cli.OutputText
+
 var root = commands.Command{
 	Name:        "guardian",
 	Description: "Your portable personal file guardian",
+	Flags: commands.Values{
+		{Type: commands.TypeEnum, Name: cliflags.Output, Short: 'o', Description: "Output format, text by default", Choices: []string{cli.OutputText, cli.OutputJSON, cli.OutputYAML, cli.OutputTable}},
+		{Type: commands.TypeString, Name: cliflags.Profile, Short: 'p', Description: "Profile of the config file, its default profile when empty"},
+		{Type: commands.TypeString, Name: cliflags.Config, Description: "Config file with the profiles", Default: config.DefaultPath()},
+	},
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		cfg, err := config.Load(flags[cliflags.Config].(string))
+		if err != nil {
+			return
+		}
+		name, _ := flags[cliflags.Profile].(string)
+		profile, err := cfg.Select(name)
+		if err != nil {
+			return
+		}
+		// Added after the environment, it only overrides the defaults
+		ctx.AddSource(profile.Source())
+
+		format, found := flags[cliflags.Output].(string)
+		if !found {
+			format = profile.Output
+		}
+		if format != "" {
+			output, err = cli.ParseOutput(format)
+			if err != nil {
+				output = cli.OutputText
+				return
+			}
+		}
+		ctx.Set(cliflags.Output, output)
+		return
+	},
 	SubCommands: commands.Commands{
 		secrets.SecretsCommand,
 		mount.MountCommand,
+		ssh.SSHCommand,
+		gitcredential.GitCredentialCommand,
+		dockercredential.DockerCredentialCommand,
+		imports.ImportCommand,
+		exports.ExportCommand,
+		recipients.RecipientsCommand,
+		identity.IdentityCommand,
+		keyfile.KeyfileCommand,
+		recovery.RecoveryCommand,
+		signers.SignersCommand,
+		files.EncryptCommand,
+		files.DecryptCommand,
 	},
 }
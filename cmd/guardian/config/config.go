@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+// Environment variables of the flags are named PREFIX_FLAG_NAME
+const EnvPrefix = "GUARDIAN"
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+type Argon struct {
+	Time    int `json:"time,omitempty"`
+	Memory  int `json:"memory,omitempty"`
+	Threads int `json:"threads,omitempty"`
+}
+
+// Profile holds the values of the flags shared by the commands, empty values are ignored
+type Profile struct {
+	// Secrets database
+	Path string `json:"path,omitempty"`
+	// Key source of -key-from
+	KeyFrom  string `json:"keyFrom,omitempty"`
+	Argon    Argon  `json:"argon,omitempty"`
+	SaltSize int    `json:"saltSize,omitempty"`
+	Output   string `json:"output,omitempty"`
+	// Seconds before -clipboard clears the copied secret
+	ClipboardTimeout int `json:"clipboardTimeout,omitempty"`
+}
+
+type Config struct {
+	// Profile used when -profile isn't set
+	Profile  string             `json:"profile,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// DefaultPath returns XDG_CONFIG_HOME/guardian/config.json, ~/.config when it isn't set
+func DefaultPath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		base = path.Join(cli.Home(), ".config")
+	}
+	return path.Join(base, "guardian", "config.json")
+}
+
+// Load reads the config file, a missing file is an empty config
+func Load(filepath string) (config Config, err error) {
+	contents, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read config: %w", err)
+		return
+	}
+	err = json.Unmarshal(contents, &config)
+	if err != nil {
+		err = fmt.Errorf("failed to decode config: %s: %w", filepath, err)
+	}
+	return
+}
+
+// Select returns the named profile, the default one when name is empty
+func (c Config) Select(name string) (profile Profile, err error) {
+	if name == "" {
+		name = c.Profile
+		if name == "" {
+			return
+		}
+	}
+	profile, found := c.Profiles[name]
+	if !found {
+		err = fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	return
+}
+
+// Source returns the profile values keyed by the flags they set, the output is read by the root command
+func (p Profile) Source() commands.Source {
+	values := map[string]string{}
+	set := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setInt := func(name string, value int) {
+		if value != 0 {
+			values[name] = strconv.Itoa(value)
+		}
+	}
+	set(cliflags.Secrets, p.Path)
+	set(cliflags.KeyFrom, p.KeyFrom)
+	setInt(cliflags.ArgonTime, p.Argon.Time)
+	setInt(cliflags.ArgonMemory, p.Argon.Memory)
+	setInt(cliflags.ArgonThreads, p.Argon.Threads)
+	setInt(cliflags.SaltSize, p.SaltSize)
+	if p.ClipboardTimeout != 0 {
+		values[cliflags.ClipboardTimeout] = (time.Duration(p.ClipboardTimeout) * time.Second).String()
+	}
+	return commands.MapSource(values)
+}
@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/RogueTeam/guardian/cmd/guardian/config"
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, contents string) string {
+		filepath := path.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(filepath, []byte(contents), 0o600)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return filepath
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		// A missing file is an empty config
+		cfg, err := config.Load(path.Join(t.TempDir(), "missing.json"))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		profile, err := cfg.Select("")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if profile != (config.Profile{}) {
+			t.Fatalf("expecting empty profile but received: %+v", profile)
+		}
+
+		cfg, err = config.Load(write(t, `{
+			"profile": "home",
+			"profiles": {
+				"home": {"path": "/home/user/guardian.json"},
+				"work": {"path": "/home/user/work.json", "argon": {"time": 4}, "saltSize": 32, "clipboardTimeout": 30}
+			}
+		}`))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		// The default profile is used without a name
+		profile, err = cfg.Select("")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if profile.Path != "/home/user/guardian.json" {
+			t.Fatalf("expecting home profile but received: %+v", profile)
+		}
+
+		profile, err = cfg.Select("work")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		source := profile.Source()
+		for name, expect := range map[string]string{cliflags.Secrets: "/home/user/work.json", cliflags.ArgonTime: "4", cliflags.SaltSize: "32", cliflags.ClipboardTimeout: "30s"} {
+			if value, found := source(name); !found || value != expect {
+				t.Fatalf("expecting %s for %s but received: %s", expect, name, value)
+			}
+		}
+		// Empty values are left to the defaults
+		if value, found := source(cliflags.ArgonMemory); found {
+			t.Fatalf("expecting no argon memory but received: %s", value)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := config.Load(write(t, `{"profile": "missing", "profiles": {"home": {}}}`))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		_, err = cfg.Select("work")
+		if !errors.Is(err, config.ErrUnknownProfile) {
+			t.Fatalf("expecting %v but received: %v", config.ErrUnknownProfile, err)
+		}
+		_, err = cfg.Select("")
+		if !errors.Is(err, config.ErrUnknownProfile) {
+			t.Fatalf("expecting %v but received: %v", config.ErrUnknownProfile, err)
+		}
+
+		_, err = config.Load(write(t, "garbage"))
+		if err == nil {
+			t.Fatal("expecting error")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/RogueTeam/guardian/cmd/guardian/config"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+// Exit codes, scripts tell apart a wrong key from a damaged file
+const (
+	ExitFailure     = 1
+	ExitWrongKey    = 2
+	ExitCorrupted   = 3
+	ExitUnsupported = 4
+	ExitNotFound    = 5
+	ExitLocked      = 6
+	ExitUsage       = 7
+)
+
+// Error codes of the json and yaml outputs, stable across releases
+const (
+	CodeFailure     = "failure"
+	CodeWrongKey    = "wrong_key"
+	CodeCorrupted   = "corrupted"
+	CodeUnsupported = "unsupported_version"
+	CodeNotFound    = "not_found"
+	CodeLocked      = "locked"
+	CodeUsage       = "usage"
+)
+
+// Failure is the machine readable error written by the json and yaml outputs
+type Failure struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// failures are matched in order, the first one wrapped by the error wins
+var failures = []struct {
+	code     string
+	exitCode int
+	errs     []error
+}{
+	{CodeUnsupported, ExitUnsupported, []error{database.ErrUnsupportedVersion}},
+	// Corruption is checked first, a modified payload also fails decryption
+	{CodeCorrupted, ExitCorrupted, []error{database.ErrCorrupted}},
+	{CodeWrongKey, ExitWrongKey, []error{
+		crypto.ErrDecryptionFailed,
+		database.ErrNoMatchingSlot,
+		database.ErrKeyfileRequired,
+		database.ErrKeyfileUnexpected,
+	}},
+	{CodeLocked, ExitLocked, []error{database.ErrLocked, database.ErrChanged}},
+	{CodeNotFound, ExitNotFound, []error{
+		database.ErrEntryNotFound,
+		database.ErrRecipientNotFound,
+		database.ErrSignerNotFound,
+		os.ErrNotExist,
+	}},
+	{CodeUsage, ExitUsage, []error{
+		commands.ErrFlagNotFound,
+		commands.ErrIncompleteFlag,
+		commands.ErrInvalidNumberOfArgs,
+		commands.ErrArgsNotAllowedInParent,
+		commands.ErrInvalidSourceValue,
+		commands.ErrRequiredFlag,
+		commands.ErrMissingArgument,
+		commands.ErrInvalidChoice,
+		crypto.ErrUnknownPadding,
+		cli.ErrUnknownOutput,
+		config.ErrUnknownProfile,
+	}},
+}
+
+func newFailure(err error) (failure Failure) {
+	failure = Failure{Code: CodeFailure, Message: err.Error(), ExitCode: ExitFailure}
+	for _, entry := range failures {
+		for _, target := range entry.errs {
+			if errors.Is(err, target) {
+				failure.Code, failure.ExitCode = entry.code, entry.exitCode
+				return
+			}
+		}
+	}
+	return
+}
@@ -1,17 +1,62 @@
 package flags
 
 const (
-	ArgonTime    = "argon-time"
-	ArgonMemory  = "argon-memory"
-	ArgonThreads = "argon-threads"
-	NoPrompt     = "no-prompt"
-	Secrets      = "secrets"
-	Id           = "id"
-	Value        = "value"
-	Db           = "db"
-	SaltSize     = "salt-size"
-	MountPoint   = "mount-point"
-	File         = "file"
-	Key          = "key"
-	Argon        = "argon"
+	ArgonTime        = "argon-time"
+	ArgonMemory      = "argon-memory"
+	ArgonThreads     = "argon-threads"
+	NoPrompt         = "no-prompt"
+	Secrets          = "secrets"
+	Id               = "id"
+	Value            = "value"
+	Db               = "db"
+	SaltSize         = "salt-size"
+	MountPoint       = "mount-point"
+	File             = "file"
+	Key              = "key"
+	Argon            = "argon"
+	Type             = "type"
+	Bits             = "bits"
+	Comment          = "comment"
+	Passphrase       = "passphrase"
+	Out              = "out"
+	Pattern          = "pattern"
+	Prefix           = "prefix"
+	KDBXKeyfile      = "kdbx-keyfile"
+	Format           = "format"
+	Columns          = "columns"
+	Skip             = "skip"
+	Overwrite        = "overwrite"
+	Rename           = "rename"
+	DryRun           = "dry-run"
+	Duplicates       = "duplicates"
+	Dir              = "dir"
+	Tag              = "tag"
+	Yes              = "yes"
+	Identity         = "identity"
+	IdentityKey      = "identity-key"
+	Recipient        = "recipient"
+	Shares           = "shares"
+	Threshold        = "threshold"
+	RecoveryKey      = "recovery-key"
+	Rekey            = "rekey"
+	Keyfile          = "keyfile"
+	KeyfileHash      = "keyfile-hash"
+	KeyFrom          = "key-from"
+	Padding          = "padding"
+	SigningKey       = "signing-key"
+	DeviceKey        = "device-key"
+	Warnings         = "warnings"
+	RequireSigner    = "require-signer"
+	Signer           = "signer"
+	In               = "in"
+	Password         = "password"
+	MinArgonTime     = "min-argon-time"
+	MinArgonMemory   = "min-argon-memory"
+	MinArgonThreads  = "min-argon-threads"
+	MinSaltSize      = "min-salt-size"
+	Output           = "output"
+	Profile          = "profile"
+	Config           = "config"
+	Clipboard        = "clipboard"
+	ClipboardTimeout = "clipboard-timeout"
 )
@@ -1,24 +1,61 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+
+	"github.com/RogueTeam/guardian/cmd/guardian/config"
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/secure"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
 )
 
 func main() {
-	result, err := root.Run(os.Args[1:])
+	err := secure.DisableCoreDumps()
+	if err != nil {
+		log.Printf("failed to disable core dumps: %v", err)
+	}
+
+	ctx := commands.NewContext()
+	ctx.AddSource(commands.EnvSource(config.EnvPrefix))
+	result, err := root.RunContext(ctx, os.Args[1:])
+	secure.DestroyAll()
+	if warnings, found := ctx.Get(cliflags.Warnings); found {
+		writeWarnings(warnings.([]string))
+	}
 	if err != nil {
-		log.Fatalf("something went wrong: %v", err)
+		failure := newFailure(err)
+		switch output {
+		case cli.OutputJSON, cli.OutputYAML:
+			cli.WriteOutput(os.Stderr, output, map[string]any{"error": failure})
+		default:
+			log.Printf("something went wrong: %v", err)
+		}
+		os.Exit(failure.ExitCode)
 	}
 
-	switch result := result.(type) {
-	case nil:
+	switch {
+	case result == nil && output == cli.OutputText:
 		fmt.Fprintf(os.Stderr, "Command exited successfully!")
-	case string, int, float64:
-		fmt.Fprintf(os.Stdout, "%v", result)
+	case result == nil:
+	default:
+		err = cli.WriteOutput(os.Stdout, output, result)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// writeWarnings writes the warnings of the command to stderr, stdout only holds the result
+func writeWarnings(warnings []string) {
+	switch output {
+	case cli.OutputJSON, cli.OutputYAML:
+		cli.WriteOutput(os.Stderr, output, map[string]any{"warnings": warnings})
 	default:
-		json.NewEncoder(os.Stdout).Encode(result)
+		for _, warning := range warnings {
+			log.Printf("warning: %s", warning)
+		}
 	}
 }
@@ -0,0 +1,63 @@
+package dockercredential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/credentials"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var DockerCredentialCommand = &commands.Command{
+	Name:        "docker-credential",
+	Description: `Docker credential helper, use the docker-credential-guardian binary with "credsStore": "guardian"`,
+	Flags: append(
+		utils.DatabaseFlags(),
+		commands.Value{Type: commands.TypeString, Name: cliflags.Prefix, Description: "Prefix of the registry entries", Default: credentials.DefaultDockerPrefix},
+	),
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		ctx.Set(cliflags.Prefix, flags[cliflags.Prefix])
+		return utils.SetDatabaseFlags(ctx, flags)
+	},
+	SubCommands: commands.Commands{
+		operation(credentials.DockerStore, "Stores the credentials JSON in stdin", true),
+		operation(credentials.DockerGet, "Prints the credentials of the server URL in stdin", false),
+		operation(credentials.DockerErase, "Removes the credentials of the server URL in stdin", true),
+		operation(credentials.DockerList, "Prints the server URLs with their usernames", false),
+	},
+}
+
+func operation(name, description string, save bool) (cmd *commands.Command) {
+	cmd = &commands.Command{
+		Name:        name,
+		Description: description,
+		Setup:       utils.SetupDB,
+		Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+			// Dependencies
+			docker := credentials.Docker{
+				Database: ctx.MustGet(cliflags.Db).(*database.Database),
+				Prefix:   ctx.MustGet(cliflags.Prefix).(string),
+			}
+
+			var output bytes.Buffer
+			err = docker.Serve(name, os.Stdin, &output)
+			if err != nil {
+				err = fmt.Errorf("failed to %s credentials: %w", name, err)
+				return
+			}
+
+			// Written as is, the output format would break the protocol
+			result = cli.Raw(output.String())
+			return
+		},
+	}
+	if save {
+		cmd.Defer = utils.DeferSaveDB
+	}
+	return cmd
+}
@@ -0,0 +1,137 @@
+package exports
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/exports"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+var (
+	ErrNotConfirmed     = errors.New("plaintext export not confirmed")
+	ErrPasswordMismatch = errors.New("passwords doesn't match")
+	ErrNoEntries        = errors.New("no entries match the filters")
+)
+
+var ExportCommand = &commands.Command{
+	Name:        "export",
+	Description: "Exports the database as plaintext json, csv or dotenv, or as a KeePass kdbx file protected with a new password",
+	Flags: append(
+		utils.DatabaseFlags(),
+		commands.Value{Type: commands.TypeEnum, Name: cliflags.Format, Description: "Output format", Default: exports.FormatJSON, Choices: []string{exports.FormatJSON, exports.FormatCSV, exports.FormatDotenv, exports.FormatKDBX}},
+		commands.Value{Type: commands.TypeString, Name: cliflags.Out, Description: "File to write to, stdout when empty", Default: ""},
+		commands.Value{Type: commands.TypeString, Name: cliflags.Prefix, Description: "Only export ids starting with this prefix", Default: ""},
+		commands.Value{Type: commands.TypeString, Name: cliflags.Tag, Description: "Only export entries with this tag", Default: ""},
+		commands.Value{Type: commands.TypeBool, Name: cliflags.Yes, Description: "Don't ask for confirmation before writing plaintext secrets", Default: false},
+	),
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		err = utils.SetDatabaseFlags(ctx, flags)
+		if err != nil {
+			return
+		}
+		return utils.SetupDB(ctx, flags)
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		format := flags[cliflags.Format].(string)
+		filter := exports.Filter{
+			Prefix: flags[cliflags.Prefix].(string),
+			Tag:    flags[cliflags.Tag].(string),
+		}
+		ids := filter.Ids(db)
+		if len(ids) == 0 {
+			err = ErrNoEntries
+			return
+		}
+
+		out := flags[cliflags.Out].(string)
+		var buf bytes.Buffer
+		switch format {
+		case exports.FormatJSON:
+			err = exports.JSON(&buf, db, ids)
+		case exports.FormatCSV:
+			err = exports.CSV(&buf, db, ids)
+		case exports.FormatDotenv:
+			err = exports.Dotenv(&buf, db, ids)
+		case exports.FormatKDBX:
+			var key kdbx.Key
+			key, err = readKDBXKey(ctx)
+			if err != nil {
+				return
+			}
+			defer wipe(key.Password)
+			err = exports.KDBX(&buf, db, ids, key, kdbx.DefaultOptions())
+		default:
+			err = fmt.Errorf("%w: %s", exports.ErrUnknownFormat, format)
+		}
+		defer wipe(buf.Bytes())
+		if err != nil {
+			return
+		}
+
+		if exports.Plaintext(format) && !flags[cliflags.Yes].(bool) {
+			destination := out
+			if destination == "" {
+				destination = "stdout"
+			}
+			if !cli.Confirm(fmt.Sprintf("Write %d plaintext secrets to %s?", len(ids), destination)) {
+				err = ErrNotConfirmed
+				return
+			}
+		}
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			var file *os.File
+			file, err = os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				err = fmt.Errorf("failed to open output file: %w", err)
+				return
+			}
+			defer file.Close()
+			w = file
+		}
+
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			err = fmt.Errorf("failed to write export: %w", err)
+		}
+		return
+	},
+}
+
+func readKDBXKey(ctx *commands.Context) (key kdbx.Key, err error) {
+	prompt := !ctx.MustGet(cliflags.NoPrompt).(bool)
+
+	key.Password, err = cli.ReadSecret("KDBX password", prompt)
+	if err != nil {
+		return
+	}
+	again, err := cli.ReadSecret("Confirm KDBX password", prompt)
+	if err != nil {
+		wipe(key.Password)
+		return
+	}
+	defer wipe(again)
+	if !bytes.Equal(key.Password, again) {
+		wipe(key.Password)
+		err = ErrPasswordMismatch
+	}
+	return
+}
+
+func wipe(buf []byte) {
+	rand.Read(buf)
+}
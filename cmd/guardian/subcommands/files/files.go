@@ -0,0 +1,195 @@
+package files
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+// fileFlags returns the flags of encrypt and decrypt
+func fileFlags() commands.Values {
+	return append(commands.Values{
+		{Type: commands.TypeString, Name: cliflags.In, Description: "File to read, stdin when empty", Default: ""},
+		{Type: commands.TypeString, Name: cliflags.Out, Description: "File to write, stdout when empty", Default: ""},
+	}, utils.KeyFlags()...)
+}
+
+// password returns the master key combined with the keyfile
+func password(ctx *commands.Context) []byte {
+	var digest []byte
+	if keyfile, found := ctx.Get(cliflags.KeyfileHash); found {
+		digest = keyfile.([]byte)
+	}
+	return crypto.CompositeKey(ctx.MustGet(cliflags.Key).([]byte), digest)
+}
+
+// open returns the input and output files, the output is removed when the command fails
+func open(flags map[string]any, err *error) (in io.Reader, out io.Writer, done func()) {
+	var closers []func()
+	done = func() {
+		for _, closer := range closers {
+			closer()
+		}
+	}
+
+	in, out = os.Stdin, os.Stdout
+	if name := flags[cliflags.In].(string); name != "" {
+		var file *os.File
+		file, *err = os.Open(name)
+		if *err != nil {
+			*err = fmt.Errorf("failed to open input file: %w", *err)
+			return
+		}
+		closers = append(closers, func() { file.Close() })
+		in = file
+	}
+	if name := flags[cliflags.Out].(string); name != "" {
+		var file *os.File
+		file, *err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if *err != nil {
+			*err = fmt.Errorf("failed to open output file: %w", *err)
+			return
+		}
+		closers = append(closers, func() {
+			file.Close()
+			if *err != nil {
+				os.Remove(name)
+			}
+		})
+		out = file
+	}
+	return
+}
+
+var EncryptCommand = &commands.Command{
+	Name:        "encrypt",
+	Description: "Encrypts a file for the master key or for recipients",
+	Flags: append(fileFlags(),
+		commands.Value{Type: commands.TypeStrings, Name: cliflags.Recipient, Description: "Recipient able to decrypt the file instead of the master key"},
+		commands.Value{Type: commands.TypeBool, Name: cliflags.Password, Description: "Also encrypt for the master key when recipients are given", Default: false},
+	),
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		utils.SetKeyFlags(ctx, flags)
+		if recipients, _ := flags[cliflags.Recipient].([]string); len(recipients) > 0 && !flags[cliflags.Password].(bool) {
+			return
+		}
+		return utils.SetupKey(ctx, flags)
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		fileKey := crypto.NewFileKey()
+		defer rand.Read(fileKey)
+
+		var header crypto.FileHeader
+		if _, found := ctx.Get(cliflags.Key); found {
+			key := password(ctx)
+			defer rand.Read(key)
+			_, header.Keyfile = ctx.Get(cliflags.KeyfileHash)
+			header.Slots = append(header.Slots, crypto.PasswordSlot(key, fileKey, ctx.MustGet(cliflags.Argon).(crypto.Argon), ctx.MustGet(cliflags.SaltSize).(int)))
+		}
+		recipients, _ := flags[cliflags.Recipient].([]string)
+		for _, encoded := range recipients {
+			var recipient *ecdh.PublicKey
+			recipient, err = crypto.ParseRecipient(encoded)
+			if err != nil {
+				return
+			}
+			var slot crypto.Slot
+			slot, err = crypto.X25519Slot(recipient, fileKey)
+			if err != nil {
+				return
+			}
+			header.Slots = append(header.Slots, slot)
+		}
+
+		in, out, done := open(flags, &err)
+		defer done()
+		if err != nil {
+			return
+		}
+
+		err = crypto.WriteFileHeader(out, header)
+		if err != nil {
+			return
+		}
+		w, err := crypto.NewStreamWriter(fileKey, out)
+		if err != nil {
+			return
+		}
+		_, err = io.Copy(w, in)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to encrypt file: %w", err)
+		}
+		return
+	},
+}
+
+var DecryptCommand = &commands.Command{
+	Name:        "decrypt",
+	Description: "Decrypts a file created by encrypt",
+	Flags: append(fileFlags(),
+		commands.Value{Type: commands.TypeString, Name: cliflags.Identity, Description: "Identity file to decrypt with instead of the master key", Default: ""},
+	),
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		utils.SetKeyFlags(ctx, flags)
+		ctx.Set(cliflags.Identity, flags[cliflags.Identity])
+		return utils.SetupKey(ctx, flags)
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		in, out, done := open(flags, &err)
+		defer done()
+		if err != nil {
+			return
+		}
+
+		r := bufio.NewReader(in)
+		header, err := crypto.ReadFileHeader(r)
+		if err != nil {
+			return
+		}
+
+		var fileKey []byte
+		if identity, found := ctx.Get(cliflags.IdentityKey); found {
+			fileKey, err = header.Open(nil, identity.(*ecdh.PrivateKey))
+		} else {
+			_, keyfile := ctx.Get(cliflags.KeyfileHash)
+			if header.Keyfile && !keyfile {
+				err = crypto.ErrKeyfileRequired
+				return
+			}
+			key := password(ctx)
+			defer rand.Read(key)
+			fileKey, err = header.Open(key, nil)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to open file key: %w", err)
+			return
+		}
+		defer rand.Read(fileKey)
+
+		stream, err := crypto.NewStreamReader(fileKey, r)
+		if err != nil {
+			return
+		}
+		defer stream.Close()
+		_, err = io.Copy(out, stream)
+		if errors.Is(err, crypto.ErrDecryptionFailed) {
+			err = fmt.Errorf("file was modified or truncated: %w", err)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to decrypt file: %w", err)
+		}
+		return
+	},
+}
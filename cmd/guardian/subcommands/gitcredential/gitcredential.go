@@ -0,0 +1,62 @@
+package gitcredential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/credentials"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var GitCredentialCommand = &commands.Command{
+	Name:        "git-credential",
+	Description: "Git credential helper, configure it with: git config credential.helper 'guardian git-credential'",
+	Flags: append(
+		utils.DatabaseFlags(),
+		commands.Value{Type: commands.TypeString, Name: cliflags.Pattern, Description: "Entry id pattern, supports {protocol}, {host}, {path} and {username}", Default: credentials.DefaultGitPattern},
+	),
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		ctx.Set(cliflags.Pattern, flags[cliflags.Pattern])
+		return utils.SetDatabaseFlags(ctx, flags)
+	},
+	SubCommands: commands.Commands{
+		operation(credentials.GitGet, "Prints the credentials matching the attributes in stdin", false),
+		operation(credentials.GitStore, "Stores the credentials in stdin", true),
+		operation(credentials.GitErase, "Removes the credentials matching the attributes in stdin", true),
+	},
+}
+
+func operation(name, description string, save bool) (cmd *commands.Command) {
+	cmd = &commands.Command{
+		Name:        name,
+		Description: description,
+		Setup:       utils.SetupDB,
+		Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+			// Dependencies
+			git := credentials.Git{
+				Database: ctx.MustGet(cliflags.Db).(*database.Database),
+				Pattern:  ctx.MustGet(cliflags.Pattern).(string),
+			}
+
+			var output bytes.Buffer
+			err = git.Serve(name, os.Stdin, &output)
+			if err != nil {
+				err = fmt.Errorf("failed to %s credentials: %w", name, err)
+				return
+			}
+
+			// Written as is, the output format would break the protocol
+			result = cli.Raw(output.String())
+			return
+		},
+	}
+	if save {
+		cmd.Defer = utils.DeferSaveDB
+	}
+	return cmd
+}
@@ -0,0 +1,55 @@
+package identity
+
+import (
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var IdentityCommand = &commands.Command{
+	Name:        "identity",
+	Description: "Manage the X25519 identities used to open shared databases",
+	SubCommands: commands.Commands{
+		NewCommand,
+	},
+}
+
+var NewCommand = &commands.Command{
+	Name:        "new",
+	Description: "Generates a new identity file and prints its recipient",
+	Flags: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Out, Description: "File to write the identity to, stdout when empty", Default: ""},
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		identity, err := crypto.NewIdentity()
+		if err != nil {
+			return
+		}
+
+		contents := crypto.MarshalIdentity(identity)
+		out := flags[cliflags.Out].(string)
+		if out == "" {
+			result = string(contents)
+			return
+		}
+
+		file, err := os.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			err = fmt.Errorf("failed to create identity file: %w", err)
+			return
+		}
+		defer file.Close()
+
+		_, err = file.Write(contents)
+		if err != nil {
+			err = fmt.Errorf("failed to write identity file: %w", err)
+			return
+		}
+
+		result = crypto.EncodeRecipient(identity.PublicKey())
+		return
+	},
+}
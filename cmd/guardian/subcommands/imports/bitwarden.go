@@ -0,0 +1,44 @@
+package imports
+
+import (
+	"bytes"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var BitwardenJSONCommand = &commands.Command{
+	Name:        "bitwarden-json",
+	Description: "Imports a Bitwarden JSON export, prompting for the password of encrypted exports",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.File, Description: "JSON file to import"},
+	},
+	Flags: policyFlags(),
+	Setup: setup,
+	Defer: deferSave,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		contents, err := readFile(args[cliflags.File].(string))
+		if err != nil {
+			return
+		}
+		defer wipe(contents)
+
+		var readErr error
+		password := func() (secret []byte) {
+			secret, readErr = cli.ReadSecret("Export password", !ctx.MustGet(cliflags.NoPrompt).(bool))
+			return
+		}
+		entries, unmapped, err := imports.BitwardenJSON(bytes.NewReader(contents), password)
+		if readErr != nil {
+			err = readErr
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		return apply(ctx, entries, unmapped)
+	},
+}
@@ -0,0 +1,45 @@
+package imports
+
+import (
+	"bytes"
+	"fmt"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var CSVCommand = &commands.Command{
+	Name:        "csv",
+	Description: "Imports a CSV export of bitwarden, 1password, chrome, firefox or a generic file with id and secret columns",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.File, Description: "CSV file to import"},
+	},
+	Flags: append(
+		policyFlags(),
+		commands.Value{Type: commands.TypeEnum, Name: cliflags.Format, Description: "Format of the file", Default: imports.FormatGeneric, Choices: []string{imports.FormatBitwarden, imports.Format1Password, imports.FormatChrome, imports.FormatFirefox, imports.FormatGeneric}},
+		commands.Value{Type: commands.TypeString, Name: cliflags.Columns, Description: `Generic columns mapping like "id=Title,secret=Password,username=Login"`, Default: ""},
+	),
+	Setup: setup,
+	Defer: deferSave,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		columns, err := imports.ParseColumns(flags[cliflags.Columns].(string))
+		if err != nil {
+			return
+		}
+
+		contents, err := readFile(args[cliflags.File].(string))
+		if err != nil {
+			return
+		}
+		defer wipe(contents)
+
+		entries, err := imports.CSV(bytes.NewReader(contents), flags[cliflags.Format].(string), columns)
+		if err != nil {
+			err = fmt.Errorf("failed to parse csv: %w", err)
+			return
+		}
+
+		return apply(ctx, entries, nil)
+	},
+}
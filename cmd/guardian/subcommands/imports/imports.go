@@ -0,0 +1,103 @@
+package imports
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var ErrConflictingPolicies = errors.New("only one of -skip, -overwrite and -rename can be used")
+
+var ImportCommand = &commands.Command{
+	Name:        "import",
+	Description: "Import secrets from other password managers",
+	Flags:       utils.DatabaseFlags(),
+	Setup:       utils.SetDatabaseFlags,
+	SubCommands: commands.Commands{
+		KDBXCommand,
+		CSVCommand,
+		BitwardenJSONCommand,
+		PassTreeCommand,
+	},
+}
+
+// policyFlags returns the flags shared by the import subcommands
+func policyFlags() commands.Values {
+	return commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Skip, Description: "Keep existing entries with the same id (default)", Default: false},
+		{Type: commands.TypeBool, Name: cliflags.Overwrite, Description: "Replace existing entries with the same id", Default: false},
+		{Type: commands.TypeBool, Name: cliflags.Rename, Description: "Import entries with an existing id under a numbered one", Default: false},
+		{Type: commands.TypeBool, Name: cliflags.DryRun, Description: "Report the changes without saving them", Default: false},
+	}
+}
+
+// setup reads the flags of policyFlags and opens the database
+func setup(ctx *commands.Context, flags map[string]any) (err error) {
+	policy := imports.Skip
+	set := 0
+	for flag, value := range map[string]imports.Policy{cliflags.Skip: imports.Skip, cliflags.Overwrite: imports.Overwrite, cliflags.Rename: imports.Rename} {
+		if flags[flag].(bool) {
+			policy = value
+			set++
+		}
+	}
+	if set > 1 {
+		err = ErrConflictingPolicies
+		return
+	}
+	ctx.Set(cliflags.Duplicates, policy)
+	ctx.Set(cliflags.DryRun, flags[cliflags.DryRun])
+
+	return utils.SetupDB(ctx, flags)
+}
+
+// Reads the whole export so the raw contents can be overwritten after parsing,
+// the strings of the csv, json and xml decoders are left to the garbage collector
+func readFile(filename string) (contents []byte, err error) {
+	contents, err = os.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("failed to read file: %w", err)
+	}
+	return
+}
+
+func apply(ctx *commands.Context, entries []imports.Entry, unmapped []string) (result any, err error) {
+	// Dependencies
+	db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+	// The database keeps its own copies of the values
+	defer imports.Wipe(entries)
+
+	options := imports.Options{
+		Duplicates: ctx.MustGet(cliflags.Duplicates).(imports.Policy),
+		DryRun:     ctx.MustGet(cliflags.DryRun).(bool),
+	}
+	report, err := imports.Apply(db, entries, options)
+	if err != nil {
+		return
+	}
+
+	report.Unmapped = unmapped
+	result = report
+	return
+}
+
+func deferSave(ctx *commands.Context, result any) (finalResult any, err error) {
+	if ctx.MustGet(cliflags.DryRun).(bool) {
+		finalResult = result
+		return
+	}
+	return utils.DeferSaveDB(ctx, result)
+}
+
+// wipe overwrites the raw contents of an export, apply overwrites the parsed entries
+func wipe(buf []byte) {
+	rand.Read(buf)
+}
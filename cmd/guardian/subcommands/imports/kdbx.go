@@ -0,0 +1,53 @@
+package imports
+
+import (
+	"bytes"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+var KDBXCommand = &commands.Command{
+	Name:        "kdbx",
+	Description: "Imports a KeePass KDBX 4 file, groups are mapped to hierarchical ids",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.File, Description: "KDBX file to import"},
+	},
+	Flags: append(
+		policyFlags(),
+		commands.Value{Type: commands.TypeString, Name: cliflags.KDBXKeyfile, Description: "Key file of the KDBX database", Default: ""},
+	),
+	Setup: setup,
+	Defer: deferSave,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		contents, err := readFile(args[cliflags.File].(string))
+		if err != nil {
+			return
+		}
+		defer wipe(contents)
+
+		var key kdbx.Key
+		if keyfile := flags[cliflags.KDBXKeyfile].(string); keyfile != "" {
+			key.Keyfile, err = readFile(keyfile)
+			if err != nil {
+				return
+			}
+			defer wipe(key.Keyfile)
+		}
+		key.Password, err = cli.ReadSecret("KDBX password", !ctx.MustGet(cliflags.NoPrompt).(bool))
+		if err != nil {
+			return
+		}
+		defer wipe(key.Password)
+
+		entries, err := imports.KDBX(bytes.NewReader(contents), key)
+		if err != nil {
+			return
+		}
+
+		return apply(ctx, entries, nil)
+	},
+}
@@ -0,0 +1,28 @@
+package imports
+
+import (
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var PassTreeCommand = &commands.Command{
+	Name:        "pass-tree",
+	Description: "Imports a directory of already decrypted password-store files, paths are used as ids",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.Dir, Description: "Root of the decrypted store"},
+	},
+	Flags: policyFlags(),
+	Setup: setup,
+	Defer: deferSave,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		entries, unmapped, err := imports.PassTree(os.DirFS(args[cliflags.Dir].(string)))
+		if err != nil {
+			return
+		}
+
+		return apply(ctx, entries, unmapped)
+	},
+}
@@ -0,0 +1,53 @@
+package keyfile
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var ErrMissingOut = errors.New("missing -out file")
+
+var KeyfileCommand = &commands.Command{
+	Name:        "keyfile",
+	Description: "Manage the keyfiles combined with the master key",
+	SubCommands: commands.Commands{
+		NewCommand,
+	},
+}
+
+var NewCommand = &commands.Command{
+	Name:        "new",
+	Description: "Generates a new random keyfile, any existing file can be used as keyfile too",
+	Flags: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Out, Description: "File to write the keyfile to", Default: ""},
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		out := flags[cliflags.Out].(string)
+		if out == "" {
+			err = ErrMissingOut
+			return
+		}
+
+		contents := crypto.NewKeyfile()
+		defer rand.Read(contents)
+
+		file, err := os.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			err = fmt.Errorf("failed to create keyfile: %w", err)
+			return
+		}
+		defer file.Close()
+
+		_, err = file.Write(contents)
+		if err != nil {
+			err = fmt.Errorf("failed to write keyfile: %w", err)
+		}
+		return
+	},
+}
@@ -0,0 +1,69 @@
+package recipients
+
+import (
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var RecipientsCommand = &commands.Command{
+	Name:        "recipients",
+	Description: "Manage the identities able to open the database besides the master key",
+	Flags:       utils.DatabaseFlags(),
+	Setup:       utils.SetDatabaseFlags,
+	SubCommands: commands.Commands{
+		AddCommand,
+		RmCommand,
+		ListCommand,
+	},
+}
+
+var AddCommand = &commands.Command{
+	Name:        "add",
+	Description: "Wraps the data key for a new recipient",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Recipient, Description: "recipient printed by identity new"},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		err = db.AddRecipient(args[cliflags.Recipient].(string))
+		return
+	},
+}
+
+var RmCommand = &commands.Command{
+	Name:        "rm",
+	Description: "Removes a recipient rotating the data key, requires the master key",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Recipient, Description: "recipient to remove"},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		err = db.RemoveRecipient(args[cliflags.Recipient].(string))
+		return
+	},
+}
+
+var ListCommand = &commands.Command{
+	Name:        "list",
+	Description: "Lists the recipients of the database",
+	Setup:       utils.SetupDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		recipients := make([]string, len(db.Recipients))
+		copy(recipients, db.Recipients)
+		result = recipients
+		return
+	},
+}
@@ -0,0 +1,193 @@
+package recovery
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/shamir"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+	"golang.org/x/term"
+)
+
+// Share formats
+const (
+	FormatWords = "words"
+	FormatText  = "text"
+)
+
+var (
+	ErrUnknownFormat    = errors.New("unknown share format")
+	ErrPasswordMismatch = errors.New("passwords doesn't match")
+)
+
+var RecoveryCommand = &commands.Command{
+	Name:        "recovery",
+	Description: "Splits a recovery key into shares able to unlock the database when the master key is lost",
+	Flags:       utils.DatabaseFlags(),
+	Setup:       utils.SetDatabaseFlags,
+	SubCommands: commands.Commands{
+		SplitCommand,
+		CombineCommand,
+	},
+}
+
+var SplitCommand = &commands.Command{
+	Name:        "split",
+	Description: "Generates a new recovery key and prints its shares, previous shares stop working",
+	Flags: commands.Values{
+		{Type: commands.TypeInt, Name: cliflags.Shares, Description: "Number of shares to print", Default: 5},
+		{Type: commands.TypeInt, Name: cliflags.Threshold, Description: "Number of shares required to recover", Default: 3},
+		{Type: commands.TypeEnum, Name: cliflags.Format, Description: "Share format, text is QR ready", Default: FormatWords, Choices: []string{FormatWords, FormatText}},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		var encode func([]byte) string
+		switch format := flags[cliflags.Format].(string); format {
+		case FormatWords:
+			encode = shamir.Words
+		case FormatText:
+			encode = shamir.Text
+		default:
+			err = fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+			return
+		}
+
+		recoveryKey := make([]byte, crypto.KeySize)
+		rand.Read(recoveryKey)
+
+		threshold := flags[cliflags.Threshold].(int)
+		shares, err := shamir.Split(recoveryKey, flags[cliflags.Shares].(int), threshold)
+		if err != nil {
+			rand.Read(recoveryKey)
+			return
+		}
+		if db.RecoveryKey != nil {
+			rand.Read(db.RecoveryKey)
+		}
+		db.RecoveryKey = recoveryKey
+
+		var group [2]byte
+		rand.Read(group[:])
+
+		var output strings.Builder
+		for index, share := range shares {
+			encoded := shamir.Encoded{Share: share, Threshold: threshold, Group: group}
+			fmt.Fprintf(&output, "Share %d/%d: %s\n", index+1, len(shares), encode(encoded.Bytes()))
+			rand.Read(share.Y)
+		}
+		result = output.String()
+		return
+	},
+}
+
+var CombineCommand = &commands.Command{
+	Name:        "combine",
+	Description: "Reads shares from stdin, one per line, and unlocks the database with the recovered key",
+	Flags: commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Rekey, Description: "Sets a new master key", Default: false},
+	},
+	Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+		recoveryKey, err := readShares()
+		if err != nil {
+			err = fmt.Errorf("failed to recover key: %w", err)
+			return
+		}
+		ctx.Set(cliflags.RecoveryKey, recoveryKey)
+		ctx.Set(cliflags.Rekey, flags[cliflags.Rekey])
+
+		return utils.SetupDB(ctx, flags)
+	},
+	Defer: func(ctx *commands.Context, result any) (finalResult any, err error) {
+		finalResult = result
+		if !ctx.MustGet(cliflags.Rekey).(bool) {
+			return
+		}
+		return utils.DeferSaveDB(ctx, result)
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		if !flags[cliflags.Rekey].(bool) {
+			ids, _ := db.List()
+			result = fmt.Sprintf("Database unlocked, %d secrets recovered\n", len(ids))
+			return
+		}
+
+		prompt := !ctx.MustGet(cliflags.NoPrompt).(bool)
+		key, err := cli.ReadSecret("New master key", prompt)
+		if err != nil {
+			return
+		}
+		again, err := cli.ReadSecret("Confirm new master key", prompt)
+		if err != nil {
+			rand.Read(key)
+			return
+		}
+		defer rand.Read(again)
+		if !bytes.Equal(key, again) {
+			rand.Read(key)
+			err = ErrPasswordMismatch
+			return
+		}
+		db.SetKey(key, db.Keyfile)
+		return
+	},
+}
+
+// readShares reads lines until the threshold of the first share is reached
+func readShares() (recoveryKey []byte, err error) {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+
+	var encoded []shamir.Encoded
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		if interactive {
+			fmt.Fprintf(os.Stderr, "Share %d: ", len(encoded)+1)
+		}
+		if !scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		// Accept the lines printed by split as is
+		if _, share, found := strings.Cut(line, ": "); found {
+			line = share
+		}
+		if line == "" {
+			continue
+		}
+
+		var e shamir.Encoded
+		e, err = shamir.Parse(line)
+		if err != nil {
+			err = fmt.Errorf("share %d: %w", len(encoded)+1, err)
+			return
+		}
+		encoded = append(encoded, e)
+		if len(encoded) >= encoded[0].Threshold {
+			break
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("failed to read shares: %w", err)
+		return
+	}
+
+	return shamir.CombineEncoded(encoded)
+}
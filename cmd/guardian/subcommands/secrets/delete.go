@@ -24,7 +24,7 @@ var DelCommand = &commands.Command{
 		// Delete
 		err = db.Del(args[cliflags.Id].(string))
 		if err != nil {
-			err = fmt.Errorf("failed to delete value")
+			err = fmt.Errorf("failed to delete value: %w", err)
 		}
 		return
 	},
@@ -2,11 +2,14 @@ package secrets
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
 	"github.com/RogueTeam/guardian/cmd/guardian/utils"
 	"github.com/RogueTeam/guardian/database"
 	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
 )
 
 var GetCommand = &commands.Command{
@@ -15,16 +18,31 @@ var GetCommand = &commands.Command{
 	Args: commands.Values{
 		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the entry"},
 	},
+	Flags: commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Clipboard, Description: "Copy the value to the clipboard instead of printing it", Default: false},
+		{Type: commands.TypeDuration, Name: cliflags.ClipboardTimeout, Description: "Time before the clipboard is cleared, 0 keeps the value", Default: 45 * time.Second},
+	},
 	Setup: utils.SetupDB,
 	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 		// Dependencies
 		db := ctx.MustGet(cliflags.Db).(*database.Database)
 
 		// Retrieve
-		result, err = db.Get(args[cliflags.Id].(string))
+		value, err := db.Get(args[cliflags.Id].(string))
 		if err != nil {
-			err = fmt.Errorf("failed to retrieve value")
+			err = fmt.Errorf("failed to retrieve value: %w", err)
+			return
+		}
+		if !flags[cliflags.Clipboard].(bool) {
+			result = value
+			return
+		}
+
+		timeout := flags[cliflags.ClipboardTimeout].(time.Duration)
+		if timeout > 0 {
+			fmt.Fprintf(os.Stderr, "Copied %s to the clipboard, clearing it in %s\n", args[cliflags.Id], timeout)
 		}
+		err = cli.CopyToClipboardFor([]byte(value), timeout)
 		return
 	},
 }
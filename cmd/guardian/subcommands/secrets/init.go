@@ -16,19 +16,41 @@ var InitCommand = &commands.Command{
 	Description: "Initialize the secrets database",
 	Setup:       utils.OpenDBFile,
 	Defer:       utils.DeferSaveDB,
+	Flags: commands.Values{
+		{
+			Type:        commands.TypeString,
+			Name:        cliflags.Padding,
+			Description: "Padding hiding the number and size of the secrets: none, pow2 or fixed:SIZE[:ENTRIES]",
+			Default:     "none",
+			// Rejected before the master key is read
+			Validate: func(value any) (err error) {
+				_, err = crypto.ParsePadding(value.(string))
+				return
+			},
+		},
+	},
 	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 		// Initialize command
+		padding, err := crypto.ParsePadding(flags[cliflags.Padding].(string))
+		if err != nil {
+			return
+		}
 
 		config := database.Config{
 			Key:      ctx.MustGet(cliflags.Key).([]byte),
 			Argon:    ctx.MustGet(cliflags.Argon).(crypto.Argon),
 			SaltSize: ctx.MustGet(cliflags.SaltSize).(int),
+			Padding:  padding,
+		}
+		if keyfile, found := ctx.Get(cliflags.KeyfileHash); found {
+			config.Keyfile = keyfile.([]byte)
 		}
 		db, err := database.Open(config, strings.NewReader("{}"))
 		if err != nil {
 			err = fmt.Errorf("failed to initialize database: %w", err)
 			return
 		}
+		utils.SignDB(ctx, db)
 		ctx.Set(cliflags.Db, db)
 
 		return
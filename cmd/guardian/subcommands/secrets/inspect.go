@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var defaultPolicy = database.DefaultPolicy()
+
+var InspectCommand = &commands.Command{
+	Name:        "inspect",
+	Description: "Prints the algorithms and parameters of a database file, no key is needed",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.File, Description: "database file to inspect"},
+	},
+	Flags: commands.Values{
+		{Type: commands.TypeInt, Name: cliflags.MinArgonTime, Description: "Weakest accepted argon time", Default: int(defaultPolicy.Argon.Time)},
+		{Type: commands.TypeInt, Name: cliflags.MinArgonMemory, Description: "Weakest accepted argon memory in KiB", Default: int(defaultPolicy.Argon.Memory)},
+		{Type: commands.TypeInt, Name: cliflags.MinArgonThreads, Description: "Weakest accepted argon threads", Default: int(defaultPolicy.Argon.Threads)},
+		{Type: commands.TypeInt, Name: cliflags.MinSaltSize, Description: "Smallest accepted salt size", Default: defaultPolicy.SaltSize},
+	},
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		file, err := os.Open(args[cliflags.File].(string))
+		if err != nil {
+			err = fmt.Errorf("failed to open file: %w", err)
+			return
+		}
+		defer file.Close()
+
+		inspection, err := database.Inspect(file)
+		if err != nil {
+			return
+		}
+
+		policy := database.Policy{SaltSize: flags[cliflags.MinSaltSize].(int)}
+		policy.Argon.Time = uint32(flags[cliflags.MinArgonTime].(int))
+		policy.Argon.Memory = uint32(flags[cliflags.MinArgonMemory].(int))
+		policy.Argon.Threads = uint8(flags[cliflags.MinArgonThreads].(int))
+		policy.Assess(&inspection)
+
+		// Printed as a table by the text and table outputs
+		result = inspection
+		return
+	},
+}
@@ -13,7 +13,7 @@ var ListCommand = &commands.Command{
 	Name:        "list",
 	Description: "List all available keys",
 	Args: commands.Values{
-		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the entry"},
+		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the entry", Optional: true},
 	},
 	Setup: utils.SetupDB,
 	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
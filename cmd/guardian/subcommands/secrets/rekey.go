@@ -0,0 +1,44 @@
+package secrets
+
+import (
+	"fmt"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var RekeyCommand = &commands.Command{
+	Name:        "rekey",
+	Description: "Wraps the master key again with the -argon-* and -salt-size flags of secrets",
+	Flags: commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Yes, Description: "Don't ask for confirmation before weakening the parameters", Default: false},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		previous, previousSaltSize := db.Argon, db.SaltSize
+		err = db.Rekey(ctx.MustGet(cliflags.Argon).(crypto.Argon), ctx.MustGet(cliflags.SaltSize).(int))
+		if err != nil {
+			err = fmt.Errorf("failed to rekey database: %w", err)
+			return
+		}
+
+		if db.Downgrades() && !flags[cliflags.Yes].(bool) {
+			question := fmt.Sprintf("Weaken argon %+v salt size %d to argon %+v salt size %d?",
+				previous, previousSaltSize, db.Argon, db.SaltSize)
+			if !cli.Confirm(question) {
+				err = database.ErrDowngrade
+				return
+			}
+		}
+		db.AllowDowngrade = true
+		return
+	},
+}
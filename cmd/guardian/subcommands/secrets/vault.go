@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var (
+	ErrKeyMismatch = errors.New("keys doesn't match")
+	ErrEmptyVault  = errors.New("no entries match the prefix")
+	ErrMissingOut  = errors.New("missing -out file")
+)
+
+func readVaultKey(ctx *commands.Context, confirm bool) (key []byte, err error) {
+	prompt := !ctx.MustGet(cliflags.NoPrompt).(bool)
+
+	key, err = cli.ReadSecret("Sub-vault key", prompt)
+	if err != nil || !confirm {
+		return
+	}
+
+	again, err := cli.ReadSecret("Confirm sub-vault key", prompt)
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(key, again) {
+		err = ErrKeyMismatch
+	}
+	return
+}
+
+var ExportVaultCommand = &commands.Command{
+	Name:        "export-vault",
+	Description: "Writes the entries under a prefix to a standalone database encrypted with a different key",
+	Flags: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Prefix, Description: "Prefix of the exported ids", Default: ""},
+		{Type: commands.TypeString, Name: cliflags.Out, Description: "Database file to create", Default: ""},
+	},
+	Setup: utils.SetupDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		out := flags[cliflags.Out].(string)
+		if out == "" {
+			err = ErrMissingOut
+			return
+		}
+
+		sub, err := db.Subset(flags[cliflags.Prefix].(string))
+		if err != nil {
+			return
+		}
+		if len(sub.Secrets) == 0 {
+			err = ErrEmptyVault
+			return
+		}
+
+		sub.Key, err = readVaultKey(ctx, true)
+		if err != nil {
+			return
+		}
+		sub.Argon = ctx.MustGet(cliflags.Argon).(crypto.Argon)
+		sub.SaltSize = ctx.MustGet(cliflags.SaltSize).(int)
+		utils.SignDB(ctx, sub)
+
+		file, err := os.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			err = fmt.Errorf("failed to create sub-vault: %w", err)
+			return
+		}
+		defer file.Close()
+
+		err = sub.Save(file)
+		if err != nil {
+			err = fmt.Errorf("failed to save sub-vault: %w", err)
+			return
+		}
+
+		result, _ = sub.List()
+		return
+	},
+}
+
+var MergeCommand = &commands.Command{
+	Name:        "merge",
+	Description: "Copies the entries of a sub-vault into the database, keeping existing ones unless -overwrite is set",
+	Args: commands.Values{
+		{Type: commands.TypePath, Name: cliflags.File, Description: "Sub-vault database file"},
+	},
+	Flags: commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Overwrite, Description: "Replace existing entries with the sub-vault ones", Default: false},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		file, err := os.Open(args[cliflags.File].(string))
+		if err != nil {
+			err = fmt.Errorf("failed to open sub-vault: %w", err)
+			return
+		}
+		defer file.Close()
+
+		key, err := readVaultKey(ctx, false)
+		if err != nil {
+			return
+		}
+		config := database.Config{
+			Key:      key,
+			Argon:    ctx.MustGet(cliflags.Argon).(crypto.Argon),
+			SaltSize: ctx.MustGet(cliflags.SaltSize).(int),
+		}
+		sub, err := database.Open(config, file)
+		if err != nil {
+			err = fmt.Errorf("failed to open sub-vault: %w", err)
+			return
+		}
+
+		merged, skipped, err := db.Merge(sub, flags[cliflags.Overwrite].(bool))
+		if err != nil {
+			return
+		}
+		result = map[string][]string{"merged": merged, "skipped": skipped}
+		return
+	},
+}
@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var VerifyCommand = &commands.Command{
+	Name:        "verify",
+	Description: "Checks every entry of a database decrypts, without modifying it",
+	Flags: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.File, Description: "Database file to verify, like a backup copy, the secrets database when empty", Default: ""},
+	},
+	Setup: utils.SetupKey,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		filepath := flags[cliflags.File].(string)
+		if filepath == "" {
+			filepath = ctx.MustGet(cliflags.Secrets).(string)
+		}
+
+		file, err := os.Open(filepath)
+		if err != nil {
+			err = fmt.Errorf("failed to open file: %w", err)
+			return
+		}
+		defer file.Close()
+
+		report, err := database.Verify(utils.Config(ctx), file)
+		if err != nil {
+			err = fmt.Errorf("failed to verify %s: %w", filepath, err)
+			return
+		}
+		result = report
+		return
+	},
+}
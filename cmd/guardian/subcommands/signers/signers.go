@@ -0,0 +1,113 @@
+package signers
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+)
+
+var SignersCommand = &commands.Command{
+	Name:        "signers",
+	Description: "Manage the devices trusted to write the database",
+	Flags:       utils.DatabaseFlags(),
+	Setup:       utils.SetDatabaseFlags,
+	SubCommands: commands.Commands{
+		DeviceCommand,
+		TrustCommand,
+		UntrustCommand,
+		ListCommand,
+	},
+}
+
+var DeviceCommand = &commands.Command{
+	Name:        "device",
+	Description: "Prints the signer of this device, creating its signing key when missing",
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		filepath := ctx.MustGet(cliflags.SigningKey).(string)
+		if filepath == "" {
+			filepath = utils.DefaultSigningKey()
+		}
+
+		contents, err := os.ReadFile(filepath)
+		if errors.Is(err, os.ErrNotExist) {
+			var key ed25519.PrivateKey
+			key, err = crypto.NewSigningKey()
+			if err != nil {
+				return
+			}
+			contents = crypto.MarshalSigningKey(key)
+			err = os.WriteFile(filepath, contents, 0o600)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read signing key: %w", err)
+			return
+		}
+
+		key, err := crypto.ParseSigningKey(contents)
+		if err != nil {
+			return
+		}
+		result = crypto.EncodeSigner(key.Public().(ed25519.PublicKey))
+		return
+	},
+}
+
+var TrustCommand = &commands.Command{
+	Name:        "trust",
+	Description: "Trusts the versions of the database signed by the signer",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Signer, Description: "signer printed by signers device"},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		err = db.TrustSigner(args[cliflags.Signer].(string))
+		return
+	},
+}
+
+var UntrustCommand = &commands.Command{
+	Name:        "untrust",
+	Description: "Removes a trusted signer",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Signer, Description: "signer to remove"},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		err = db.UntrustSigner(args[cliflags.Signer].(string))
+		return
+	},
+}
+
+var ListCommand = &commands.Command{
+	Name:        "list",
+	Description: "Lists the trusted signers and the signer of the current version",
+	Setup:       utils.SetupDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		signers := make([]string, len(db.Signers))
+		copy(signers, db.Signers)
+		result = map[string]any{
+			"signers":  signers,
+			"signedBy": db.Signer,
+			"trusted":  db.Trusted(),
+		}
+		return
+	},
+}
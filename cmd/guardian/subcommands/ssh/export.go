@@ -0,0 +1,67 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	keys "github.com/RogueTeam/guardian/internal/utils/crypto"
+)
+
+var ExportCommand = &commands.Command{
+	Name:        "export",
+	Description: "Exports a stored SSH key in the OpenSSH private key format",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the private key entry"},
+	},
+	Flags: commands.Values{
+		{Type: commands.TypeBool, Name: cliflags.Passphrase, Description: "Encrypt the exported key with a passphrase", Default: false},
+		{Type: commands.TypeString, Name: cliflags.Out, Description: "File to write the key to, stdout when empty", Default: ""},
+	},
+	Setup: utils.SetupDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		id := args[cliflags.Id].(string)
+		private, err := db.Get(id)
+		if err != nil {
+			err = fmt.Errorf("failed to retrieve key: %w", err)
+			return
+		}
+		key, err := keys.ParseSSHKey([]byte(private), nil)
+		if err != nil {
+			return
+		}
+
+		data := []byte(private)
+		if flags[cliflags.Passphrase].(bool) {
+			var passphrase []byte
+			passphrase, err = readPassphrase(ctx, true)
+			if err != nil {
+				return
+			}
+
+			public, _ := db.Get(publicId(id))
+			data, err = keys.MarshalSSHKey(key, keys.SSHComment(public), passphrase)
+			if err != nil {
+				return
+			}
+		}
+
+		out := flags[cliflags.Out].(string)
+		if out == "" {
+			result = string(data)
+			return
+		}
+
+		err = os.WriteFile(out, data, 0o600)
+		if err != nil {
+			err = fmt.Errorf("failed to write key file: %w", err)
+		}
+		return
+	},
+}
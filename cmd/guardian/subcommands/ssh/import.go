@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	keys "github.com/RogueTeam/guardian/internal/utils/crypto"
+)
+
+var ImportCommand = &commands.Command{
+	Name:        "import",
+	Description: "Imports an OpenSSH private key file, prompting for its passphrase when encrypted",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the private key entry, public key is stored in id.pub"},
+		{Type: commands.TypePath, Name: cliflags.File, Description: "private key file"},
+	},
+	Flags: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Comment, Description: "Key comment", Default: ""},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		id := args[cliflags.Id].(string)
+		found, _ := db.Lookup(id)
+		if found {
+			err = fmt.Errorf("%w: %s", ErrEntryExists, id)
+			return
+		}
+
+		filename := args[cliflags.File].(string)
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			err = fmt.Errorf("failed to read key file: %w", err)
+			return
+		}
+
+		key, err := keys.ParseSSHKey(contents, nil)
+		if errors.Is(err, keys.ErrPassphraseRequired) {
+			var passphrase []byte
+			passphrase, err = readPassphrase(ctx, false)
+			if err != nil {
+				return
+			}
+			key, err = keys.ParseSSHKey(contents, passphrase)
+		}
+		if err != nil {
+			return
+		}
+
+		// Stored unencrypted, the database already protects it
+		comment := flags[cliflags.Comment].(string)
+		private, err := keys.MarshalSSHKey(key, comment, nil)
+		if err != nil {
+			return
+		}
+		public, err := keys.AuthorizedKey(key, comment)
+		if err != nil {
+			return
+		}
+
+		db.Set(id, string(private))
+		db.Set(publicId(id), public)
+
+		result = public
+		return
+	},
+}
@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"fmt"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	keys "github.com/RogueTeam/guardian/internal/utils/crypto"
+)
+
+var KeygenCommand = &commands.Command{
+	Name:        "keygen",
+	Description: "Generates a SSH key pair directly into the database",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the private key entry, public key is stored in id.pub"},
+	},
+	Flags: commands.Values{
+		{Type: commands.TypeEnum, Name: cliflags.Type, Description: "Key type", Default: keys.KeyEd25519, Choices: []string{keys.KeyEd25519, keys.KeyRSA, keys.KeyECDSA}},
+		{Type: commands.TypeInt, Name: cliflags.Bits, Description: "Key size for rsa and ecdsa keys, type default when 0", Default: 0},
+		{Type: commands.TypeString, Name: cliflags.Comment, Description: "Key comment", Default: ""},
+	},
+	Setup: utils.SetupDB,
+	Defer: utils.DeferSaveDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		id := args[cliflags.Id].(string)
+		found, _ := db.Lookup(id)
+		if found {
+			err = fmt.Errorf("%w: %s", ErrEntryExists, id)
+			return
+		}
+
+		// Generate
+		key, err := keys.NewKey(flags[cliflags.Type].(string), flags[cliflags.Bits].(int))
+		if err != nil {
+			err = fmt.Errorf("failed to generate key: %w", err)
+			return
+		}
+
+		comment := flags[cliflags.Comment].(string)
+		private, err := keys.MarshalSSHKey(key, comment, nil)
+		if err != nil {
+			return
+		}
+		public, err := keys.AuthorizedKey(key, comment)
+		if err != nil {
+			return
+		}
+
+		// Store
+		db.Set(id, string(private))
+		db.Set(publicId(id), public)
+
+		result = public
+		return
+	},
+}
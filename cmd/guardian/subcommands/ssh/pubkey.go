@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"fmt"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/commands"
+	keys "github.com/RogueTeam/guardian/internal/utils/crypto"
+)
+
+var PubkeyCommand = &commands.Command{
+	Name:        "pubkey",
+	Description: "Prints the authorized_keys line of a stored SSH key",
+	Args: commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Id, Description: "id of the private key entry"},
+	},
+	Setup: utils.SetupDB,
+	Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+		// Dependencies
+		db := ctx.MustGet(cliflags.Db).(*database.Database)
+
+		id := args[cliflags.Id].(string)
+		public, err := db.Get(publicId(id))
+		if err == nil {
+			result = public
+			return
+		}
+
+		// Fallback to derive it from the private key
+		private, err := db.Get(id)
+		if err != nil {
+			err = fmt.Errorf("failed to retrieve key: %w", err)
+			return
+		}
+		key, err := keys.ParseSSHKey([]byte(private), nil)
+		if err != nil {
+			return
+		}
+
+		result, err = keys.AuthorizedKey(key, "")
+		return
+	},
+}
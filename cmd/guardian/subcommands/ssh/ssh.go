@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+
+	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
+	"github.com/RogueTeam/guardian/cmd/guardian/utils"
+	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+var (
+	ErrEntryExists        = errors.New("entry already exists")
+	ErrPassphraseMismatch = errors.New("passphrases doesn't match")
+)
+
+var SSHCommand = &commands.Command{
+	Name:        "ssh",
+	Description: "Generate and manage SSH keys stored in the database",
+	Flags:       utils.DatabaseFlags(),
+	Setup:       utils.SetDatabaseFlags,
+	SubCommands: commands.Commands{
+		KeygenCommand,
+		PubkeyCommand,
+		ImportCommand,
+		ExportCommand,
+	},
+}
+
+// Public keys are stored next to the private one, the same way ssh-keygen does
+func publicId(id string) string {
+	return id + ".pub"
+}
+
+func readPassphrase(ctx *commands.Context, confirm bool) (passphrase []byte, err error) {
+	prompt := !ctx.MustGet(cliflags.NoPrompt).(bool)
+
+	passphrase, err = cli.ReadSecret("Passphrase", prompt)
+	if err != nil || !confirm {
+		return
+	}
+
+	again, err := cli.ReadSecret("Confirm passphrase", prompt)
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(passphrase, again) {
+		err = ErrPassphraseMismatch
+	}
+	return
+}
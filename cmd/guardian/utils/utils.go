@@ -1,27 +1,44 @@
 package utils
 
 import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 
 	cliflags "github.com/RogueTeam/guardian/cmd/guardian/flags"
 	"github.com/RogueTeam/guardian/crypto"
 	"github.com/RogueTeam/guardian/database"
 	"github.com/RogueTeam/guardian/internal/commands"
+	"github.com/RogueTeam/guardian/internal/secure"
 	"github.com/RogueTeam/guardian/internal/utils/cli"
 )
 
 func OpenDBFile(ctx *commands.Context, flags map[string]any) (err error) {
 	filepath := ctx.MustGet(cliflags.Secrets).(string)
 
-	// Open file
-	file, err := os.OpenFile(filepath, os.O_CREATE|os.O_RDWR, 0777)
+	// Open file, readers share it and DeferSaveDB waits for them before writing
+	file, err := database.OpenFile(filepath, 0777)
 	if err != nil {
 		err = fmt.Errorf("failed to open file: %s: %w", filepath, err)
 		return
 	}
 	ctx.Set(cliflags.File, file)
 
+	// Device key signing the saved database
+	err = loadSigningKey(ctx)
+	if err != nil {
+		return
+	}
+
+	return SetupKey(ctx, flags)
+}
+
+// SetupKey reads the master key, the keyfile and the identity configured in the context
+func SetupKey(ctx *commands.Context, flags map[string]any) (err error) {
 	// Setup argon
 	argon := crypto.Argon{
 		Time:    uint32(ctx.MustGet(cliflags.ArgonTime).(int)),
@@ -30,9 +47,60 @@ func OpenDBFile(ctx *commands.Context, flags map[string]any) (err error) {
 	}
 	ctx.Set(cliflags.Argon, argon)
 
+	// Keyfile combined with the master key
+	if keyfilePath, _ := ctx.Get(cliflags.Keyfile); keyfilePath != nil && keyfilePath.(string) != "" {
+		var contents []byte
+		contents, err = os.ReadFile(keyfilePath.(string))
+		if err != nil {
+			err = fmt.Errorf("failed to read keyfile: %w", err)
+			return
+		}
+		digest := crypto.HashKeyfile(contents)
+		rand.Read(contents)
+		ctx.Set(cliflags.KeyfileHash, secure.Copy(digest).Bytes())
+		rand.Read(digest)
+	}
+
+	// Identities replace the master key
+	if identityPath, _ := ctx.Get(cliflags.Identity); identityPath != nil && identityPath.(string) != "" {
+		var contents []byte
+		contents, err = os.ReadFile(identityPath.(string))
+		if err != nil {
+			err = fmt.Errorf("failed to read identity: %w", err)
+			return
+		}
+		defer rand.Read(contents)
+
+		var identity *ecdh.PrivateKey
+		identity, err = crypto.ParseIdentity(contents)
+		if err != nil {
+			return
+		}
+		ctx.Set(cliflags.IdentityKey, identity)
+		ctx.Set(cliflags.Key, []byte(nil))
+		return
+	}
+
+	// Recovery keys replace the master key
+	if recoveryKey, found := ctx.Get(cliflags.RecoveryKey); found && recoveryKey != nil {
+		ctx.Set(cliflags.Key, []byte(nil))
+		return
+	}
+
 	// User key
-	key := cli.ReadKey(!ctx.MustGet(cliflags.NoPrompt).(bool))
-	ctx.Set(cliflags.Key, key)
+	var key []byte
+	if source, _ := ctx.Get(cliflags.KeyFrom); source != nil && source.(string) != "" {
+		key, err = cli.ReadKeyFrom(source.(string))
+	} else {
+		key, err = cli.ReadKey(!ctx.MustGet(cliflags.NoPrompt).(bool))
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read master key: %w", err)
+		return
+	}
+	// Freed by secure.DestroyAll before exiting
+	ctx.Set(cliflags.Key, secure.Copy(key).Bytes())
+	rand.Read(key)
 
 	return
 }
@@ -46,37 +114,155 @@ func SetupDB(ctx *commands.Context, flags map[string]any) (err error) {
 	}
 
 	// Dependencies
-	file := ctx.MustGet(cliflags.File).(*os.File)
-	key := ctx.MustGet(cliflags.Key).([]byte)
+	file := ctx.MustGet(cliflags.File).(*database.File)
 
-	// Prepare database
-	config := database.Config{
-		Key:      key,
-		Argon:    ctx.MustGet(cliflags.Argon).(crypto.Argon),
-		SaltSize: ctx.MustGet(cliflags.SaltSize).(int),
-	}
-	db, err := database.Open(config, file)
+	db, err := database.Open(Config(ctx), file)
 	if err != nil {
 		err = fmt.Errorf("failed to open database: %w", err)
 		return
 	}
+	if len(db.Signers) > 0 && !db.Trusted() {
+		Warn(ctx, fmt.Sprintf("database signed by an unknown signer: %q", db.Signer))
+	}
+	SignDB(ctx, db)
 	ctx.Set(cliflags.Db, db)
 
 	return
 }
 
+// Config returns the database configuration with the keys read by SetupKey
+func Config(ctx *commands.Context) (config database.Config) {
+	config = database.Config{
+		Key:      ctx.MustGet(cliflags.Key).([]byte),
+		Argon:    ctx.MustGet(cliflags.Argon).(crypto.Argon),
+		SaltSize: ctx.MustGet(cliflags.SaltSize).(int),
+	}
+	if identity, found := ctx.Get(cliflags.IdentityKey); found {
+		config.Identity = identity.(*ecdh.PrivateKey)
+	}
+	if keyfile, found := ctx.Get(cliflags.KeyfileHash); found {
+		config.Keyfile = keyfile.([]byte)
+	}
+	if recoveryKey, found := ctx.Get(cliflags.RecoveryKey); found {
+		config.RecoveryKey = recoveryKey.([]byte)
+	}
+	if require, found := ctx.Get(cliflags.RequireSigner); found {
+		config.RequireTrustedSigner = require.(bool)
+	}
+	return
+}
+
 func DeferSaveDB(ctx *commands.Context, result any) (finalResult any, err error) {
 	finalResult = result
 
 	// Dependencies
-	file := ctx.MustGet(cliflags.File).(*os.File)
+	file := ctx.MustGet(cliflags.File).(*database.File)
 
 	db := ctx.MustGet(cliflags.Db).(*database.Database)
 
-	// Save changes
-	_, err = file.Seek(0, 0)
-	if err == nil {
-		err = db.Save(file)
+	// Save changes, the file is only replaced once they are written
+	err = file.Save(db)
+	return
+}
+
+// DefaultSigningKey returns the per user signing key of the device
+func DefaultSigningKey() string {
+	return path.Join(cli.Home(), ".guardian-signing-key")
+}
+
+// loadSigningKey reads the signing key of the device, the default one is optional
+func loadSigningKey(ctx *commands.Context) (err error) {
+	keyPath, _ := ctx.Get(cliflags.SigningKey)
+	if keyPath == nil {
+		return
+	}
+	filepath := keyPath.(string)
+	if filepath == "" {
+		filepath = DefaultSigningKey()
+	}
+
+	contents, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) && keyPath.(string) == "" {
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read signing key: %w", err)
+		return
+	}
+	defer rand.Read(contents)
+
+	key, err := crypto.ParseSigningKey(contents)
+	if err != nil {
+		return
+	}
+	// Freed by secure.DestroyAll before exiting, crypto.Sign signs with a heap copy
+	ctx.Set(cliflags.DeviceKey, ed25519.PrivateKey(secure.Copy(key).Bytes()))
+	rand.Read(key)
+	return
+}
+
+// Warn reports a problem that doesn't stop the command, main writes the warnings in the output format
+func Warn(ctx *commands.Context, warning string) {
+	value, _ := ctx.Get(cliflags.Warnings)
+	warnings, _ := value.([]string)
+	ctx.Set(cliflags.Warnings, append(warnings, warning))
+}
+
+// SignDB signs the database with the device key on save
+func SignDB(ctx *commands.Context, db *database.Database) {
+	if key, found := ctx.Get(cliflags.DeviceKey); found {
+		db.SigningKey = key.(ed25519.PrivateKey)
 	}
+}
+
+var defaultArgon = crypto.DefaultArgon()
+
+// KeyFlags returns the flags reading the master key and the argon parameters it is derived with
+func KeyFlags() commands.Values {
+	return commands.Values{
+		{Type: commands.TypeInt, Name: cliflags.SaltSize, Description: "Salt size of new databases and files, see secrets rekey", Default: crypto.DefaultSaltSize},
+		{Type: commands.TypeInt, Name: cliflags.ArgonTime, Description: "Argon time of new databases and files, see secrets rekey", Default: int(defaultArgon.Time)},
+		{Type: commands.TypeInt, Name: cliflags.ArgonMemory, Description: "Argon memory in KiB of new databases and files, see secrets rekey", Default: int(defaultArgon.Memory)},
+		{Type: commands.TypeInt, Name: cliflags.ArgonThreads, Description: "Argon threads of new databases and files, see secrets rekey", Default: int(defaultArgon.Threads)},
+		{Type: commands.TypeBool, Name: cliflags.NoPrompt, Description: "No password prompt", Default: false},
+		{Type: commands.TypeString, Name: cliflags.Keyfile, Description: "Keyfile combined with the master key", Default: ""},
+		{Type: commands.TypeString, Name: cliflags.KeyFrom, Description: "Reads the master key from env:VAR, fd:N, file:PATH or cmd:COMMAND instead of the terminal", Default: ""},
+	}
+}
+
+// SetKeyFlags copies the flags returned by KeyFlags into the context
+func SetKeyFlags(ctx *commands.Context, flags map[string]any) (err error) {
+	ctx.Set(cliflags.SaltSize, flags[cliflags.SaltSize])
+	ctx.Set(cliflags.ArgonTime, flags[cliflags.ArgonTime])
+	ctx.Set(cliflags.ArgonMemory, flags[cliflags.ArgonMemory])
+	ctx.Set(cliflags.ArgonThreads, flags[cliflags.ArgonThreads])
+	ctx.Set(cliflags.NoPrompt, flags[cliflags.NoPrompt])
+	ctx.Set(cliflags.Keyfile, flags[cliflags.Keyfile])
+	ctx.Set(cliflags.KeyFrom, flags[cliflags.KeyFrom])
+
+	return
+}
+
+// DatabaseFlags returns the flags needed by commands that open the secrets database
+func DatabaseFlags() commands.Values {
+	flags := commands.Values{
+		{Type: commands.TypeString, Name: cliflags.Secrets, Description: "Secrets database to use", Default: path.Join(cli.Home(), "guardian.json")},
+	}
+	flags = append(flags, KeyFlags()...)
+	return append(flags,
+		commands.Value{Type: commands.TypeString, Name: cliflags.SigningKey, Description: "Signing key of the device, " + DefaultSigningKey() + " when it exists", Default: ""},
+		commands.Value{Type: commands.TypeBool, Name: cliflags.RequireSigner, Description: "Refuses databases not signed by a trusted signer", Default: false},
+		commands.Value{Type: commands.TypeString, Name: cliflags.Identity, Description: "Identity file to open the database with instead of the master key", Default: ""},
+	)
+}
+
+// SetDatabaseFlags copies the flags returned by DatabaseFlags into the context
+func SetDatabaseFlags(ctx *commands.Context, flags map[string]any) (err error) {
+	ctx.Set(cliflags.Secrets, flags[cliflags.Secrets])
+	SetKeyFlags(ctx, flags)
+	ctx.Set(cliflags.SigningKey, flags[cliflags.SigningKey])
+	ctx.Set(cliflags.RequireSigner, flags[cliflags.RequireSigner])
+	ctx.Set(cliflags.Identity, flags[cliflags.Identity])
+
 	return
 }
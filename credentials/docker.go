@@ -0,0 +1,176 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Operations of the docker credential helper protocol
+const (
+	DockerStore = "store"
+	DockerGet   = "get"
+	DockerErase = "erase"
+	DockerList  = "list"
+)
+
+// DefaultDockerPrefix groups registry credentials like registry/ghcr.io
+const DefaultDockerPrefix = "registry/"
+
+var (
+	// Docker expects this exact message to report missing credentials
+	ErrCredentialsNotFound = errors.New("credentials not found in native keychain")
+	ErrMissingServerURL    = errors.New("no credentials server URL")
+	ErrMissingUsername     = errors.New("no credentials username")
+)
+
+// DockerCredentials is the payload exchanged with docker
+type DockerCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// Docker implements the docker credential helper protocol used by credsStore
+// https://github.com/docker/docker-credential-helpers
+type Docker struct {
+	Database *database.Database
+	Prefix   string
+}
+
+func (d *Docker) prefix() string {
+	if d.Prefix == "" {
+		return DefaultDockerPrefix
+	}
+	return d.Prefix
+}
+
+// Id returns the database id of the server URL.
+// The scheme and trailing slashes are ignored so https://ghcr.io/ and ghcr.io share the entry
+func (d *Docker) Id(serverURL string) (id string) {
+	_, host, found := strings.Cut(serverURL, "://")
+	if !found {
+		host = serverURL
+	}
+	return d.prefix() + strings.TrimRight(host, "/")
+}
+
+func readServerURL(r io.Reader) (serverURL string, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("failed to read server URL: %w", err)
+		return
+	}
+
+	serverURL = strings.TrimSpace(string(data))
+	if serverURL == "" {
+		err = ErrMissingServerURL
+	}
+	return
+}
+
+// Store saves the credentials sent by docker login
+func (d *Docker) Store(r io.Reader) (err error) {
+	var creds DockerCredentials
+	err = json.NewDecoder(r).Decode(&creds)
+	if err != nil {
+		err = fmt.Errorf("failed to decode credentials: %w", err)
+		return
+	}
+
+	if creds.ServerURL == "" {
+		err = ErrMissingServerURL
+		return
+	}
+	if creds.Username == "" {
+		err = ErrMissingUsername
+		return
+	}
+
+	id := d.Id(creds.ServerURL)
+	d.Database.Set(id, creds.Secret)
+	d.Database.SetField(id, database.FieldUsername, creds.Username)
+	d.Database.SetField(id, database.FieldURL, creds.ServerURL)
+	return
+}
+
+// Get writes the credentials of the server URL read from r
+func (d *Docker) Get(r io.Reader, w io.Writer) (err error) {
+	serverURL, err := readServerURL(r)
+	if err != nil {
+		return
+	}
+
+	id := d.Id(serverURL)
+	secret, err := d.Database.Get(id)
+	if err != nil {
+		err = ErrCredentialsNotFound
+		return
+	}
+
+	creds := DockerCredentials{ServerURL: serverURL, Secret: secret}
+	creds.Username, _ = d.Database.GetField(id, database.FieldUsername)
+
+	err = json.NewEncoder(w).Encode(creds)
+	return
+}
+
+// Erase removes the credentials of the server URL read from r
+func (d *Docker) Erase(r io.Reader) (err error) {
+	serverURL, err := readServerURL(r)
+	if err != nil {
+		return
+	}
+
+	err = d.Database.Del(d.Id(serverURL))
+	if err != nil {
+		err = ErrCredentialsNotFound
+	}
+	return
+}
+
+// List writes a map of server URLs to usernames
+func (d *Docker) List(w io.Writer) (err error) {
+	ids, err := d.Database.List()
+	if err != nil {
+		err = fmt.Errorf("failed to list entries: %w", err)
+		return
+	}
+
+	servers := make(map[string]string)
+	for _, id := range ids {
+		if !strings.HasPrefix(id, d.prefix()) {
+			continue
+		}
+
+		serverURL, fieldErr := d.Database.GetField(id, database.FieldURL)
+		if fieldErr != nil {
+			serverURL = strings.TrimPrefix(id, d.prefix())
+		}
+		servers[serverURL], _ = d.Database.GetField(id, database.FieldUsername)
+	}
+
+	err = json.NewEncoder(w).Encode(servers)
+	return
+}
+
+// Serve runs the requested operation reading from r and answering to w
+func (d *Docker) Serve(operation string, r io.Reader, w io.Writer) (err error) {
+	switch operation {
+	case DockerStore:
+		err = d.Store(r)
+	case DockerGet:
+		err = d.Get(r, w)
+	case DockerErase:
+		err = d.Erase(r)
+	case DockerList:
+		err = d.List(w)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownOperation, operation)
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package credentials_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RogueTeam/guardian/credentials"
+	"github.com/RogueTeam/guardian/database"
+)
+
+func TestDocker_Serve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Step struct {
+			Operation string
+			Input     string
+			Expect    string
+		}
+
+		type Test struct {
+			Name   string
+			Prefix string
+			Steps  []Step
+			Id     string
+		}
+
+		const store = `{"ServerURL":"https://ghcr.io/","Username":"sulcud","Secret":"token"}`
+
+		tests := []Test{
+			{
+				Name: "Store and get",
+				Steps: []Step{
+					{credentials.DockerStore, store, ""},
+					{credentials.DockerGet, "https://ghcr.io/\n", `{"ServerURL":"https://ghcr.io/","Username":"sulcud","Secret":"token"}` + "\n"},
+					{credentials.DockerGet, "ghcr.io", `{"ServerURL":"ghcr.io","Username":"sulcud","Secret":"token"}` + "\n"},
+				},
+				Id: "registry/ghcr.io",
+			},
+			{
+				Name:   "Custom prefix",
+				Prefix: "docker/",
+				Steps: []Step{
+					{credentials.DockerStore, store, ""},
+				},
+				Id: "docker/ghcr.io",
+			},
+			{
+				Name: "List",
+				Steps: []Step{
+					{credentials.DockerStore, store, ""},
+					{credentials.DockerStore, `{"ServerURL":"registry.example.com","Username":"ci","Secret":"token"}`, ""},
+					{credentials.DockerList, "", `{"https://ghcr.io/":"sulcud","registry.example.com":"ci"}` + "\n"},
+				},
+				Id: "registry/registry.example.com",
+			},
+			{
+				Name: "Erase",
+				Steps: []Step{
+					{credentials.DockerStore, store, ""},
+					{credentials.DockerErase, "https://ghcr.io/", ""},
+					{credentials.DockerList, "", "{}\n"},
+				},
+			},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				d := &credentials.Docker{Database: database.New(), Prefix: test.Prefix}
+				// Entries outside the prefix are ignored
+				d.Database.Set("other", "secret")
+
+				for _, step := range test.Steps {
+					output, err := serve(d.Serve, step.Operation, step.Input)
+					if err != nil {
+						t.Fatalf("expecting no errors, but received: %v", err)
+					}
+					if output != step.Expect {
+						t.Fatalf("expecting %q but received: %q", step.Expect, output)
+					}
+				}
+
+				if test.Id == "" {
+					return
+				}
+				found, _ := d.Database.Lookup(test.Id)
+				if !found {
+					t.Fatalf("expecting entry with id: %s", test.Id)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name      string
+			Operation string
+			Input     string
+			Expect    error
+		}
+
+		tests := []Test{
+			{"Unknown operation", "version", "", credentials.ErrUnknownOperation},
+			{"Get not found", credentials.DockerGet, "ghcr.io", credentials.ErrCredentialsNotFound},
+			{"Erase not found", credentials.DockerErase, "ghcr.io", credentials.ErrCredentialsNotFound},
+			{"Get missing server URL", credentials.DockerGet, "\n", credentials.ErrMissingServerURL},
+			{"Store missing server URL", credentials.DockerStore, `{"Username":"sulcud","Secret":"token"}`, credentials.ErrMissingServerURL},
+			{"Store missing username", credentials.DockerStore, `{"ServerURL":"ghcr.io","Secret":"token"}`, credentials.ErrMissingUsername},
+			{"Store invalid JSON", credentials.DockerStore, `{`, nil},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				d := &credentials.Docker{Database: database.New()}
+				_, err := serve(d.Serve, test.Operation, test.Input)
+				if err == nil {
+					t.Fatal("expecting error")
+				}
+				if test.Expect != nil && !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,238 @@
+// Package credentials implements the credential helper protocols of third party tools
+// backed by the guardian database
+package credentials
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Operations of the git credential helper protocol
+const (
+	GitGet   = "get"
+	GitStore = "store"
+	GitErase = "erase"
+)
+
+// Attributes exchanged with git
+const (
+	GitProtocol = "protocol"
+	GitHost     = "host"
+	GitPath     = "path"
+	GitUsername = "username"
+	GitPassword = "password"
+	GitURL      = "url"
+)
+
+// DefaultGitPattern maps credentials to entries like git/https/github.com
+const DefaultGitPattern = "git/{protocol}/{host}/{path}"
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrInvalidAttribute = errors.New("invalid attribute")
+	ErrMissingHost      = errors.New("missing host attribute")
+)
+
+// Git implements the git credential helper protocol
+// https://git-scm.com/docs/git-credential
+type Git struct {
+	Database *database.Database
+	// Pattern used to build the entry id. Supports {protocol}, {host}, {path} and {username}
+	Pattern string
+}
+
+// ReadAttributes reads key=value lines until a blank line or EOF
+func ReadAttributes(r io.Reader) (attrs map[string]string, err error) {
+	attrs = make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			err = fmt.Errorf("%w: %s", ErrInvalidAttribute, line)
+			return
+		}
+		attrs[key] = value
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("failed to read attributes: %w", err)
+	}
+	return
+}
+
+// WriteAttributes writes the attributes as key=value lines sorted by key
+func WriteAttributes(w io.Writer, attrs map[string]string) (err error) {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		_, err = fmt.Fprintf(w, "%s=%s\n", key, attrs[key])
+		if err != nil {
+			err = fmt.Errorf("failed to write attribute: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// Id returns the database id for the attributes sent by git
+func (g *Git) Id(attrs map[string]string) (id string, err error) {
+	// Newer versions of git may only send the url
+	if rawURL, found := attrs[GitURL]; found && attrs[GitHost] == "" {
+		var u *url.URL
+		u, err = url.Parse(rawURL)
+		if err != nil {
+			err = fmt.Errorf("%w: %s: %w", ErrInvalidAttribute, GitURL, err)
+			return
+		}
+		attrs[GitProtocol] = u.Scheme
+		attrs[GitHost] = u.Host
+		attrs[GitPath] = strings.TrimPrefix(u.Path, "/")
+		if u.User != nil && attrs[GitUsername] == "" {
+			attrs[GitUsername] = u.User.Username()
+		}
+	}
+
+	if attrs[GitHost] == "" {
+		err = ErrMissingHost
+		return
+	}
+
+	// Values sent by git must not reach entries of other hosts
+	for _, key := range []string{GitProtocol, GitHost, GitUsername} {
+		if strings.Contains(attrs[key], "/") {
+			err = fmt.Errorf("%w: %s: %q", ErrInvalidAttribute, key, attrs[key])
+			return
+		}
+	}
+	for _, key := range []string{GitProtocol, GitHost, GitUsername, GitPath} {
+		for _, segment := range strings.Split(attrs[key], "/") {
+			if segment == "." || segment == ".." {
+				err = fmt.Errorf("%w: %s: %q", ErrInvalidAttribute, key, attrs[key])
+				return
+			}
+		}
+	}
+
+	pattern := g.Pattern
+	if pattern == "" {
+		pattern = DefaultGitPattern
+	}
+
+	id = strings.NewReplacer(
+		"{protocol}", attrs[GitProtocol],
+		"{host}", attrs[GitHost],
+		"{path}", attrs[GitPath],
+		"{username}", attrs[GitUsername],
+	).Replace(pattern)
+
+	// Placeholders not sent by git should not leave empty components
+	components := strings.FieldsFunc(id, func(r rune) bool { return r == '/' })
+	id = strings.Join(components, "/")
+	return
+}
+
+// Get writes the username and password of the matching entry.
+// Nothing is written when there is no match so git can try other helpers
+func (g *Git) Get(r io.Reader, w io.Writer) (err error) {
+	attrs, err := ReadAttributes(r)
+	if err != nil {
+		return
+	}
+
+	id, err := g.Id(attrs)
+	if err != nil {
+		return
+	}
+
+	password, err := g.Database.Get(id)
+	if err != nil {
+		err = nil
+		return
+	}
+
+	username, _ := g.Database.GetField(id, database.FieldUsername)
+	if attrs[GitUsername] != "" && username != "" && attrs[GitUsername] != username {
+		return
+	}
+
+	response := map[string]string{GitPassword: password}
+	if username != "" {
+		response[GitUsername] = username
+	}
+	err = WriteAttributes(w, response)
+	return
+}
+
+// Store saves the credentials approved by git
+func (g *Git) Store(r io.Reader) (err error) {
+	attrs, err := ReadAttributes(r)
+	if err != nil {
+		return
+	}
+
+	id, err := g.Id(attrs)
+	if err != nil {
+		return
+	}
+
+	g.Database.Set(id, attrs[GitPassword])
+	if attrs[GitUsername] != "" {
+		g.Database.SetField(id, database.FieldUsername, attrs[GitUsername])
+	}
+	return
+}
+
+// Erase removes the credentials rejected by git
+func (g *Git) Erase(r io.Reader) (err error) {
+	attrs, err := ReadAttributes(r)
+	if err != nil {
+		return
+	}
+
+	id, err := g.Id(attrs)
+	if err != nil {
+		return
+	}
+
+	username, _ := g.Database.GetField(id, database.FieldUsername)
+	if attrs[GitUsername] != "" && username != "" && attrs[GitUsername] != username {
+		return
+	}
+
+	// Erasing something that doesn't exists is not an error for git
+	g.Database.Del(id)
+	return
+}
+
+// Serve runs the requested operation reading from r and answering to w
+func (g *Git) Serve(operation string, r io.Reader, w io.Writer) (err error) {
+	switch operation {
+	case GitGet:
+		err = g.Get(r, w)
+	case GitStore:
+		err = g.Store(r)
+	case GitErase:
+		err = g.Erase(r)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownOperation, operation)
+	}
+	return
+}
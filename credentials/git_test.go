@@ -0,0 +1,166 @@
+package credentials_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/RogueTeam/guardian/credentials"
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Drives a helper the same way git and docker do, through pipes
+func serve(helper func(operation string, r io.Reader, w io.Writer) error, operation, input string) (output string, err error) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	go func() {
+		io.WriteString(inW, input)
+		inW.Close()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- helper(operation, inR, outW)
+		inR.Close()
+		outW.Close()
+	}()
+
+	data, _ := io.ReadAll(outR)
+	output = string(data)
+	err = <-errCh
+	return
+}
+
+func TestGit_Serve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Step struct {
+			Operation string
+			Input     string
+			Expect    string
+		}
+
+		type Test struct {
+			Name    string
+			Pattern string
+			Steps   []Step
+			Id      string
+		}
+
+		const store = "protocol=https\nhost=github.com\nusername=sulcud\npassword=secret\n\n"
+
+		tests := []Test{
+			{
+				Name: "Store and get",
+				Steps: []Step{
+					{credentials.GitStore, store, ""},
+					{credentials.GitGet, "protocol=https\nhost=github.com\n\n", "password=secret\nusername=sulcud\n"},
+				},
+				Id: "git/https/github.com",
+			},
+			{
+				Name: "Get unknown host",
+				Steps: []Step{
+					{credentials.GitStore, store, ""},
+					{credentials.GitGet, "protocol=https\nhost=gitlab.com\n\n", ""},
+				},
+				Id: "git/https/github.com",
+			},
+			{
+				Name: "Get with other username",
+				Steps: []Step{
+					{credentials.GitStore, store, ""},
+					{credentials.GitGet, "protocol=https\nhost=github.com\nusername=other\n\n", ""},
+				},
+				Id: "git/https/github.com",
+			},
+			{
+				Name: "Erase",
+				Steps: []Step{
+					{credentials.GitStore, store, ""},
+					{credentials.GitErase, "protocol=https\nhost=github.com\nusername=sulcud\n\n", ""},
+					{credentials.GitGet, "protocol=https\nhost=github.com\n\n", ""},
+				},
+			},
+			{
+				Name:    "Custom pattern with path",
+				Pattern: "{host}/{path}/{username}",
+				Steps: []Step{
+					{credentials.GitStore, "protocol=https\nhost=example.com\npath=org/repo.git\nusername=sulcud\npassword=secret\n", ""},
+					{credentials.GitGet, "protocol=https\nhost=example.com\npath=org/repo.git\nusername=sulcud\n", "password=secret\nusername=sulcud\n"},
+				},
+				Id: "example.com/org/repo.git/sulcud",
+			},
+			{
+				Name: "URL attribute",
+				Steps: []Step{
+					{credentials.GitStore, store, ""},
+					{credentials.GitGet, "url=https://github.com\n\n", "password=secret\nusername=sulcud\n"},
+				},
+				Id: "git/https/github.com",
+			},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				g := &credentials.Git{Database: database.New(), Pattern: test.Pattern}
+				for _, step := range test.Steps {
+					output, err := serve(g.Serve, step.Operation, step.Input)
+					if err != nil {
+						t.Fatalf("expecting no errors, but received: %v", err)
+					}
+					if output != step.Expect {
+						t.Fatalf("expecting %q but received: %q", step.Expect, output)
+					}
+				}
+
+				if test.Id == "" {
+					return
+				}
+				found, _ := g.Database.Lookup(test.Id)
+				if !found {
+					t.Fatalf("expecting entry with id: %s", test.Id)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name      string
+			Operation string
+			Input     string
+		}
+
+		tests := []Test{
+			{"Unknown operation", "list", "protocol=https\nhost=github.com\n"},
+			{"Invalid attribute", credentials.GitGet, "protocol\n"},
+			{"Missing host", credentials.GitStore, "protocol=https\npassword=secret\n"},
+			{"Invalid URL", credentials.GitGet, "url=://github.com\n"},
+			{"Path traversal", credentials.GitGet, "protocol=https\nhost=evil.com\npath=../github.com/org\n"},
+			{"URL traversal", credentials.GitGet, "url=https://evil.com/../../github.com\n"},
+			{"Host with slash", credentials.GitGet, "protocol=https\nhost=evil.com/../github.com\n"},
+			{"Dot host", credentials.GitGet, "protocol=https\nhost=..\n"},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				g := &credentials.Git{Database: database.New()}
+				_, err := serve(g.Serve, test.Operation, test.Input)
+				if err == nil {
+					t.Fatal("expecting error")
+				}
+			})
+		}
+	})
+}
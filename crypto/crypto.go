@@ -7,8 +7,11 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"errors"
+	"io"
 
+	"github.com/RogueTeam/guardian/internal/secure"
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -18,6 +21,13 @@ var (
 
 const DefaultSaltSize = 1024
 
+// Key derivation functions
+const (
+	KDFArgon2id = ""
+	// Only for uniformly random keys, like the data key of a database
+	KDFHKDF = "hkdf"
+)
+
 const (
 	ChecksumSize = 512 / 8
 	ChunkSize    = 256
@@ -29,6 +39,8 @@ const (
 )
 
 type Secret struct {
+	// Key derivation function, argon2id when empty
+	KDF string `json:"kdf,omitempty"`
 	// Configuration for the argon function
 	Argon Argon `json:"argon"`
 
@@ -69,6 +81,12 @@ type Argon struct {
 	Threads uint8  `json:"threads"`
 }
 
+// Weaker reports whether the time or the memory cost is below the one of other.
+// Threads don't change the cost of a guess and are ignored
+func (a Argon) Weaker(other Argon) bool {
+	return a.Time < other.Time || a.Memory < other.Memory
+}
+
 func (a *Argon) Release() {
 	a.Time = 0
 	a.Memory = 0
@@ -79,11 +97,20 @@ type Job struct {
 	Key, Data []byte
 	Argon     Argon
 	SaltSize  int
+	KDF       string
+	// Locked memory holding the decrypted Data
+	plain *secure.Buffer
 }
 
+// Release wipes the key and data, decrypted data is no longer valid after it
 func (j *Job) Release() {
 	rand.Read(j.Key)
 	rand.Read(j.Data)
+	if j.plain != nil {
+		j.plain.Destroy()
+		j.plain = nil
+		j.Data = nil
+	}
 	j.Argon.Release()
 	j.SaltSize = 0
 }
@@ -92,6 +119,7 @@ func (j *Job) Release() {
 func (j *Job) Encrypt() (secret *Secret) {
 	dataLength := ChunkSize * (1 + len(j.Data)/ChunkSize)
 	secret = &Secret{
+		KDF:      j.KDF,
 		Argon:    j.Argon,
 		IV:       make([]byte, IVSize),
 		KeySalt:  make([]byte, j.SaltSize),
@@ -104,14 +132,17 @@ func (j *Job) Encrypt() (secret *Secret) {
 	rand.Read(secret.HMACSalt)
 
 	// Prepare data to encrypt
-	data := make([]byte, dataLength)
-	defer rand.Read(data)
+	buffer := secure.New(dataLength)
+	defer buffer.Destroy()
+	data := buffer.Bytes()
 	copy(data, j.Data)
 	rand.Read(data[len(j.Data):])
 	data[dataLength-1] = byte(dataLength - len(j.Data))
 
 	// Prepare encryption key
-	key := argon2.IDKey(j.Key, secret.KeySalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, KeySize)
+	key, hmacKey := j.keys(secret)
+	defer rand.Read(key)
+	defer rand.Read(hmacKey)
 
 	// Encrypt data
 	// Error doesn't need verification because key is always of valid size, thanks to argon
@@ -120,7 +151,6 @@ func (j *Job) Encrypt() (secret *Secret) {
 	enc.CryptBlocks(secret.Cipher, data)
 
 	// Calculate HMAC sum
-	hmacKey := argon2.IDKey(key, secret.HMACSalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, HMACKeySize)
 	hash := hmac.New(sha3.New512, hmacKey)
 	hash.Write(secret.Cipher)
 	secret.HMAC = hash.Sum(nil)
@@ -132,10 +162,11 @@ func (j *Job) Encrypt() (secret *Secret) {
 // On failure returns ErrDecryptionFailed
 func (j *Job) Decrypt(secret *Secret) (err error) {
 	// Prepare decryption key
-	key := argon2.IDKey(j.Key, secret.KeySalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, KeySize)
+	key, hmacKey := j.keys(secret)
+	defer rand.Read(key)
+	defer rand.Read(hmacKey)
 
 	// Verify HMAC
-	hmacKey := argon2.IDKey(key, secret.HMACSalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, HMACKeySize)
 	hash := hmac.New(sha3.New512, hmacKey)
 	hash.Write(secret.Cipher)
 	computedHMAC := hash.Sum(nil)
@@ -144,8 +175,9 @@ func (j *Job) Decrypt(secret *Secret) (err error) {
 	}
 
 	// Prepare decrypt buffer
-	data := make([]byte, len(secret.Cipher))
-	defer rand.Read(data)
+	buffer := secure.New(len(secret.Cipher))
+	defer buffer.Destroy()
+	data := buffer.Bytes()
 
 	// Decrypt data
 	block, _ := aes.NewCipher(key)
@@ -153,9 +185,32 @@ func (j *Job) Decrypt(secret *Secret) (err error) {
 	enc.CryptBlocks(data, secret.Cipher)
 
 	// Copy Data
-	realLength := len(data) - int(data[len(data)-1])
-	j.Data = make([]byte, realLength)
-	copy(j.Data, data[:realLength])
+	// A full chunk of padding doesn't fit in a byte and is stored as zero
+	padding := int(data[len(data)-1])
+	if padding == 0 {
+		padding = ChunkSize
+	}
+	realLength := len(data) - padding
+	if j.plain != nil {
+		j.plain.Destroy()
+	}
+	j.plain = secure.Copy(data[:realLength])
+	j.Data = j.plain.Bytes()
 
 	return err
 }
+
+// Derives the encryption and HMAC keys with the KDF of the secret
+func (j *Job) keys(secret *Secret) (key, hmacKey []byte) {
+	if secret.KDF == KDFHKDF {
+		key = make([]byte, KeySize)
+		io.ReadFull(hkdf.New(sha3.New512, j.Key, secret.KeySalt, []byte("guardian key")), key)
+		hmacKey = make([]byte, HMACKeySize)
+		io.ReadFull(hkdf.New(sha3.New512, key, secret.HMACSalt, []byte("guardian hmac")), hmacKey)
+		return
+	}
+
+	key = argon2.IDKey(j.Key, secret.KeySalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, KeySize)
+	hmacKey = argon2.IDKey(key, secret.HMACSalt, secret.Argon.Time, secret.Argon.Memory, secret.Argon.Threads, HMACKeySize)
+	return
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/RogueTeam/guardian/crypto"
@@ -22,9 +24,10 @@ func Test_Encryption(t *testing.T) {
 		}
 
 		tests := []Test{
-			{"Basic", crypto.Job{testsuite.Random(16), testsuite.Random(16), crypto.DefaultArgon(), 16}},
-			{"Empty Key", crypto.Job{make([]byte, 16), testsuite.Random(16), crypto.DefaultArgon(), 16}},
-			{"Empty Data", crypto.Job{testsuite.Random(16), make([]byte, 16), crypto.DefaultArgon(), 16}},
+			{"Basic", crypto.Job{Key: testsuite.Random(16), Data: testsuite.Random(16), Argon: crypto.DefaultArgon(), SaltSize: 16}},
+			{"Empty Key", crypto.Job{Key: make([]byte, 16), Data: testsuite.Random(16), Argon: crypto.DefaultArgon(), SaltSize: 16}},
+			{"Empty Data", crypto.Job{Key: testsuite.Random(16), Data: make([]byte, 16), Argon: crypto.DefaultArgon(), SaltSize: 16}},
+			{"HKDF", crypto.Job{Key: testsuite.Random(crypto.KeySize), Data: testsuite.Random(1024), SaltSize: 16, KDF: crypto.KDFHKDF}},
 		}
 
 		for _, test := range tests {
@@ -38,6 +41,7 @@ func Test_Encryption(t *testing.T) {
 					Data:     test.Data,
 					Argon:    test.Argon,
 					SaltSize: test.SaltSize,
+					KDF:      test.KDF,
 				}
 				defer encryption.Release()
 				secret := encryption.Encrypt()
@@ -70,6 +74,44 @@ func Test_Encryption(t *testing.T) {
 func TestJob_Decrypt(t *testing.T) {
 	t.Parallel()
 
+	// Secrets encrypted by the first version of guardian with the key "baseline".
+	// A full chunk of padding is stored as zero, the baseline decrypted it with the padding appended
+	t.Run("Baseline", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Secret string
+			Expect string
+		}
+		tests := []Test{
+			{Name: "Short", Expect: "password", Secret: `{"argon":{"time":1,"memory":64,"threads":1},"iv":"uQbfK34uB9cV2w5Gr7Zazw==","keySalt":"NIg+3/STrGzkZE5GDKKlCQ==","hmacSalt":"Hf9WoZ+9jkYkWq39xXkydw==","cipher":"tYMHhgkDgp9dbv74kigznKYUtf4Ar6Ty087csvNAwsbLWVuGz4Hn12vJhsts0QtlCaJTcMqCNbby2e/eBtXfL2lFgoVuJkp8blidgAiPOnaxIZh1nhCEqYG/rrmx+5vlqTed0vEju5Lbn0z8IvEgCreGo5sqW9zDfZzAZmV5u6lja3bWjUzuck7P5Ds+TkWhFSjQDR+hh8fFoEAqtEfekHJCl8bMPNMKt4nvJk9/9oqNYwwcXEDbkfuxgj1e8OsIwBYxNOjuVczSccdV0WdPkj1GS4MxlR1EKkkhnVzjHuahqADv2lTCQ5dfUPc1287X6sVZ7llVYfOjAj+ApGIC/Q==","hmac":"jLtkV5+YPl9+13ai0F/tPGuoFvuh8nWz0zK4t8m1epPRtOmBNozaoQ7LiSfzsAo240pCtpnCYdGO5pQ/CL+q3w=="}`},
+			{Name: "Partial chunk", Expect: strings.Repeat("x", 200), Secret: `{"argon":{"time":1,"memory":64,"threads":1},"iv":"1/QozCexImgWwSAA/Z48zQ==","keySalt":"za0+LNo62ZeN/QMqf4YFoQ==","hmacSalt":"BULIyVIoq+N/pSvmIHYMDg==","cipher":"+TULIa3jisRAoCs34jR3Qi1fW+AjztzR/StrXqWc9bJBkvatF9tjD1hdx011Exm3b1PeivwXy4nePZAGVwtn60m4fRoP9Xu0FPNPKXrZjXhRN+RreZ94K5bOE8K8amhLJ3Nk8YIpLrJ1+7gJGRnHMbSdVA+SdZbMD4WllPnXllhO6/hGw+7dY1MRow9OqOQ7r1XZgZrxJWv5T8i9txuUdypuLp7Wat5jjNMQOpW3eg9x6S9ntFzBvbrLPpSteCFZOkG2JHPR0W/cI4Ica6Khi6fXw8cDmn4uDILResy1b0I4CwkTfWJdoP2d5FYUwYP5WqMDi5RL54Q9dbmczV6e1w==","hmac":"m7rxcebOhYKPkYAkyzKd1Fc+oNWhk4RUNOcBIwHPYMVbwXxHDCTxpVFBZ2HN1aG5nGIfA4YJJq4hMT250QdZGA=="}`},
+			{Name: "Full chunk", Expect: strings.Repeat("y", 256), Secret: `{"argon":{"time":1,"memory":64,"threads":1},"iv":"eNcR7aQRmzZC7z2psHwAyg==","keySalt":"ZB7w2Jdy11N8vdbS1iPMUQ==","hmacSalt":"A8kgn/JCHKIWsft5BTNCmg==","cipher":"ZRja3SJ6ZynKKAb3YlllzlLZY3UdEM4Dx7uVockMFd7NXIE+aoTL1BBVlYrmchgCUp+fgcgswbFHYJN0tWfgFFT+j1o+I3uOvP3HCM0CxqRAlUwSPpEaxM/EIZidzu/7qMKewBZqiCHiT5Q2p4a0vR5ND3qbyZoGrinuNP3rVNGJPQoTXF0YqsjA/2XQcAYfotSnCfjs7lbvkk1GAphWHq1osiZXnEM4tQ8Al9JjhwhD6QEizO2wIcWXUzzQdKA33kXlIsnY/8YO3k24D3FZZfhPsSyW5E+8ZG4N2ECuzKeco6znIJT3b+lST65csT129SLzj66UQ9wxJIr9qPR9vOZjoxYYgJwG0Yz3xR/KYR5FXv36D8seamaP04i/UFDy6Ui8bOE2Csd0jBAOIIsBhpWi8YRSZ9uwkXEvGowyu6+oiIf1x7ssTncvlFvdV9vcDB0bZifGs8QeQivlsqao6Zpe8lJoDgy5ra4TjHNp4sn6+stTH7pCZs4/3GdrsnsM4GzLUQNSrFwadKuu2aEntElhBls+jbtDgtaPpqy9D3tqg5sEF8kDUfiQhHkQAJd2TpRJX7njxB/v/atzmRF8kRmEvet3UsFB1+K7sJ+LJxme1yzbsr+RcoPP0jqzIVee9Do4bCF1NxmcPBtui1pLfqGGvxPepZdrepXsfE7crH8=","hmac":"yAY0qckq7OZsoClHeqUwjW13uRo0KJQMUmDZjYLwCi63wvKj0renznPhkClAArkcLo+BTza5CuoF1e/6uVincQ=="}`},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				var secret crypto.Secret
+				err := json.Unmarshal([]byte(test.Secret), &secret)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				decryption := crypto.Job{Key: []byte("baseline")}
+				defer decryption.Release()
+				err = decryption.Decrypt(&secret)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if string(decryption.Data) != test.Expect {
+					t.Fatalf("expecting %q but received: %q", test.Expect, decryption.Data)
+				}
+			})
+		}
+	})
+
 	t.Run("Fail", func(t *testing.T) {
 		t.Parallel()
 
@@ -107,3 +149,31 @@ func TestJob_Decrypt(t *testing.T) {
 		})
 	})
 }
+
+func TestArgon_Weaker(t *testing.T) {
+	t.Parallel()
+
+	stored := crypto.Argon{Time: 2, Memory: 128, Threads: 2}
+	type Test struct {
+		Name   string
+		Argon  crypto.Argon
+		Expect bool
+	}
+	tests := []Test{
+		{Name: "Same", Argon: stored, Expect: false},
+		{Name: "Less threads", Argon: crypto.Argon{Time: 2, Memory: 128, Threads: 1}, Expect: false},
+		{Name: "Stronger", Argon: crypto.Argon{Time: 3, Memory: 256, Threads: 2}, Expect: false},
+		{Name: "Less time", Argon: crypto.Argon{Time: 1, Memory: 256, Threads: 2}, Expect: true},
+		{Name: "Less memory", Argon: crypto.Argon{Time: 3, Memory: 64, Threads: 2}, Expect: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if weaker := test.Argon.Weaker(stored); weaker != test.Expect {
+				t.Fatalf("expecting %v but received: %v", test.Expect, weaker)
+			}
+		})
+	}
+}
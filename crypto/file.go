@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted files are a JSON header line with the file key wrapped in slots, followed by
+// the contents encrypted as a stream with the file key
+const FileVersion = 1
+
+var (
+	ErrInvalidFile     = errors.New("not a guardian encrypted file")
+	ErrKeyfileRequired = errors.New("file requires a keyfile")
+)
+
+type FileHeader struct {
+	Version int `json:"version"`
+	// Not secret, tells the password slot was wrapped with a keyfile
+	Keyfile bool   `json:"keyfile,omitempty"`
+	Slots   []Slot `json:"slots"`
+}
+
+// NewFileKey returns a random key for the stream of a file
+func NewFileKey() (fileKey []byte) {
+	fileKey = make([]byte, KeySize)
+	rand.Read(fileKey)
+	return
+}
+
+// WriteFileHeader writes the header, the stream encrypted with the file key must follow
+func WriteFileHeader(w io.Writer, header FileHeader) (err error) {
+	header.Version = FileVersion
+	err = json.NewEncoder(w).Encode(header)
+	if err != nil {
+		err = fmt.Errorf("failed to write file header: %w", err)
+	}
+	return
+}
+
+// ReadFileHeader reads the header line, leaving r at the start of the stream
+func ReadFileHeader(r *bufio.Reader) (header FileHeader, err error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		err = fmt.Errorf("%w: failed to read header: %w", ErrInvalidFile, err)
+		return
+	}
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		return
+	}
+	if header.Version != FileVersion {
+		err = fmt.Errorf("%w: unsupported version %d", ErrInvalidFile, header.Version)
+	}
+	return
+}
+
+// Open returns the file key, from the x25519 slots when identity is set or the password slot otherwise.
+// password must already be combined with the keyfile, see CompositeKey
+func (h *FileHeader) Open(password []byte, identity *ecdh.PrivateKey) (fileKey []byte, err error) {
+	err = ErrSlotMismatch
+	for index := range h.Slots {
+		slot := &h.Slots[index]
+		switch {
+		case identity != nil && slot.Type == SlotX25519:
+			fileKey, err = slot.OpenX25519(identity)
+		case identity == nil && slot.Type == SlotPassword:
+			fileKey, err = slot.OpenPassword(password)
+		default:
+			continue
+		}
+		if err == nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package crypto_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RogueTeam/guardian/crypto"
+)
+
+func TestFileHeader(t *testing.T) {
+	t.Parallel()
+
+	identity, err := crypto.NewIdentity()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	fileKey := crypto.NewFileKey()
+	recipientSlot, err := crypto.X25519Slot(identity.PublicKey(), fileKey)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	header := crypto.FileHeader{Slots: []crypto.Slot{
+		crypto.PasswordSlot([]byte("password"), fileKey, crypto.Argon{Time: 1, Memory: 64, Threads: 1}, 16),
+		recipientSlot,
+	}}
+
+	var file bytes.Buffer
+	err = crypto.WriteFileHeader(&file, header)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	file.WriteString("stream")
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		r := bufio.NewReader(bytes.NewReader(file.Bytes()))
+		read, err := crypto.ReadFileHeader(r)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if rest, _ := r.ReadString(0); rest != "stream" {
+			t.Fatalf("expecting the stream after the header but received: %q", rest)
+		}
+
+		obtained, err := read.Open([]byte("password"), nil)
+		if err != nil || !bytes.Equal(obtained, fileKey) {
+			t.Fatalf("expecting file key from the password, but received: %v", err)
+		}
+		obtained, err = read.Open(nil, identity)
+		if err != nil || !bytes.Equal(obtained, fileKey) {
+			t.Fatalf("expecting file key from the identity, but received: %v", err)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		for _, contents := range []string{"", "plain text\n", `{"version":2,"slots":[]}` + "\n"} {
+			_, err := crypto.ReadFileHeader(bufio.NewReader(strings.NewReader(contents)))
+			if !errors.Is(err, crypto.ErrInvalidFile) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrInvalidFile, err)
+			}
+		}
+
+		other, _ := crypto.NewIdentity()
+		_, err := header.Open(nil, other)
+		if err == nil {
+			t.Fatal("expecting an error")
+		}
+		_, err = header.Open([]byte("wrong"), nil)
+		if !errors.Is(err, crypto.ErrDecryptionFailed) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Prefixes of the encoded keys
+const (
+	RecipientPrefix = "x25519:"
+	IdentityPrefix  = "GUARDIAN-IDENTITY-X25519:"
+)
+
+var (
+	ErrInvalidRecipient = errors.New("invalid recipient")
+	ErrInvalidIdentity  = errors.New("invalid identity")
+)
+
+func NewIdentity() (identity *ecdh.PrivateKey, err error) {
+	identity, err = ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		err = fmt.Errorf("failed to generate identity: %w", err)
+	}
+	return
+}
+
+func EncodeRecipient(recipient *ecdh.PublicKey) string {
+	return RecipientPrefix + base64.RawURLEncoding.EncodeToString(recipient.Bytes())
+}
+
+func ParseRecipient(s string) (recipient *ecdh.PublicKey, err error) {
+	encoded, found := strings.CutPrefix(strings.TrimSpace(s), RecipientPrefix)
+	if !found {
+		err = fmt.Errorf("%w: expecting %s prefix", ErrInvalidRecipient, RecipientPrefix)
+		return
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		recipient, err = ecdh.X25519().NewPublicKey(raw)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidRecipient, err)
+	}
+	return
+}
+
+// MarshalIdentity encodes the identity file, the recipient is included as a comment
+func MarshalIdentity(identity *ecdh.PrivateKey) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# created: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# recipient: %s\n", EncodeRecipient(identity.PublicKey()))
+	fmt.Fprintf(&buf, "%s%s\n", IdentityPrefix, base64.RawURLEncoding.EncodeToString(identity.Bytes()))
+	return buf.Bytes()
+}
+
+// ParseIdentity decodes an identity file ignoring comments and blank lines
+func ParseIdentity(data []byte) (identity *ecdh.PrivateKey, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		encoded, found := strings.CutPrefix(line, IdentityPrefix)
+		if !found {
+			break
+		}
+		var raw []byte
+		raw, err = base64.RawURLEncoding.DecodeString(encoded)
+		if err == nil {
+			defer rand.Read(raw)
+			identity, err = ecdh.X25519().NewPrivateKey(raw)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidIdentity, err)
+		}
+		return
+	}
+
+	err = fmt.Errorf("%w: expecting %s line", ErrInvalidIdentity, IdentityPrefix)
+	return
+}
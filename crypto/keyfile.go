@@ -0,0 +1,37 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Size of the keyfiles generated by NewKeyfile
+const KeyfileSize = 64
+
+// NewKeyfile returns random contents for a new keyfile
+func NewKeyfile() (contents []byte) {
+	contents = make([]byte, KeyfileSize)
+	rand.Read(contents)
+	return
+}
+
+// HashKeyfile hashes the contents of any file used as keyfile
+func HashKeyfile(contents []byte) (digest []byte) {
+	sum := sha3.Sum256(contents)
+	return sum[:]
+}
+
+// CompositeKey combines the password with the keyfile digest before argon derivation.
+// Without keyfile the password is used as is, keeping older databases compatible
+func CompositeKey(password, keyfileDigest []byte) (key []byte) {
+	if keyfileDigest == nil {
+		return bytes.Clone(password)
+	}
+
+	sum := sha3.Sum256(password)
+	key = append(sum[:], keyfileDigest...)
+	rand.Read(sum[:])
+	return
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Padding schemes
+const (
+	// Only the chunk padding of Encrypt
+	PaddingNone = ""
+	// Sizes and counts grow in power of two buckets
+	PaddingPowerOfTwo = "pow2"
+	// Sizes and counts grow in multiples of a fixed minimum
+	PaddingFixed = "fixed"
+)
+
+// Limits of PaddingFixed, every entry and decoy is allocated with the padded size
+const (
+	MaxPaddingSize  = 64 * 1024
+	MaxPaddingCount = 1024
+)
+
+var ErrUnknownPadding = errors.New("unknown padding, expecting none, pow2 or fixed:SIZE[:ENTRIES]")
+
+// Padding hides the length of the data before it is encrypted
+type Padding struct {
+	Scheme string `json:"scheme,omitempty"`
+	// Minimum size in bytes of PaddingFixed, rounded up to a multiple of ChunkSize
+	Size int `json:"size,omitempty"`
+	// Minimum number of items of PaddingFixed
+	Count int `json:"count,omitempty"`
+}
+
+// ParsePadding parses none, pow2 or fixed:SIZE[:ENTRIES]
+func ParsePadding(s string) (padding Padding, err error) {
+	parts := strings.Split(s, ":")
+	switch parts[0] {
+	case "", "none":
+		if len(parts) == 1 {
+			return
+		}
+	case PaddingPowerOfTwo:
+		if len(parts) == 1 {
+			padding.Scheme = PaddingPowerOfTwo
+			return
+		}
+	case PaddingFixed:
+		if len(parts) < 2 || len(parts) > 3 {
+			break
+		}
+		padding.Scheme = PaddingFixed
+		padding.Size, err = strconv.Atoi(parts[1])
+		if err != nil || padding.Size <= 0 || padding.Size > MaxPaddingSize {
+			err = fmt.Errorf("%w: invalid size %s", ErrUnknownPadding, parts[1])
+			return
+		}
+		if len(parts) == 3 {
+			padding.Count, err = strconv.Atoi(parts[2])
+			if err != nil || padding.Count <= 0 || padding.Count > MaxPaddingCount {
+				err = fmt.Errorf("%w: invalid count %s", ErrUnknownPadding, parts[2])
+				return
+			}
+		}
+		return
+	}
+	err = fmt.Errorf("%w: %s", ErrUnknownPadding, s)
+	return
+}
+
+// Check validates padding decoded from a file, as ParsePadding does for flags
+func (p Padding) Check() (err error) {
+	parsed, err := ParsePadding(p.String())
+	if err == nil && parsed != p {
+		err = fmt.Errorf("%w: %+v", ErrUnknownPadding, p)
+	}
+	return
+}
+
+func (p Padding) String() string {
+	switch p.Scheme {
+	case PaddingPowerOfTwo:
+		return PaddingPowerOfTwo
+	case PaddingFixed:
+		if p.Count > 0 {
+			return fmt.Sprintf("%s:%d:%d", PaddingFixed, p.Size, p.Count)
+		}
+		return fmt.Sprintf("%s:%d", PaddingFixed, p.Size)
+	}
+	return "none"
+}
+
+// Length returns the padded length of n bytes of data.
+// Padded lengths always fill whole chunks, the padding of Encrypt doesn't change the bucket
+func (p Padding) Length(n int) int {
+	switch p.Scheme {
+	case PaddingPowerOfTwo:
+		size := ChunkSize
+		for size < n+1 {
+			size *= 2
+		}
+		return size - 1
+	case PaddingFixed:
+		size := ChunkSize * ((p.Size + ChunkSize - 1) / ChunkSize)
+		return size*((n+size)/size) - 1
+	}
+	return n
+}
+
+// Items returns the padded number of n items
+func (p Padding) Items(n int) int {
+	switch p.Scheme {
+	case PaddingPowerOfTwo:
+		count := 1
+		for count < n {
+			count *= 2
+		}
+		return count
+	case PaddingFixed:
+		if p.Count > 0 {
+			return p.Count * ((n + p.Count - 1) / p.Count)
+		}
+	}
+	return n
+}
+
+// Decoy returns random contents shaped like the secret Encrypt returns for dataLength bytes
+// with KDFHKDF. Without the key it can't be told apart from a real one
+func Decoy(dataLength, saltSize int) (secret *Secret) {
+	secret = &Secret{
+		KDF:      KDFHKDF,
+		IV:       make([]byte, IVSize),
+		KeySalt:  make([]byte, saltSize),
+		HMACSalt: make([]byte, saltSize),
+		Cipher:   make([]byte, ChunkSize*(1+dataLength/ChunkSize)),
+		HMAC:     make([]byte, ChecksumSize),
+	}
+	rand.Read(secret.IV)
+	rand.Read(secret.KeySalt)
+	rand.Read(secret.HMACSalt)
+	rand.Read(secret.Cipher)
+	rand.Read(secret.HMAC)
+	return
+}
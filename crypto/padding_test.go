@@ -0,0 +1,75 @@
+package crypto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RogueTeam/guardian/crypto"
+)
+
+func TestPadding(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name    string
+			Padding string
+			Lengths [][2]int
+			Items   [][2]int
+		}
+		tests := []Test{
+			{Name: "None", Padding: "none", Lengths: [][2]int{{0, 0}, {300, 300}}, Items: [][2]int{{3, 3}}},
+			{Name: "Power of two", Padding: "pow2", Lengths: [][2]int{{0, 255}, {255, 255}, {256, 511}, {600, 1023}}, Items: [][2]int{{0, 1}, {3, 4}, {4, 4}, {5, 8}}},
+			{Name: "Fixed", Padding: "fixed:1000", Lengths: [][2]int{{0, 1023}, {1023, 1023}, {1024, 2047}}, Items: [][2]int{{3, 3}}},
+			{Name: "Fixed entries", Padding: "fixed:256:10", Lengths: [][2]int{{10, 255}, {300, 511}}, Items: [][2]int{{0, 0}, {3, 10}, {11, 20}}},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				padding, err := crypto.ParsePadding(test.Padding)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				for _, length := range test.Lengths {
+					if obtained := padding.Length(length[0]); obtained != length[1] {
+						t.Fatalf("expecting length %d for %d but received: %d", length[1], length[0], obtained)
+					}
+					// The padded data always fills the chunks of the cipher
+					if test.Padding != "none" && (length[1]+1)%crypto.ChunkSize != 0 {
+						t.Fatalf("expecting whole chunks but received: %d", length[1])
+					}
+				}
+				for _, items := range test.Items {
+					if obtained := padding.Items(items[0]); obtained != items[1] {
+						t.Fatalf("expecting %d items for %d but received: %d", items[1], items[0], obtained)
+					}
+				}
+			})
+		}
+
+		decoy := crypto.Decoy(300, 16)
+		if len(decoy.Cipher) != 512 || len(decoy.KeySalt) != 16 || decoy.KDF != crypto.KDFHKDF {
+			t.Fatal("expecting decoy shaped like an encrypted secret")
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		for _, padding := range []string{"pow3", "pow2:1", "fixed", "fixed:0", "fixed:a", "fixed:10:-1", "fixed:1:2:3", "none:1", "fixed:65537", "fixed:1:1025"} {
+			_, err := crypto.ParsePadding(padding)
+			if !errors.Is(err, crypto.ErrUnknownPadding) {
+				t.Fatalf("expecting %v for %s but received: %v", crypto.ErrUnknownPadding, padding, err)
+			}
+		}
+		for _, padding := range []crypto.Padding{{Scheme: "pow3"}, {Scheme: crypto.PaddingFixed}, {Scheme: crypto.PaddingFixed, Size: 1 << 30}, {Size: 1}} {
+			err := padding.Check()
+			if !errors.Is(err, crypto.ErrUnknownPadding) {
+				t.Fatalf("expecting %v for %+v but received: %v", crypto.ErrUnknownPadding, padding, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	utilscrypto "github.com/RogueTeam/guardian/internal/utils/crypto"
+)
+
+// Prefixes of the encoded signing keys
+const (
+	SignerPrefix     = "ed25519:"
+	SigningKeyPrefix = "GUARDIAN-SIGNING-KEY-ED25519:"
+)
+
+var (
+	ErrInvalidSigner     = errors.New("invalid signer")
+	ErrInvalidSigningKey = errors.New("invalid signing key")
+	ErrInvalidSignature  = errors.New("invalid signature")
+)
+
+// Signature authenticates the device that wrote a file
+type Signature struct {
+	// Encoded public key of the signing key
+	Signer string `json:"signer"`
+	Value  []byte `json:"value"`
+}
+
+// NewSigningKey generates the ed25519 key identifying a device
+func NewSigningKey() (key ed25519.PrivateKey, err error) {
+	key, err = utilscrypto.NewPrivKey()
+	if err != nil {
+		err = fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return
+}
+
+// Sign signs the message with the key
+func Sign(key ed25519.PrivateKey, message []byte) (signature *Signature) {
+	// ed25519 caches the expanded key by the address of the key, which must be on the Go heap.
+	// Keys in locked memory are copied for the call and the copy is wiped
+	heapKey := bytes.Clone(key)
+	defer rand.Read(heapKey)
+	return &Signature{
+		Signer: EncodeSigner(key.Public().(ed25519.PublicKey)),
+		Value:  ed25519.Sign(heapKey, message),
+	}
+}
+
+// Verify checks the signature was made by its signer
+func (s *Signature) Verify(message []byte) (err error) {
+	signer, err := ParseSigner(s.Signer)
+	if err != nil {
+		return
+	}
+	if !ed25519.Verify(signer, message, s.Value) {
+		err = fmt.Errorf("%w: %s", ErrInvalidSignature, s.Signer)
+	}
+	return
+}
+
+func EncodeSigner(signer ed25519.PublicKey) string {
+	return SignerPrefix + base64.RawURLEncoding.EncodeToString(signer)
+}
+
+func ParseSigner(s string) (signer ed25519.PublicKey, err error) {
+	encoded, found := strings.CutPrefix(strings.TrimSpace(s), SignerPrefix)
+	if !found {
+		err = fmt.Errorf("%w: expecting %s prefix", ErrInvalidSigner, SignerPrefix)
+		return
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil && len(raw) != ed25519.PublicKeySize {
+		err = fmt.Errorf("expecting %d bytes but received %d", ed25519.PublicKeySize, len(raw))
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidSigner, err)
+		return
+	}
+	signer = raw
+	return
+}
+
+// MarshalSigningKey encodes the signing key file, the signer is included as a comment
+func MarshalSigningKey(key ed25519.PrivateKey) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# created: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# signer: %s\n", EncodeSigner(key.Public().(ed25519.PublicKey)))
+	fmt.Fprintf(&buf, "%s%s\n", SigningKeyPrefix, base64.RawURLEncoding.EncodeToString(key.Seed()))
+	return buf.Bytes()
+}
+
+// ParseSigningKey decodes a signing key file ignoring comments and blank lines
+func ParseSigningKey(data []byte) (key ed25519.PrivateKey, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		encoded, found := strings.CutPrefix(line, SigningKeyPrefix)
+		if !found {
+			break
+		}
+		var seed []byte
+		seed, err = base64.RawURLEncoding.DecodeString(encoded)
+		if err == nil && len(seed) != ed25519.SeedSize {
+			err = fmt.Errorf("expecting %d bytes but received %d", ed25519.SeedSize, len(seed))
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidSigningKey, err)
+			return
+		}
+		defer rand.Read(seed)
+		key = ed25519.NewKeyFromSeed(seed)
+		return
+	}
+
+	err = fmt.Errorf("%w: expecting %s line", ErrInvalidSigningKey, SigningKeyPrefix)
+	return
+}
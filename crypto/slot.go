@@ -0,0 +1,174 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Slot types
+const (
+	SlotPassword = "password"
+	SlotX25519   = "x25519"
+	SlotRecovery = "recovery"
+)
+
+var (
+	ErrSlotMismatch    = errors.New("slot can't be opened with the provided key")
+	ErrUnknownSlotType = errors.New("unknown slot type")
+)
+
+// Slot holds the data key of an envelope wrapped for a single recipient
+type Slot struct {
+	Type string `json:"type"`
+	// Encoded public key of x25519 slots
+	Recipient string `json:"recipient,omitempty"`
+	// Ephemeral public key used for the key agreement
+	Ephemeral []byte `json:"ephemeral,omitempty"`
+	// Data key encrypted with chacha20poly1305
+	Wrapped []byte `json:"wrapped,omitempty"`
+	// Data key encrypted with the password
+	Secret *Secret `json:"secret,omitempty"`
+}
+
+// PasswordSlot wraps the data key with a key stretched by argon
+func PasswordSlot(password, dataKey []byte, argon Argon, saltSize int) (slot Slot) {
+	job := Job{
+		Key:      bytes.Clone(password),
+		Data:     bytes.Clone(dataKey),
+		Argon:    argon,
+		SaltSize: saltSize,
+	}
+	defer job.Release()
+
+	return Slot{Type: SlotPassword, Secret: job.Encrypt()}
+}
+
+// RecoverySlot wraps the data key with a random recovery key, no stretching is needed
+func RecoverySlot(recoveryKey, dataKey []byte, saltSize int) (slot Slot) {
+	job := Job{
+		Key:      bytes.Clone(recoveryKey),
+		Data:     bytes.Clone(dataKey),
+		SaltSize: saltSize,
+		KDF:      KDFHKDF,
+	}
+	defer job.Release()
+
+	return Slot{Type: SlotRecovery, Secret: job.Encrypt()}
+}
+
+// X25519Slot wraps the data key for the owner of the recipient identity
+func X25519Slot(recipient *ecdh.PublicKey, dataKey []byte) (slot Slot, err error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		err = fmt.Errorf("failed to generate ephemeral key: %w", err)
+		return
+	}
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		err = fmt.Errorf("failed to agree key: %w", err)
+		return
+	}
+	defer rand.Read(shared)
+
+	slot = Slot{
+		Type:      SlotX25519,
+		Recipient: EncodeRecipient(recipient),
+		Ephemeral: ephemeral.PublicKey().Bytes(),
+	}
+
+	aead := slotAEAD(shared, slot.Ephemeral, recipient.Bytes())
+	// Every wrapping key is unique thanks to the ephemeral key, so a zero nonce is safe
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	slot.Wrapped = aead.Seal(nil, nonce, dataKey, nil)
+	return
+}
+
+func slotAEAD(shared, ephemeral, recipient []byte) (aead cipher.AEAD) {
+	salt := append(bytes.Clone(ephemeral), recipient...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	defer rand.Read(key)
+	io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("guardian x25519 slot")), key)
+
+	// Error doesn't need verification because key is always of valid size
+	aead, _ = chacha20poly1305.New(key)
+	return
+}
+
+// OpenPassword returns the data key of a password slot
+func (s *Slot) OpenPassword(password []byte) (dataKey []byte, err error) {
+	if s.Type != SlotPassword || s.Secret == nil {
+		err = ErrSlotMismatch
+		return
+	}
+
+	job := Job{Key: bytes.Clone(password)}
+	defer job.Release()
+	err = job.Decrypt(s.Secret)
+	if err != nil {
+		return
+	}
+	dataKey = bytes.Clone(job.Data)
+	return
+}
+
+// OpenRecovery returns the data key of a recovery slot
+func (s *Slot) OpenRecovery(recoveryKey []byte) (dataKey []byte, err error) {
+	if s.Type != SlotRecovery || s.Secret == nil {
+		err = ErrSlotMismatch
+		return
+	}
+
+	job := Job{Key: bytes.Clone(recoveryKey)}
+	defer job.Release()
+	err = job.Decrypt(s.Secret)
+	if err != nil {
+		return
+	}
+	dataKey = bytes.Clone(job.Data)
+	return
+}
+
+// OpenX25519 returns the data key of a slot wrapped for the identity
+func (s *Slot) OpenX25519(identity *ecdh.PrivateKey) (dataKey []byte, err error) {
+	if s.Type != SlotX25519 || s.Recipient != EncodeRecipient(identity.PublicKey()) {
+		err = ErrSlotMismatch
+		return
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(s.Ephemeral)
+	if err != nil {
+		err = fmt.Errorf("invalid ephemeral key: %w", err)
+		return
+	}
+	shared, err := identity.ECDH(ephemeral)
+	if err != nil {
+		err = fmt.Errorf("failed to agree key: %w", err)
+		return
+	}
+	defer rand.Read(shared)
+
+	aead := slotAEAD(shared, s.Ephemeral, identity.PublicKey().Bytes())
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	dataKey, err = aead.Open(nil, nonce, s.Wrapped, nil)
+	if err != nil {
+		err = ErrDecryptionFailed
+	}
+	return
+}
+
+func (s *Slot) Release() {
+	if s.Secret != nil {
+		s.Secret.Release()
+	}
+	rand.Read(s.Wrapped)
+}
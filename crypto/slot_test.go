@@ -0,0 +1,133 @@
+package crypto_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/testsuite"
+)
+
+func TestSlot(t *testing.T) {
+	t.Parallel()
+
+	dataKey := testsuite.Random(crypto.KeySize)
+	argon := crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Password", func(t *testing.T) {
+			t.Parallel()
+
+			slot := crypto.PasswordSlot([]byte("password"), dataKey, argon, 16)
+			obtained, err := slot.OpenPassword([]byte("password"))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if !bytes.Equal(dataKey, obtained) {
+				t.Fatal("expecting data key to match")
+			}
+		})
+		t.Run("X25519", func(t *testing.T) {
+			t.Parallel()
+
+			identity, err := crypto.NewIdentity()
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			// Identity files survive a round trip
+			identity, err = crypto.ParseIdentity(crypto.MarshalIdentity(identity))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			recipient, err := crypto.ParseRecipient(crypto.EncodeRecipient(identity.PublicKey()))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			slot, err := crypto.X25519Slot(recipient, dataKey)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			obtained, err := slot.OpenX25519(identity)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if !bytes.Equal(dataKey, obtained) {
+				t.Fatal("expecting data key to match")
+			}
+		})
+		t.Run("Recovery", func(t *testing.T) {
+			t.Parallel()
+
+			recoveryKey := testsuite.Random(crypto.KeySize)
+			slot := crypto.RecoverySlot(recoveryKey, dataKey, 16)
+			obtained, err := slot.OpenRecovery(recoveryKey)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if !bytes.Equal(dataKey, obtained) {
+				t.Fatal("expecting data key to match")
+			}
+		})
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Wrong password", func(t *testing.T) {
+			t.Parallel()
+
+			slot := crypto.PasswordSlot([]byte("password"), dataKey, argon, 16)
+			_, err := slot.OpenPassword([]byte("invalid"))
+			if !errors.Is(err, crypto.ErrDecryptionFailed) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+			}
+		})
+		t.Run("Wrong recovery key", func(t *testing.T) {
+			t.Parallel()
+
+			slot := crypto.RecoverySlot(testsuite.Random(crypto.KeySize), dataKey, 16)
+			_, err := slot.OpenRecovery(testsuite.Random(crypto.KeySize))
+			if !errors.Is(err, crypto.ErrDecryptionFailed) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+			}
+		})
+		t.Run("Other identity", func(t *testing.T) {
+			t.Parallel()
+
+			identity, _ := crypto.NewIdentity()
+			other, _ := crypto.NewIdentity()
+
+			slot, err := crypto.X25519Slot(identity.PublicKey(), dataKey)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			_, err = slot.OpenX25519(other)
+			if !errors.Is(err, crypto.ErrSlotMismatch) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrSlotMismatch, err)
+			}
+
+			// Tampered recipient
+			slot.Recipient = crypto.EncodeRecipient(other.PublicKey())
+			_, err = slot.OpenX25519(other)
+			if !errors.Is(err, crypto.ErrDecryptionFailed) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+			}
+		})
+		t.Run("Invalid encodings", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := crypto.ParseRecipient("age1invalid")
+			if !errors.Is(err, crypto.ErrInvalidRecipient) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrInvalidRecipient, err)
+			}
+			_, err = crypto.ParseIdentity([]byte("# only comments\n"))
+			if !errors.Is(err, crypto.ErrInvalidIdentity) {
+				t.Fatalf("expecting %v but received: %v", crypto.ErrInvalidIdentity, err)
+			}
+		})
+	})
+}
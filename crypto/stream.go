@@ -0,0 +1,235 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/RogueTeam/guardian/internal/secure"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// Streams are a random nonce followed by chunks sealed with chacha20poly1305.
+// The nonce of every chunk is its counter and a flag marking the last one, like the STREAM
+// construction used by age, so reordered, dropped or truncated chunks fail to open
+const (
+	StreamNonceSize = 16
+	StreamChunkSize = 64 * 1024
+
+	streamSealedSize = StreamChunkSize + chacha20poly1305.Overhead
+)
+
+var (
+	ErrStreamTruncated    = errors.New("stream truncated")
+	ErrStreamTrailingData = errors.New("stream has data after the last chunk")
+	ErrStreamClosed       = errors.New("stream already closed")
+)
+
+// streamNonce is the big endian chunk counter, the last byte flags the final chunk
+type streamNonce [chacha20poly1305.NonceSize]byte
+
+func (n *streamNonce) increment() (err error) {
+	for index := len(n) - 2; index >= 0; index-- {
+		n[index]++
+		if n[index] != 0 {
+			return
+		}
+	}
+	return errors.New("stream chunk counter overflow")
+}
+
+// streamAEAD derives the chunk key from the key and the stream nonce
+func streamAEAD(key, nonce []byte) (aead cipher.AEAD) {
+	streamKey := make([]byte, chacha20poly1305.KeySize)
+	defer rand.Read(streamKey)
+	io.ReadFull(hkdf.New(sha3.New512, key, nonce, []byte("guardian stream")), streamKey)
+	// Error doesn't need verification because the key is always of valid size
+	aead, _ = chacha20poly1305.New(streamKey)
+	return
+}
+
+// StreamWriter encrypts everything written to it, Close must be called to write the last chunk
+type StreamWriter struct {
+	dst    io.Writer
+	aead   cipher.AEAD
+	nonce  streamNonce
+	plain  *secure.Buffer
+	length int
+	sealed []byte
+	closed bool
+}
+
+// NewStreamWriter writes the stream nonce to dst and returns the writer encrypting to it
+func NewStreamWriter(key []byte, dst io.Writer) (w *StreamWriter, err error) {
+	nonce := make([]byte, StreamNonceSize)
+	rand.Read(nonce)
+	_, err = dst.Write(nonce)
+	if err != nil {
+		err = fmt.Errorf("failed to write stream nonce: %w", err)
+		return
+	}
+
+	w = &StreamWriter{
+		dst:    dst,
+		aead:   streamAEAD(key, nonce),
+		plain:  secure.New(StreamChunkSize),
+		sealed: make([]byte, 0, streamSealedSize),
+	}
+	return
+}
+
+func (w *StreamWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		err = ErrStreamClosed
+		return
+	}
+
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, the last one must be flagged
+		if w.length == StreamChunkSize {
+			err = w.flush(false)
+			if err != nil {
+				return
+			}
+		}
+		copied := copy(w.plain.Bytes()[w.length:], p)
+		w.length += copied
+		n += copied
+		p = p[copied:]
+	}
+	return
+}
+
+// Close seals the last chunk, it doesn't close the underlying writer
+func (w *StreamWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	defer w.plain.Destroy()
+	w.closed = true
+
+	return w.flush(true)
+}
+
+func (w *StreamWriter) flush(last bool) (err error) {
+	if last {
+		w.nonce[len(w.nonce)-1] = 1
+	}
+	w.sealed = w.aead.Seal(w.sealed[:0], w.nonce[:], w.plain.Bytes()[:w.length], nil)
+	w.length = 0
+
+	_, err = w.dst.Write(w.sealed)
+	if err != nil {
+		err = fmt.Errorf("failed to write chunk: %w", err)
+		return
+	}
+	return w.nonce.increment()
+}
+
+// StreamReader decrypts a stream written by StreamWriter, the plaintext of a chunk is only
+// returned after it was authenticated
+type StreamReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	nonce streamNonce
+	// Sealed chunk plus a byte of the next one, telling whether it is the last
+	sealed  []byte
+	carried int
+	plain   *secure.Buffer
+	chunk   []byte
+	last    bool
+	err     error
+}
+
+// NewStreamReader reads the stream nonce from src and returns the reader decrypting it
+func NewStreamReader(key []byte, src io.Reader) (r *StreamReader, err error) {
+	nonce := make([]byte, StreamNonceSize)
+	_, err = io.ReadFull(src, nonce)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to read stream nonce: %w", ErrStreamTruncated, err)
+		return
+	}
+
+	r = &StreamReader{
+		src:    src,
+		aead:   streamAEAD(key, nonce),
+		sealed: make([]byte, streamSealedSize+1),
+		plain:  secure.New(StreamChunkSize),
+	}
+	return
+}
+
+func (r *StreamReader) Read(p []byte) (n int, err error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.last {
+			r.err = io.EOF
+		} else {
+			r.err = r.next()
+		}
+		if r.err != nil {
+			r.Close()
+		}
+	}
+
+	n = copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return
+}
+
+// Close wipes the decrypted chunk, it doesn't close the underlying reader
+func (r *StreamReader) Close() (err error) {
+	r.plain.Destroy()
+	r.chunk = nil
+	return
+}
+
+func (r *StreamReader) next() (err error) {
+	read, err := io.ReadFull(r.src, r.sealed[r.carried:])
+	total := r.carried + read
+	switch {
+	case err == nil:
+		r.carried = 1
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		r.carried = 0
+		r.last = true
+	default:
+		err = fmt.Errorf("failed to read chunk: %w", err)
+		return
+	}
+	if total == 0 {
+		err = ErrStreamTruncated
+		return
+	}
+	sealed := r.sealed[:total-r.carried]
+
+	if r.last {
+		r.nonce[len(r.nonce)-1] = 1
+	}
+	r.chunk, err = r.aead.Open(r.plain.Bytes()[:0], r.nonce[:], sealed, nil)
+	if err != nil {
+		// Opening it with the other flag tells truncation and trailing data apart from tampering
+		r.nonce[len(r.nonce)-1] ^= 1
+		_, flipped := r.aead.Open(nil, r.nonce[:], sealed, nil)
+		switch {
+		case flipped != nil:
+			err = ErrDecryptionFailed
+		case r.last:
+			err = ErrStreamTruncated
+		default:
+			err = ErrStreamTrailingData
+		}
+		return
+	}
+
+	if r.carried == 1 {
+		r.sealed[0] = r.sealed[streamSealedSize]
+	}
+	return r.nonce.increment()
+}
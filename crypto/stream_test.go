@@ -0,0 +1,138 @@
+package crypto_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/testsuite"
+)
+
+func encryptStream(t *testing.T, key, plain []byte, writeSize int) []byte {
+	var sealed bytes.Buffer
+	w, err := crypto.NewStreamWriter(key, &sealed)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	for rest := plain; len(rest) > 0; {
+		size := min(writeSize, len(rest))
+		_, err = w.Write(rest[:size])
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		rest = rest[size:]
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	return sealed.Bytes()
+}
+
+func decryptStream(key, sealed []byte) (plain []byte, err error) {
+	r, err := crypto.NewStreamReader(key, bytes.NewReader(sealed))
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	key := testsuite.Random(crypto.KeySize)
+	const (
+		header = crypto.StreamNonceSize
+		sealed = crypto.StreamChunkSize + 16
+	)
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name      string
+			Size      int
+			WriteSize int
+		}
+		tests := []Test{
+			{Name: "Empty", Size: 0, WriteSize: 1},
+			{Name: "Small", Size: 100, WriteSize: 7},
+			{Name: "Chunk minus one", Size: crypto.StreamChunkSize - 1, WriteSize: 4096},
+			{Name: "Chunk", Size: crypto.StreamChunkSize, WriteSize: crypto.StreamChunkSize},
+			{Name: "Chunk plus one", Size: crypto.StreamChunkSize + 1, WriteSize: 1000},
+			{Name: "Many chunks", Size: 3*crypto.StreamChunkSize + 5, WriteSize: 3*crypto.StreamChunkSize + 5},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				plain := testsuite.Random(test.Size)
+				obtained, err := decryptStream(key, encryptStream(t, key, plain, test.WriteSize))
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if !bytes.Equal(plain, obtained) {
+					t.Fatal("expecting plaintext to match")
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		plain := testsuite.Random(3*crypto.StreamChunkSize + 5)
+		stream := encryptStream(t, key, plain, len(plain))
+		full := encryptStream(t, key, plain[:2*crypto.StreamChunkSize], len(plain))
+
+		chunk := func(stream []byte, index int) []byte {
+			start := header + index*sealed
+			return stream[start:min(start+sealed, len(stream))]
+		}
+		join := func(parts ...[]byte) []byte {
+			return bytes.Join(parts, nil)
+		}
+		flipped := bytes.Clone(stream)
+		flipped[header+sealed+10] ^= 1
+
+		type Test struct {
+			Name   string
+			Key    []byte
+			Stream []byte
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Wrong key", Key: testsuite.Random(crypto.KeySize), Stream: stream, Expect: crypto.ErrDecryptionFailed},
+			{Name: "Flipped bit", Key: key, Stream: flipped, Expect: crypto.ErrDecryptionFailed},
+			{Name: "Missing nonce", Key: key, Stream: stream[:header-1], Expect: crypto.ErrStreamTruncated},
+			{Name: "No chunks", Key: key, Stream: stream[:header], Expect: crypto.ErrStreamTruncated},
+			{Name: "Dropped last chunk", Key: key, Stream: stream[:header+3*sealed], Expect: crypto.ErrStreamTruncated},
+			{Name: "Cut chunk", Key: key, Stream: stream[:len(stream)-1], Expect: crypto.ErrDecryptionFailed},
+			{Name: "Reordered", Key: key, Stream: join(stream[:header], chunk(stream, 1), chunk(stream, 0), stream[header+2*sealed:]), Expect: crypto.ErrDecryptionFailed},
+			{Name: "Dropped middle chunk", Key: key, Stream: join(stream[:header], chunk(stream, 0), stream[header+2*sealed:]), Expect: crypto.ErrDecryptionFailed},
+			{Name: "Trailing data", Key: key, Stream: join(full, []byte{0}), Expect: crypto.ErrStreamTrailingData},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := decryptStream(test.Key, test.Stream)
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+
+		// Nothing can be written after the last chunk
+		w, _ := crypto.NewStreamWriter(key, io.Discard)
+		w.Close()
+		_, err := w.Write([]byte("data"))
+		if !errors.Is(err, crypto.ErrStreamClosed) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrStreamClosed, err)
+		}
+	})
+}
@@ -11,68 +11,435 @@ package database
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/internal/secure"
 )
 
+// Versions of the database file
+const (
+	// A single crypto.Secret encrypted with the master key
+	VersionSecret = 0
+	// Payload encrypted with a random data key, wrapped for every recipient in slots
+	VersionEnvelope = 2
+	// Envelope whose payload is an index, every entry is sealed separately and
+	// only decrypted when accessed
+	VersionIndexed = 3
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported database version")
+	ErrNoMatchingSlot     = errors.New("no slot can be opened with the provided key")
+	ErrPasswordRequired   = errors.New("operation requires the database to be opened with the password")
+	ErrRecipientExists    = errors.New("recipient already exists")
+	ErrRecipientNotFound  = errors.New("recipient not found")
+	ErrKeyfileRequired    = errors.New("database requires a keyfile")
+	ErrKeyfileUnexpected  = errors.New("database doesn't use a keyfile")
+	ErrUntrustedSigner    = errors.New("database wasn't signed by a trusted signer")
+	ErrSignerExists       = errors.New("signer already trusted")
+	ErrSignerNotFound     = errors.New("signer not trusted")
+	ErrCorrupted          = errors.New("database is corrupted")
+	ErrEntryNotFound      = errors.New("no entry found with id")
+	ErrLocked             = errors.New("database is locked by another process")
+	ErrChanged            = errors.New("database saved by another process since it was opened")
+	ErrDowngrade          = errors.New("key derivation parameters below the ones the database was saved with")
+)
+
+type Envelope struct {
+	Version int `json:"version"`
+	// Not secret, tells the password slot was wrapped with a keyfile
+	Keyfile bool           `json:"keyfile,omitempty"`
+	Slots   []crypto.Slot  `json:"slots"`
+	Payload *crypto.Secret `json:"payload"`
+	// Sealed entries of VersionIndexed, by reference
+	Entries map[string]*crypto.Secret `json:"entries,omitempty"`
+	// Padding applied to the payload and the entries before encryption, informational
+	// since the authenticated copy of the index is the one used, see Verify
+	Padding *crypto.Padding `json:"padding,omitempty"`
+	// Signature of the device that wrote the file, over the envelope without it
+	Signature *crypto.Signature `json:"signature,omitempty"`
+}
+
 type Database struct {
-	Key      []byte
+	Key []byte `json:"-"`
+	// Digest of the keyfile combined with the key
+	Keyfile  []byte `json:"-"`
 	SaltSize int
 	Argon    crypto.Argon
-	Secrets  map[string]string `json:"secrets"`
+	// Decrypted entries are ordinary strings on the Go heap, only the keys and the
+	// decrypted payload are kept in locked memory
+	Secrets map[string]string `json:"secrets"`
+	// Named values attached to an entry, like the username of a login
+	Fields map[string]map[string]string `json:"fields,omitempty"`
+	// Random key encrypting the payload
+	DataKey []byte `json:"-"`
+	// Encoded public keys of the identities able to open the database, stored in the payload
+	Recipients []string `json:"-"`
+	// Random key split into shares for emergency recovery, stored in the payload
+	// so the recovery slot survives data key rotations
+	RecoveryKey []byte `json:"recoveryKey,omitempty"`
+	// Hides the number and size of the entries, see crypto.Padding
+	Padding crypto.Padding `json:"-"`
+	// Encoded ed25519 public keys of the devices trusted to write the database, stored in the payload
+	Signers []string `json:"-"`
+	// Signs the database on save when set
+	SigningKey ed25519.PrivateKey `json:"-"`
+	// Signer of the opened file, empty when it wasn't signed
+	Signer string `json:"-"`
+	// Time of the last save, zero for databases written before it was recorded
+	Modified time.Time `json:"-"`
+	// Allows saving with weaker parameters than the stored ones, see Downgrades
+	AllowDowngrade bool `json:"-"`
+	// Parameters of the password slot the file was saved with
+	stored kdf
+	// Kept untouched when the database is opened with an identity
+	passwordSlot *crypto.Slot
+	// Whether the kept password slot was wrapped with a keyfile
+	keyfileRequired bool
+	// Locked memory holding the keys, freed by Destroy
+	buffers []*secure.Buffer
+	// Entries not decrypted yet, by id
+	sealed map[string]sealedEntry
+	mutex  sync.Mutex
+}
+
+// kdf are the parameters deriving the key of the password slot
+type kdf struct {
+	Argon    crypto.Argon
+	SaltSize int
 }
 
 func New() (db *Database) {
 	return &Database{
 		Secrets: make(map[string]string),
+		Fields:  make(map[string]map[string]string),
+		sealed:  make(map[string]sealedEntry),
 	}
 }
 
+// lock returns a copy of b in locked memory owned by the database
+func (db *Database) lock(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	buffer := secure.Copy(b)
+	db.buffers = append(db.buffers, buffer)
+	return buffer.Bytes()
+}
+
+// newDataKey replaces the data key with a random one, wiping the old key
+func (db *Database) newDataKey() {
+	rand.Read(db.DataKey)
+	buffer := secure.New(crypto.KeySize)
+	db.buffers = append(db.buffers, buffer)
+	db.DataKey = buffer.Bytes()
+	rand.Read(db.DataKey)
+}
+
+// Destroy wipes the keys of the database, it can't be saved afterwards
+func (db *Database) Destroy() {
+	for _, buffer := range db.buffers {
+		buffer.Destroy()
+	}
+	db.buffers = nil
+	db.Key = nil
+	db.Keyfile = nil
+	db.DataKey = nil
+	db.RecoveryKey = nil
+}
+
+func (db *Database) slots() (slots []crypto.Slot, err error) {
+	if db.passwordSlot != nil {
+		slots = append(slots, *db.passwordSlot)
+	} else {
+		key := crypto.CompositeKey(db.Key, db.Keyfile)
+		defer rand.Read(key)
+		slots = append(slots, crypto.PasswordSlot(key, db.DataKey, db.Argon, db.SaltSize))
+	}
+
+	if db.RecoveryKey != nil {
+		slots = append(slots, crypto.RecoverySlot(db.RecoveryKey, db.DataKey, db.SaltSize))
+	}
+
+	for _, encoded := range db.Recipients {
+		var recipient *ecdh.PublicKey
+		recipient, err = crypto.ParseRecipient(encoded)
+		if err != nil {
+			return
+		}
+
+		var slot crypto.Slot
+		slot, err = crypto.X25519Slot(recipient, db.DataKey)
+		if err != nil {
+			return
+		}
+		slots = append(slots, slot)
+	}
+	return
+}
+
 func (db *Database) Save(w io.Writer) (err error) {
-	var buffer bytes.Buffer
-	json.NewEncoder(&buffer).Encode(db)
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.Downgrades() && !db.AllowDowngrade {
+		err = fmt.Errorf("%w: argon %+v salt size %d, stored argon %+v salt size %d",
+			ErrDowngrade, db.Argon, db.SaltSize, db.stored.Argon, db.stored.SaltSize)
+		return
+	}
+
+	if db.DataKey == nil {
+		db.newDataKey()
+	}
+
+	slots, err := db.slots()
+	if err != nil {
+		err = fmt.Errorf("failed to wrap data key: %w", err)
+		return
+	}
+
+	db.Modified = time.Now().UTC().Truncate(time.Second)
+	data, entries, err := db.encodeIndex()
+	if err != nil {
+		err = fmt.Errorf("failed to encode index: %w", err)
+		return
+	}
+	defer rand.Read(data)
 
 	var job = crypto.Job{
-		Key:      make([]byte, len(db.Key)),
-		Data:     buffer.Bytes(),
-		Argon:    db.Argon,
+		Key:      make([]byte, len(db.DataKey)),
+		Data:     data,
 		SaltSize: db.SaltSize,
+		KDF:      crypto.KDFHKDF,
 	}
-	copy(job.Key, db.Key)
+	copy(job.Key, db.DataKey)
 	defer job.Release()
 
-	secret := job.Encrypt()
-	defer secret.Release()
-	err = json.NewEncoder(w).Encode(secret)
+	keyfile := db.Keyfile != nil
+	if db.passwordSlot != nil {
+		keyfile = db.keyfileRequired
+	}
+
+	envelope := Envelope{
+		Version: VersionIndexed,
+		Keyfile: keyfile,
+		Slots:   slots,
+		Payload: job.Encrypt(),
+		Entries: entries,
+	}
+	if db.Padding.Scheme != crypto.PaddingNone {
+		envelope.Padding = &db.Padding
+	}
+	defer envelope.Payload.Release()
+	if db.SigningKey != nil {
+		err = envelope.sign(db.SigningKey)
+		if err != nil {
+			err = fmt.Errorf("failed to sign database: %w", err)
+			return
+		}
+	}
+	err = json.NewEncoder(w).Encode(envelope)
+	if err == nil {
+		db.stored = kdf{Argon: db.Argon, SaltSize: db.SaltSize}
+	}
+	return
+}
+
+// Downgrades reports whether saving would use weaker parameters than the ones the file was saved with
+func (db *Database) Downgrades() bool {
+	return db.Argon.Weaker(db.stored.Argon) || db.SaltSize < db.stored.SaltSize
+}
+
+// Rekey sets the parameters wrapping the password slot on save, weaker ones need AllowDowngrade
+func (db *Database) Rekey(argon crypto.Argon, saltSize int) (err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.passwordSlot != nil {
+		err = ErrPasswordRequired
+		return
+	}
+	db.Argon = argon
+	db.SaltSize = saltSize
+	return
+}
+
+// SetKey replaces the master key and the keyfile digest, the password slot is wrapped again on save
+func (db *Database) SetKey(key, keyfile []byte) {
+	db.Key = db.lock(key)
+	db.Keyfile = db.lock(keyfile)
+	db.passwordSlot = nil
+}
+
+// AddRecipient allows the owner of the identity to open the database
+func (db *Database) AddRecipient(recipient string) (err error) {
+	_, err = crypto.ParseRecipient(recipient)
+	if err != nil {
+		return
+	}
+	for _, existing := range db.Recipients {
+		if existing == recipient {
+			err = fmt.Errorf("%w: %s", ErrRecipientExists, recipient)
+			return
+		}
+	}
+
+	db.Recipients = append(db.Recipients, recipient)
+	return
+}
+
+// RemoveRecipient removes the recipient and rotates the data key, so a copy of the
+// removed slot can't open newer versions of the database
+func (db *Database) RemoveRecipient(recipient string) (err error) {
+	index := -1
+	for i, existing := range db.Recipients {
+		if existing == recipient {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		err = fmt.Errorf("%w: %s", ErrRecipientNotFound, recipient)
+		return
+	}
+
+	// The password slot must be wrapped again with the new data key
+	if db.passwordSlot != nil {
+		err = ErrPasswordRequired
+		return
+	}
+
+	// Sealed entries depend on the old data key
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	err = db.unsealAll()
+	if err != nil {
+		return
+	}
+
+	db.Recipients = append(db.Recipients[:index], db.Recipients[index+1:]...)
+	db.newDataKey()
 	return
 }
 
 type Config struct {
-	Key      []byte
+	Key []byte
+	// Digest of the keyfile, see crypto.HashKeyfile
+	Keyfile []byte
+	// Parameters of new databases, existing ones keep the parameters they were saved with, see Rekey
 	Argon    crypto.Argon
 	SaltSize int
+	// Padding of new databases, existing ones keep the padding they were saved with
+	Padding crypto.Padding
+	// Opens the database with the x25519 slot of the identity instead of the password
+	Identity *ecdh.PrivateKey
+	// Opens the database with the recovery slot instead of the password
+	RecoveryKey []byte
+	// Refuses databases not signed by one of the trusted signers
+	RequireTrustedSigner bool
 }
 
 func Open(config Config, r io.Reader) (db *Database, err error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("failed to read database: %w", err)
+		return
+	}
+	defer rand.Read(contents)
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	err = json.Unmarshal(contents, &header)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decode secret: %w", ErrCorrupted, err)
+		return
+	}
+
+	db = New()
+	db.Key = db.lock(config.Key)
+	db.Keyfile = db.lock(config.Keyfile)
+	db.Argon = config.Argon
+	db.SaltSize = config.SaltSize
+	db.Padding = config.Padding
+
+	// The decrypted payload lives in locked memory until the job is released
+	var (
+		job     crypto.Job
+		entries map[string]*crypto.Secret
+	)
+	defer job.Release()
+	switch header.Version {
+	case VersionSecret:
+		err = openSecret(config, contents, &job)
+	case VersionEnvelope, VersionIndexed:
+		entries, err = db.openEnvelope(config, contents, &job)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+	}
+	if err != nil {
+		db.Destroy()
+		db = nil
+		return
+	}
+
+	if header.Version == VersionIndexed {
+		err = db.decodeIndex(job.Data, entries)
+	} else {
+		err = json.Unmarshal(job.Data, db)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decode JSON database: %w", ErrCorrupted, err)
+		db.Destroy()
+		db = nil
+		return
+	}
+	// The password slot is what the parameters protect, the payload copy is only a fallback
+	if db.stored != (kdf{}) {
+		db.Argon = db.stored.Argon
+		db.SaltSize = db.stored.SaltSize
+	}
+	if db.Argon == (crypto.Argon{}) {
+		db.Argon = config.Argon
+	}
+	if db.SaltSize == 0 {
+		db.SaltSize = config.SaltSize
+	}
+	db.stored = kdf{Argon: db.Argon, SaltSize: db.SaltSize}
+	if recoveryKey := db.RecoveryKey; recoveryKey != nil {
+		db.RecoveryKey = db.lock(recoveryKey)
+		rand.Read(recoveryKey)
+	}
+	if config.RequireTrustedSigner && !db.Trusted() {
+		err = fmt.Errorf("%w: %q", ErrUntrustedSigner, db.Signer)
+		db.Destroy()
+		db = nil
+	}
+	return
+}
+
+func openSecret(config Config, contents []byte, job *crypto.Job) (err error) {
 	var secret crypto.Secret
 	defer secret.Release()
-	err = json.NewDecoder(r).Decode(&secret)
+	err = json.Unmarshal(contents, &secret)
 	if err != nil {
-		err = fmt.Errorf("failed to decode secret: %w", err)
+		err = fmt.Errorf("%w: failed to decode secret: %w", ErrCorrupted, err)
 		return
 	}
 
-	var job = crypto.Job{
-		Key: make([]byte, len(config.Key)),
-	}
 	if secret.Argon.Memory != 0 &&
 		secret.Argon.Threads != 0 &&
 		secret.Argon.Time != 0 {
-		copy(job.Key, config.Key)
-		defer job.Release()
+		job.Key = bytes.Clone(config.Key)
 		err = job.Decrypt(&secret)
 		if err != nil {
 			err = fmt.Errorf("error during decryption: %w", err)
@@ -81,14 +448,96 @@ func Open(config Config, r io.Reader) (db *Database, err error) {
 	} else {
 		job.Data = []byte("{}")
 	}
+	return
+}
 
-	db = New()
-	db.Key = config.Key
-	db.Argon = config.Argon
-	db.SaltSize = config.SaltSize
-	err = json.Unmarshal(job.Data, db)
+func (db *Database) openEnvelope(config Config, contents []byte, job *crypto.Job) (entries map[string]*crypto.Secret, err error) {
+	var envelope Envelope
+	err = json.Unmarshal(contents, &envelope)
+	if err != nil || envelope.Payload == nil {
+		err = fmt.Errorf("%w: failed to decode envelope: %w", ErrCorrupted, err)
+		return
+	}
+	defer envelope.Payload.Release()
+
+	// Tampered signatures are refused even when signers aren't required
+	if envelope.Signature != nil {
+		err = envelope.verify()
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+			return
+		}
+		db.Signer = envelope.Signature.Signer
+	}
+
+	usePassword := config.Identity == nil && config.RecoveryKey == nil
+	if usePassword && envelope.Keyfile && config.Keyfile == nil {
+		err = ErrKeyfileRequired
+		return
+	}
+	if usePassword && !envelope.Keyfile && config.Keyfile != nil {
+		err = ErrKeyfileUnexpected
+		return
+	}
+	db.keyfileRequired = envelope.Keyfile
+
+	var dataKey []byte
+	defer func() { rand.Read(dataKey) }()
+
+	err = ErrNoMatchingSlot
+	for index := range envelope.Slots {
+		slot := &envelope.Slots[index]
+		switch slot.Type {
+		case crypto.SlotPassword:
+			if slot.Secret != nil {
+				db.stored = kdf{Argon: slot.Secret.Argon, SaltSize: len(slot.Secret.KeySalt)}
+			}
+			if !usePassword {
+				db.passwordSlot = slot
+				continue
+			}
+			if dataKey == nil {
+				key := crypto.CompositeKey(config.Key, config.Keyfile)
+				dataKey, err = slot.OpenPassword(key)
+				rand.Read(key)
+			}
+		case crypto.SlotRecovery:
+			if config.RecoveryKey != nil && dataKey == nil {
+				dataKey, err = slot.OpenRecovery(config.RecoveryKey)
+			}
+		case crypto.SlotX25519:
+			// Replaced by the authenticated list of the index, see decodeIndex
+			db.Recipients = append(db.Recipients, slot.Recipient)
+			if config.Identity != nil && dataKey == nil {
+				dataKey, err = slot.OpenX25519(config.Identity)
+				if errors.Is(err, crypto.ErrSlotMismatch) {
+					err = ErrNoMatchingSlot
+				}
+			}
+		}
+	}
+	if dataKey == nil {
+		err = fmt.Errorf("error during decryption: %w", err)
+		return
+	}
+	db.DataKey = db.lock(dataKey)
+
+	// The data key was unwrapped, a payload failing to decrypt was modified
+	job.Key = bytes.Clone(db.DataKey)
+	err = job.Decrypt(envelope.Payload)
 	if err != nil {
-		err = fmt.Errorf("failed to decode JSON database: %w", err)
+		err = fmt.Errorf("%w: payload: %w", ErrCorrupted, err)
+		return
+	}
+	entries = envelope.Entries
+	// Only indexes written before the padding was stored in them rely on the envelope
+	if envelope.Padding != nil {
+		err = envelope.Padding.Check()
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+			return
+		}
+		db.Padding = *envelope.Padding
 	}
 	return
 }
@@ -2,12 +2,19 @@ package database_test
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
+	"os"
+	"path"
 	"strings"
 	"testing"
 
 	"github.com/RogueTeam/guardian/crypto"
 	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/secure"
+	"github.com/RogueTeam/guardian/internal/testsuite"
 )
 
 func TestJson(t *testing.T) {
@@ -161,3 +168,1189 @@ func TestJson(t *testing.T) {
 		})
 	})
 }
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		db.SetField("example.com", database.FieldUsername, "sulcud")
+
+		secret, err := db.Get("example.com")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if secret != "" {
+			t.Fatalf("expecting empty secret but received: %s", secret)
+		}
+
+		db.Set("example.com", "password")
+		username, err := db.GetField("example.com", database.FieldUsername)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if username != "sulcud" {
+			t.Fatalf("expecting sulcud but received: %s", username)
+		}
+
+		fields, err := db.GetFields("example.com")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		fields[database.FieldUsername] = "modified"
+		username, _ = db.GetField("example.com", database.FieldUsername)
+		if username != "sulcud" {
+			t.Fatal("expecting fields to be a copy")
+		}
+
+		err = db.DelField("example.com", database.FieldUsername)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		_, err = db.GetField("example.com", database.FieldUsername)
+		if err == nil {
+			t.Fatal("expecting error")
+		}
+
+		db.SetField("example.com", database.FieldUsername, "sulcud")
+		err = db.Del("example.com")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		_, err = db.GetField("example.com", database.FieldUsername)
+		if err == nil {
+			t.Fatal("expecting fields to be deleted with the entry")
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		_, err := db.GetFields("example.com")
+		if !errors.Is(err, database.ErrEntryNotFound) {
+			t.Fatalf("expecting %v but received: %v", database.ErrEntryNotFound, err)
+		}
+		err = db.DelField("example.com", database.FieldUsername)
+		if err == nil {
+			t.Fatal("expecting error")
+		}
+	})
+}
+
+func TestSubset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		db.Key = []byte("main")
+		db.Set("projectx/api", "token")
+		db.SetField("projectx/api", database.FieldURL, "https://api.example.com")
+		db.Set("projecty/api", "other")
+
+		sub, err := db.Subset("projectx/")
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if sub.Key != nil {
+			t.Fatal("expecting key not to be copied")
+		}
+		if len(sub.Secrets) != 1 || sub.Secrets["projectx/api"] != "token" {
+			t.Fatalf("expecting only projectx entries, but received: %v", sub.Secrets)
+		}
+
+		sub.SetField("projectx/api", database.FieldURL, "modified")
+		url, _ := db.GetField("projectx/api", database.FieldURL)
+		if url != "https://api.example.com" {
+			t.Fatal("expecting fields to be a copy")
+		}
+
+		// Round trip with a different key
+		sub.Key = []byte("shared")
+		sub.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+		sub.SaltSize = 16
+
+		var file bytes.Buffer
+		err = sub.Save(&file)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		opened, err := database.Open(database.Config{Key: []byte("shared"), Argon: sub.Argon, SaltSize: sub.SaltSize}, &file)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		sub.Set("projectx/new", "created")
+		merged, skipped, err := db.Merge(opened, false)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if len(merged) != 0 || len(skipped) != 1 {
+			t.Fatalf("expecting existing entries to be skipped, but received: %v %v", merged, skipped)
+		}
+
+		merged, skipped, err = db.Merge(sub, true)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if strings.Join(merged, ",") != "projectx/api,projectx/new" || len(skipped) != 0 {
+			t.Fatalf("expecting entries to be merged, but received: %v %v", merged, skipped)
+		}
+		url, _ = db.GetField("projectx/api", database.FieldURL)
+		if url != "modified" {
+			t.Fatalf("expecting field to be overwritten, but received: %s", url)
+		}
+		if secret, _ := db.Get("projecty/api"); secret != "other" {
+			t.Fatal("expecting other entries to be untouched")
+		}
+	})
+}
+
+func TestRecipients(t *testing.T) {
+	t.Parallel()
+
+	argon := crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	config := database.Config{Key: []byte("password"), Argon: argon, SaltSize: 16}
+
+	alice, _ := crypto.NewIdentity()
+	bob, _ := crypto.NewIdentity()
+
+	save := func(t *testing.T, db *database.Database) *bytes.Buffer {
+		var file bytes.Buffer
+		err := db.Save(&file)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return &file
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		db.Key = config.Key
+		db.Argon = argon
+		db.SaltSize = 16
+		db.Set("example.com", "password")
+
+		err := db.AddRecipient(crypto.EncodeRecipient(alice.PublicKey()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		err = db.AddRecipient(crypto.EncodeRecipient(bob.PublicKey()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		file := save(t, db)
+
+		// Every recipient and the password open the same database
+		for _, identity := range []*ecdh.PrivateKey{nil, alice, bob} {
+			opened, err := database.Open(database.Config{Key: config.Key, Argon: argon, SaltSize: 16, Identity: identity}, bytes.NewReader(file.Bytes()))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if secret, _ := opened.Get("example.com"); secret != "password" {
+				t.Fatalf("expecting password but received: %s", secret)
+			}
+			if len(opened.Recipients) != 2 {
+				t.Fatalf("expecting 2 recipients but received: %v", opened.Recipients)
+			}
+		}
+
+		// Saving from an identity keeps the password slot
+		opened, _ := database.Open(database.Config{Identity: alice}, bytes.NewReader(file.Bytes()))
+		opened.Set("new", "value")
+		file = save(t, opened)
+		opened, err = database.Open(config, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if secret, _ := opened.Get("new"); secret != "value" {
+			t.Fatalf("expecting value but received: %s", secret)
+		}
+
+		// Removing rotates the data key
+		dataKey := bytes.Clone(opened.DataKey)
+		err = opened.RemoveRecipient(crypto.EncodeRecipient(bob.PublicKey()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if bytes.Equal(dataKey, opened.DataKey) {
+			t.Fatal("expecting data key to be rotated")
+		}
+		file = save(t, opened)
+
+		_, err = database.Open(database.Config{Identity: bob}, bytes.NewReader(file.Bytes()))
+		if !errors.Is(err, database.ErrNoMatchingSlot) {
+			t.Fatalf("expecting %v but received: %v", database.ErrNoMatchingSlot, err)
+		}
+		_, err = database.Open(database.Config{Identity: alice}, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+	})
+	t.Run("Injected slot", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		db.Key = config.Key
+		db.Argon = argon
+		db.SaltSize = 16
+		db.Set("example.com", "password")
+		file := save(t, db)
+
+		// Anyone able to write the file can add a slot, but not the payload listing it
+		var envelope database.Envelope
+		err := json.Unmarshal(file.Bytes(), &envelope)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		mallory, _ := crypto.NewIdentity()
+		slot, err := crypto.X25519Slot(mallory.PublicKey(), make([]byte, crypto.KeySize))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		envelope.Slots = append(envelope.Slots, slot)
+		tampered, _ := json.Marshal(envelope)
+
+		_, err = database.Verify(config, bytes.NewReader(tampered))
+		if !errors.Is(err, database.ErrCorrupted) {
+			t.Fatalf("expecting %v but received: %v", database.ErrCorrupted, err)
+		}
+
+		opened, err := database.Open(config, bytes.NewReader(tampered))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if len(opened.Recipients) != 0 {
+			t.Fatalf("expecting no recipients but received: %v", opened.Recipients)
+		}
+		opened.Set("new", "value")
+		file = save(t, opened)
+
+		envelope = database.Envelope{}
+		json.Unmarshal(file.Bytes(), &envelope)
+		for _, slot := range envelope.Slots {
+			if slot.Type == crypto.SlotX25519 {
+				t.Fatalf("expecting no recipient slots but received: %s", slot.Recipient)
+			}
+		}
+		_, err = database.Open(database.Config{Identity: mallory}, bytes.NewReader(file.Bytes()))
+		if !errors.Is(err, database.ErrNoMatchingSlot) {
+			t.Fatalf("expecting %v but received: %v", database.ErrNoMatchingSlot, err)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.New()
+		db.Key = config.Key
+		db.Argon = argon
+		db.SaltSize = 16
+
+		recipient := crypto.EncodeRecipient(alice.PublicKey())
+		err := db.AddRecipient(recipient)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		err = db.AddRecipient(recipient)
+		if !errors.Is(err, database.ErrRecipientExists) {
+			t.Fatalf("expecting %v but received: %v", database.ErrRecipientExists, err)
+		}
+		err = db.AddRecipient("invalid")
+		if !errors.Is(err, crypto.ErrInvalidRecipient) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrInvalidRecipient, err)
+		}
+		err = db.RemoveRecipient(crypto.EncodeRecipient(bob.PublicKey()))
+		if !errors.Is(err, database.ErrRecipientNotFound) {
+			t.Fatalf("expecting %v but received: %v", database.ErrRecipientNotFound, err)
+		}
+
+		file := save(t, db)
+		_, err = database.Open(database.Config{Key: []byte("invalid")}, bytes.NewReader(file.Bytes()))
+		if !errors.Is(err, crypto.ErrDecryptionFailed) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+		}
+
+		// The password slot can't be wrapped again without the password
+		opened, err := database.Open(database.Config{Identity: alice}, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		err = opened.RemoveRecipient(recipient)
+		if !errors.Is(err, database.ErrPasswordRequired) {
+			t.Fatalf("expecting %v but received: %v", database.ErrPasswordRequired, err)
+		}
+
+		_, err = database.Open(config, strings.NewReader(`{"version": 99}`))
+		if !errors.Is(err, database.ErrUnsupportedVersion) {
+			t.Fatalf("expecting %v but received: %v", database.ErrUnsupportedVersion, err)
+		}
+	})
+}
+
+func TestRecovery(t *testing.T) {
+	t.Parallel()
+
+	argon := crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	config := database.Config{Key: []byte("password"), Argon: argon, SaltSize: 16}
+	recoveryKey := testsuite.Random(crypto.KeySize)
+
+	db := database.New()
+	db.Key = config.Key
+	db.Argon = argon
+	db.SaltSize = 16
+	db.RecoveryKey = recoveryKey
+	db.Set("example.com", "password")
+
+	var file bytes.Buffer
+	err := db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		opened, err := database.Open(database.Config{RecoveryKey: recoveryKey, SaltSize: 16}, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if secret, _ := opened.Get("example.com"); secret != "password" {
+			t.Fatalf("expecting password but received: %s", secret)
+		}
+
+		// Rekeying replaces the password slot
+		opened.SetKey([]byte("new password"), nil)
+		opened.Argon = argon
+		var rekeyed bytes.Buffer
+		err = opened.Save(&rekeyed)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		_, err = database.Open(config, bytes.NewReader(rekeyed.Bytes()))
+		if !errors.Is(err, crypto.ErrDecryptionFailed) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+		}
+		opened, err = database.Open(database.Config{Key: []byte("new password")}, bytes.NewReader(rekeyed.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if !bytes.Equal(recoveryKey, opened.RecoveryKey) {
+			t.Fatal("expecting recovery key to be kept")
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := database.Open(database.Config{RecoveryKey: testsuite.Random(crypto.KeySize)}, bytes.NewReader(file.Bytes()))
+		if !errors.Is(err, crypto.ErrDecryptionFailed) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrDecryptionFailed, err)
+		}
+	})
+}
+
+func TestKeyfile(t *testing.T) {
+	t.Parallel()
+
+	argon := crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	keyfile := crypto.HashKeyfile(crypto.NewKeyfile())
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Keyfile = keyfile
+	db.Argon = argon
+	db.SaltSize = 16
+	db.Set("example.com", "password")
+
+	var file bytes.Buffer
+	err := db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		opened, err := database.Open(database.Config{Key: []byte("password"), Keyfile: keyfile}, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if secret, _ := opened.Get("example.com"); secret != "password" {
+			t.Fatalf("expecting password but received: %s", secret)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Config database.Config
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Missing keyfile", Config: database.Config{Key: []byte("password")}, Expect: database.ErrKeyfileRequired},
+			{Name: "Wrong keyfile", Config: database.Config{Key: []byte("password"), Keyfile: crypto.HashKeyfile([]byte("other"))}, Expect: crypto.ErrDecryptionFailed},
+			{Name: "Keyfile alone", Config: database.Config{Keyfile: keyfile}, Expect: crypto.ErrDecryptionFailed},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := database.Open(test.Config, bytes.NewReader(file.Bytes()))
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+
+		// Databases without keyfile refuse one, instead of failing decryption
+		db := database.New()
+		db.Key = []byte("password")
+		db.Argon = argon
+		db.SaltSize = 16
+		var plain bytes.Buffer
+		err := db.Save(&plain)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		_, err = database.Open(database.Config{Key: []byte("password"), Keyfile: keyfile}, bytes.NewReader(plain.Bytes()))
+		if !errors.Is(err, database.ErrKeyfileUnexpected) {
+			t.Fatalf("expecting %v but received: %v", database.ErrKeyfileUnexpected, err)
+		}
+	})
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	db.SaltSize = 16
+	db.Set("example.com", "password")
+	db.Set("other.com", "secret")
+	db.SetField("example.com", "username", "admin")
+
+	var file bytes.Buffer
+	err := db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		opened, err := database.Open(database.Config{Key: []byte("password")}, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if len(opened.Secrets) != 0 {
+			t.Fatalf("expecting no decrypted entries but received: %d", len(opened.Secrets))
+		}
+		ids, _ := opened.List()
+		if len(ids) != 2 {
+			t.Fatalf("expecting 2 entries but received: %v", ids)
+		}
+		if size, _ := opened.Size("example.com"); size != len("password") {
+			t.Fatalf("expecting size %d but received: %d", len("password"), size)
+		}
+		if secret, _ := opened.Get("example.com"); secret != "password" {
+			t.Fatalf("expecting password but received: %s", secret)
+		}
+		if username, _ := opened.GetField("example.com", "username"); username != "admin" {
+			t.Fatalf("expecting admin but received: %s", username)
+		}
+		if len(opened.Secrets) != 1 {
+			t.Fatalf("expecting only the accessed entry decrypted but received: %d", len(opened.Secrets))
+		}
+
+		// Untouched entries are written back without decrypting them
+		var saved bytes.Buffer
+		err = opened.Save(&saved)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		reopened, err := database.Open(database.Config{Key: []byte("password")}, &saved)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if secret, _ := reopened.Get("other.com"); secret != "secret" {
+			t.Fatalf("expecting secret but received: %s", secret)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		var envelope database.Envelope
+		err := json.Unmarshal(file.Bytes(), &envelope)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		for ref := range envelope.Entries {
+			delete(envelope.Entries, ref)
+			break
+		}
+		tampered, _ := json.Marshal(envelope)
+
+		db, err := database.Open(database.Config{Key: []byte("password")}, bytes.NewReader(tampered))
+		if !errors.Is(err, database.ErrMissingEntry) {
+			t.Fatalf("expecting %v but received: %v", database.ErrMissingEntry, err)
+		}
+		// Destroyed instead of returned with its buffers
+		if db != nil {
+			t.Fatal("expecting no database but received one")
+		}
+	})
+}
+
+func TestPadding(t *testing.T) {
+	t.Parallel()
+
+	save := func(t *testing.T, padding crypto.Padding, secrets map[string]string) []byte {
+		db, err := database.Open(database.Config{
+			Key:      []byte("password"),
+			Argon:    crypto.Argon{Time: 1, Memory: 64, Threads: 1},
+			SaltSize: 16,
+			Padding:  padding,
+		}, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		for id, secret := range secrets {
+			db.Set(id, secret)
+		}
+		var file bytes.Buffer
+		err = db.Save(&file)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return file.Bytes()
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name    string
+			Padding crypto.Padding
+			A, B    map[string]string
+		}
+		tests := []Test{
+			{
+				Name:    "Power of two",
+				Padding: crypto.Padding{Scheme: crypto.PaddingPowerOfTwo},
+				A:       map[string]string{"a": "1", "b": "2"},
+				B:       map[string]string{"example.com": "password", "other.com": strings.Repeat("x", 200)},
+			},
+			{
+				Name:    "Fixed",
+				Padding: crypto.Padding{Scheme: crypto.PaddingFixed, Size: 1024, Count: 8},
+				A:       map[string]string{"a": "1"},
+				B:       map[string]string{"example.com": "password", "other.com": strings.Repeat("x", 600), "last.com": "secret"},
+			},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				a := save(t, test.Padding, test.A)
+				b := save(t, test.Padding, test.B)
+				if len(a) != len(b) {
+					t.Fatalf("expecting identical sizes but received: %d and %d", len(a), len(b))
+				}
+
+				// Decoys are ignored and the padding is kept
+				db, err := database.Open(database.Config{Key: []byte("password")}, bytes.NewReader(b))
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if db.Padding != test.Padding {
+					t.Fatalf("expecting padding %v but received: %v", test.Padding, db.Padding)
+				}
+				ids, _ := db.List()
+				if len(ids) != len(test.B) {
+					t.Fatalf("expecting %d entries but received: %v", len(test.B), ids)
+				}
+				for id, secret := range test.B {
+					if obtained, _ := db.Get(id); obtained != secret {
+						t.Fatalf("expecting %s but received: %s", secret, obtained)
+					}
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		// Different buckets still tell the vaults apart
+		padding := crypto.Padding{Scheme: crypto.PaddingPowerOfTwo}
+		a := save(t, padding, map[string]string{"a": "1"})
+		b := save(t, padding, map[string]string{"a": "1", "b": "2"})
+		if len(a) == len(b) {
+			t.Fatal("expecting different sizes across buckets")
+		}
+
+		// tamper returns a copy of the file with the envelope padding replaced
+		tamper := func(t *testing.T, file []byte, padding *crypto.Padding) []byte {
+			var envelope database.Envelope
+			err := json.Unmarshal(file, &envelope)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			envelope.Padding = padding
+			contents, _ := json.Marshal(envelope)
+			return contents
+		}
+		config := database.Config{Key: []byte("password")}
+
+		// Removing the padding of the envelope doesn't turn it off
+		fixed := crypto.Padding{Scheme: crypto.PaddingFixed, Size: 1024, Count: 8}
+		file := save(t, fixed, map[string]string{"a": "1"})
+		stripped := tamper(t, file, nil)
+		_, err := database.Verify(config, bytes.NewReader(stripped))
+		if !errors.Is(err, database.ErrCorrupted) {
+			t.Fatalf("expecting %v but received: %v", database.ErrCorrupted, err)
+		}
+		db, err := database.Open(config, bytes.NewReader(stripped))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if db.Padding != fixed {
+			t.Fatalf("expecting padding %v but received: %v", fixed, db.Padding)
+		}
+		var saved bytes.Buffer
+		err = db.Save(&saved)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if saved.Len() != len(file) {
+			t.Fatalf("expecting %d bytes but received: %d", len(file), saved.Len())
+		}
+
+		// Huge sizes would be allocated on save
+		huge := tamper(t, file, &crypto.Padding{Scheme: crypto.PaddingFixed, Size: 1 << 40})
+		_, err = database.Open(config, bytes.NewReader(huge))
+		if !errors.Is(err, database.ErrCorrupted) {
+			t.Fatalf("expecting %v but received: %v", database.ErrCorrupted, err)
+		}
+	})
+}
+
+func TestSigners(t *testing.T) {
+	t.Parallel()
+
+	trusted, err := crypto.NewSigningKey()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	untrusted, err := crypto.NewSigningKey()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	signer := crypto.EncodeSigner(trusted.Public().(ed25519.PublicKey))
+
+	save := func(t *testing.T, key ed25519.PrivateKey) []byte {
+		db := database.New()
+		db.Key = []byte("password")
+		db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+		db.SaltSize = 16
+		db.SigningKey = key
+		db.Set("example.com", "password")
+		err := db.TrustSigner(signer)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		var file bytes.Buffer
+		err = db.Save(&file)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return file.Bytes()
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		db, err := database.Open(database.Config{Key: []byte("password"), RequireTrustedSigner: true}, bytes.NewReader(save(t, trusted)))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if db.Signer != signer || !db.Trusted() {
+			t.Fatalf("expecting trusted signer %s but received: %s", signer, db.Signer)
+		}
+
+		// Keys in locked memory sign too
+		locked := secure.Copy(trusted)
+		defer locked.Destroy()
+		db, err = database.Open(database.Config{Key: []byte("password"), RequireTrustedSigner: true}, bytes.NewReader(save(t, locked.Bytes())))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		// Unknown signers only fail when required
+		db, err = database.Open(database.Config{Key: []byte("password")}, bytes.NewReader(save(t, untrusted)))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if db.Trusted() {
+			t.Fatal("expecting untrusted signer")
+		}
+
+		err = db.UntrustSigner(signer)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if len(db.Signers) != 0 {
+			t.Fatalf("expecting no signers but received: %v", db.Signers)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		var envelope database.Envelope
+		err := json.Unmarshal(save(t, trusted), &envelope)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		envelope.Padding = &crypto.Padding{Scheme: crypto.PaddingPowerOfTwo}
+		tampered, _ := json.Marshal(envelope)
+
+		unsigned := database.New()
+		unsigned.Key = []byte("password")
+		unsigned.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+		unsigned.SaltSize = 16
+		var plain bytes.Buffer
+		err = unsigned.Save(&plain)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		type Test struct {
+			Name   string
+			File   []byte
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Tampered", File: tampered, Expect: crypto.ErrInvalidSignature},
+			{Name: "Untrusted", File: save(t, untrusted), Expect: database.ErrUntrustedSigner},
+			{Name: "Unsigned", File: plain.Bytes(), Expect: database.ErrUntrustedSigner},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := database.Open(database.Config{Key: []byte("password"), RequireTrustedSigner: true}, bytes.NewReader(test.File))
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+
+		db := database.New()
+		err = db.TrustSigner("ed25519:invalid")
+		if !errors.Is(err, crypto.ErrInvalidSigner) {
+			t.Fatalf("expecting %v but received: %v", crypto.ErrInvalidSigner, err)
+		}
+		err = db.UntrustSigner(signer)
+		if !errors.Is(err, database.ErrSignerNotFound) {
+			t.Fatalf("expecting %v but received: %v", database.ErrSignerNotFound, err)
+		}
+		db.TrustSigner(signer)
+		err = db.TrustSigner(signer)
+		if !errors.Is(err, database.ErrSignerExists) {
+			t.Fatalf("expecting %v but received: %v", database.ErrSignerExists, err)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	db.SaltSize = 16
+	db.Padding = crypto.Padding{Scheme: crypto.PaddingPowerOfTwo}
+	db.Set("example.com", "password")
+	db.Set("other.com", "secret")
+	db.SetField("example.com", "username", "admin")
+
+	var file bytes.Buffer
+	err := db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	config := database.Config{Key: []byte("password")}
+
+	// tamper returns a copy of the file modified by fn
+	tamper := func(t *testing.T, fn func(envelope *database.Envelope)) []byte {
+		var envelope database.Envelope
+		err := json.Unmarshal(file.Bytes(), &envelope)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		fn(&envelope)
+		contents, _ := json.Marshal(envelope)
+		return contents
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		report, err := database.Verify(config, bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if report.Version != database.VersionIndexed || report.Entries != 2 || report.Fields != 1 || report.Decoys != 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		if report.Padding != "pow2" || report.Argon != db.Argon || report.Modified == nil {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Config database.Config
+			File   []byte
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Wrong key", Config: database.Config{Key: []byte("wrong")}, File: file.Bytes(), Expect: crypto.ErrDecryptionFailed},
+			{Name: "Not JSON", Config: config, File: []byte("garbage"), Expect: database.ErrCorrupted},
+			{Name: "Unsupported version", Config: config, File: tamper(t, func(e *database.Envelope) { e.Version = 9 }), Expect: database.ErrUnsupportedVersion},
+			{Name: "Weak argon", Config: config, File: tamper(t, func(e *database.Envelope) { e.Slots[0].Secret.Argon.Threads = 0 }), Expect: database.ErrCorrupted},
+			{Name: "Modified payload", Config: config, File: tamper(t, func(e *database.Envelope) { e.Payload.Cipher[0] ^= 1 }), Expect: database.ErrCorrupted},
+			{Name: "Modified entry", Config: config, File: tamper(t, func(e *database.Envelope) {
+				for _, entry := range e.Entries {
+					entry.Cipher[0] ^= 1
+				}
+			}), Expect: database.ErrCorrupted},
+			{Name: "Wrong padding", Config: config, File: tamper(t, func(e *database.Envelope) {
+				e.Padding = &crypto.Padding{Scheme: crypto.PaddingFixed, Size: 4096}
+			}), Expect: database.ErrCorrupted},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := database.Verify(test.Config, bytes.NewReader(test.File))
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	identity, err := crypto.NewIdentity()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	db.SaltSize = 16
+	db.Set("example.com", "password")
+	db.AddRecipient(crypto.EncodeRecipient(identity.PublicKey()))
+
+	var file bytes.Buffer
+	err = db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		inspection, err := database.Inspect(bytes.NewReader(file.Bytes()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if inspection.Version != database.VersionIndexed || len(inspection.Parts) != 4 {
+			t.Fatalf("unexpected inspection: %+v", inspection)
+		}
+		if argon := inspection.Parts[0].Argon; argon == nil || *argon != db.Argon {
+			t.Fatalf("expecting argon %v but received: %v", db.Argon, argon)
+		}
+		if inspection.Parts[1].Algorithm != database.AlgorithmX25519 {
+			t.Fatalf("expecting x25519 slot but received: %+v", inspection.Parts[1])
+		}
+
+		database.DefaultPolicy().Assess(&inspection)
+		if len(inspection.Weaknesses) != 2 {
+			t.Fatalf("expecting weak argon time and memory but received: %v", inspection.Weaknesses)
+		}
+		database.Policy{Argon: db.Argon, SaltSize: 16}.Assess(&inspection)
+		if len(inspection.Weaknesses) != 0 {
+			t.Fatalf("expecting no weaknesses but received: %v", inspection.Weaknesses)
+		}
+		if table := inspection.Table(); !strings.Contains(table, "no weaknesses found") {
+			t.Fatalf("unexpected table: %s", table)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := database.Inspect(strings.NewReader(`{"version":9}`))
+		if !errors.Is(err, database.ErrUnsupportedVersion) {
+			t.Fatalf("expecting %v but received: %v", database.ErrUnsupportedVersion, err)
+		}
+		_, err = database.Inspect(strings.NewReader("garbage"))
+		if !errors.Is(err, database.ErrCorrupted) {
+			t.Fatalf("expecting %v but received: %v", database.ErrCorrupted, err)
+		}
+	})
+}
+
+func TestLockFile(t *testing.T) {
+	t.Parallel()
+
+	filepath := path.Join(t.TempDir(), "guardian.json")
+	file, err := os.Create(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer file.Close()
+
+	err = database.LockShared(file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	other, err := os.Open(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer other.Close()
+
+	// Readers share the lock, writers wait for them
+	err = database.LockShared(other)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	err = database.LockExclusive(other)
+	if !errors.Is(err, database.ErrLocked) {
+		t.Fatalf("expecting %v but received: %v", database.ErrLocked, err)
+	}
+
+	file.Close()
+	err = database.LockExclusive(other)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	third, err := os.Open(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer third.Close()
+	err = database.LockShared(third)
+	if !errors.Is(err, database.ErrLocked) {
+		t.Fatalf("expecting %v but received: %v", database.ErrLocked, err)
+	}
+
+	// Converted back once saved
+	err = database.LockShared(other)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	err = database.LockShared(third)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	filepath := path.Join(dir, "guardian.json")
+	err := os.WriteFile(filepath, []byte("previous"), 0o640)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	file, err := database.OpenFile(filepath, 0o600)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer file.Close()
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	db.SaltSize = 16
+	db.Set("example.com", "password")
+
+	// A failed save keeps the previous contents
+	db.Recipients = []string{"invalid"}
+	err = file.Save(db)
+	if err == nil {
+		t.Fatal("expecting error")
+	}
+	contents, err := os.ReadFile(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if string(contents) != "previous" {
+		t.Fatalf("expecting previous but received: %s", contents)
+	}
+
+	db.Recipients = nil
+	err = file.Save(db)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	saved, err := os.Open(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer saved.Close()
+	reopened, err := database.Open(database.Config{Key: []byte("password")}, saved)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer reopened.Destroy()
+	if secret, _ := reopened.Get("example.com"); secret != "password" {
+		t.Fatalf("expecting password but received: %s", secret)
+	}
+
+	// No temporary files are left and the permissions are kept
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expecting 1 file but received: %d", len(entries))
+	}
+	info, err := saved.Stat()
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Fatalf("expecting %v but received: %v", os.FileMode(0o640), info.Mode().Perm())
+	}
+
+	// The saved file keeps the shared lock
+	err = database.LockExclusive(saved)
+	if !errors.Is(err, database.ErrLocked) {
+		t.Fatalf("expecting %v but received: %v", database.ErrLocked, err)
+	}
+	saved.Close()
+
+	// Saves of other processes since it was opened are never overwritten
+	other := path.Join(dir, "other.json")
+	err = os.WriteFile(other, []byte("other"), 0o600)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	err = os.Rename(other, filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	err = file.Save(db)
+	if !errors.Is(err, database.ErrChanged) {
+		t.Fatalf("expecting %v but received: %v", database.ErrChanged, err)
+	}
+	contents, err = os.ReadFile(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if string(contents) != "other" {
+		t.Fatalf("expecting other but received: %s", contents)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	t.Parallel()
+
+	stored := crypto.Argon{Time: 2, Memory: 128, Threads: 1}
+	weaker := crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = stored
+	db.SaltSize = 32
+	db.Set("example.com", "password")
+
+	var file bytes.Buffer
+	err := db.Save(&file)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	open := func(t *testing.T, file []byte) (db *database.Database) {
+		// Parameters of the config only apply to new databases
+		db, err := database.Open(database.Config{Key: []byte("password"), Argon: weaker, SaltSize: 16}, bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return db
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		db := open(t, file.Bytes())
+		if db.Argon != stored || db.SaltSize != 32 || db.Downgrades() {
+			t.Fatalf("expecting stored parameters but received: %v %d", db.Argon, db.SaltSize)
+		}
+
+		stronger := crypto.Argon{Time: 3, Memory: 128, Threads: 2}
+		err := db.Rekey(stronger, 32)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		var rekeyed bytes.Buffer
+		err = db.Save(&rekeyed)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if db := open(t, rekeyed.Bytes()); db.Argon != stronger {
+			t.Fatalf("expecting %v but received: %v", stronger, db.Argon)
+		}
+
+		db.Rekey(weaker, 16)
+		db.AllowDowngrade = true
+		rekeyed.Reset()
+		err = db.Save(&rekeyed)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if db := open(t, rekeyed.Bytes()); db.Argon != weaker || db.SaltSize != 16 {
+			t.Fatalf("expecting %v but received: %v", weaker, db.Argon)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		db := open(t, file.Bytes())
+		db.Rekey(weaker, 32)
+		err := db.Save(&bytes.Buffer{})
+		if !errors.Is(err, database.ErrDowngrade) {
+			t.Fatalf("expecting %v but received: %v", database.ErrDowngrade, err)
+		}
+		db.Rekey(stored, 16)
+		err = db.Save(&bytes.Buffer{})
+		if !errors.Is(err, database.ErrDowngrade) {
+			t.Fatalf("expecting %v but received: %v", database.ErrDowngrade, err)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File is an open database file. Saving writes a new file and renames it over the old one,
+// a failed save leaves the previous contents untouched
+type File struct {
+	*os.File
+
+	// Database path, the open file changes on every save
+	path string
+}
+
+// OpenFile opens the database file, creating it when missing, with the shared lock
+func OpenFile(name string, perm os.FileMode) (file *File, err error) {
+	for {
+		var f *os.File
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_RDWR, perm)
+		if err != nil {
+			return
+		}
+		file = &File{File: f}
+
+		// Saving replaces the target of symbolic links
+		file.path, err = filepath.EvalSymlinks(name)
+		if err == nil {
+			err = LockShared(f)
+		}
+		var replaced bool
+		if err == nil {
+			replaced, err = file.replaced()
+		}
+		if err != nil {
+			f.Close()
+			file = nil
+			return
+		}
+		if !replaced {
+			return
+		}
+		// Saved by another process between opening and locking it
+		f.Close()
+	}
+}
+
+// replaced reports whether the database path no longer refers to the open file
+func (f *File) replaced() (replaced bool, err error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		err = fmt.Errorf("failed to stat database file: %w", err)
+		return
+	}
+	current, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to stat database file: %w", err)
+		return
+	}
+	return !os.SameFile(info, current), nil
+}
+
+// Save takes the exclusive lock and replaces the file with the database.
+// The new file holds the shared lock once saved
+func (f *File) Save(db *Database) (err error) {
+	defer func() {
+		if err != nil {
+			// A failed conversion may release the lock of the old file
+			LockShared(f.File)
+		}
+	}()
+	err = LockExclusive(f.File)
+	if err != nil {
+		return
+	}
+
+	// Every save replaces the file, a failed conversion may have let another process save it in between
+	replaced, err := f.replaced()
+	if err != nil {
+		return
+	}
+	if replaced {
+		err = fmt.Errorf("%w: %s", ErrChanged, f.path)
+		return
+	}
+
+	info, err := f.File.Stat()
+	if err != nil {
+		err = fmt.Errorf("failed to stat database file: %w", err)
+		return
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		err = fmt.Errorf("failed to create database file: %w", err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	// Locked before other processes can open it
+	err = LockShared(temp)
+	if err != nil {
+		return
+	}
+	err = temp.Chmod(info.Mode().Perm())
+	if err != nil {
+		err = fmt.Errorf("failed to set database file permissions: %w", err)
+		return
+	}
+	err = db.Save(temp)
+	if err != nil {
+		return
+	}
+	err = temp.Sync()
+	if err != nil {
+		err = fmt.Errorf("failed to sync database file: %w", err)
+		return
+	}
+	err = os.Rename(temp.Name(), f.path)
+	if err != nil {
+		err = fmt.Errorf("failed to replace database file: %w", err)
+		return
+	}
+
+	// Reopened by path while the temporary handle keeps the new file locked
+	saved, err := os.OpenFile(f.path, os.O_RDWR, 0)
+	if err == nil {
+		err = LockShared(saved)
+	}
+	temp.Close()
+	if err != nil {
+		err = fmt.Errorf("failed to reopen database file: %w", err)
+		saved.Close()
+		return
+	}
+	f.File.Close()
+	f.File = saved
+	return
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/RogueTeam/guardian/crypto"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+var ErrMissingEntry = errors.New("indexed entry missing from the database file")
+
+// IndexEntry is the non-secret metadata of an entry, decrypted when the database is opened
+type IndexEntry struct {
+	// Random reference of the sealed payload in the envelope
+	Ref string `json:"ref"`
+	// Length of the secret, FUSE uses it without decrypting the entry
+	Size int `json:"size"`
+}
+
+// Payload of VersionIndexed databases, the entries are sealed separately
+type index struct {
+	SaltSize    int
+	Argon       crypto.Argon
+	Entries     map[string]IndexEntry `json:"entries"`
+	RecoveryKey []byte                `json:"recoveryKey,omitempty"`
+	Signers     []string              `json:"signers,omitempty"`
+	// Recipients wrapped on save, the slots of the envelope aren't authenticated.
+	// Always encoded, nil tells the index was written before it was stored
+	Recipients []string `json:"recipients"`
+	// Authenticated copy of the padding of the envelope, nil for indexes written before it was stored
+	Padding  *crypto.Padding `json:"padding"`
+	Modified time.Time       `json:"modified"`
+}
+
+// Sealed payload of a single entry
+type entry struct {
+	Secret string            `json:"secret"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type sealedEntry struct {
+	IndexEntry
+	payload *crypto.Secret
+}
+
+func newRef() string {
+	ref := make([]byte, 16)
+	rand.Read(ref)
+	return hex.EncodeToString(ref)
+}
+
+// entryKey derives the subkey of an entry, binding the payload to its reference
+func (db *Database) entryKey(ref string) (key []byte) {
+	key = make([]byte, crypto.KeySize)
+	io.ReadFull(hkdf.New(sha3.New512, db.DataKey, []byte(ref), []byte("guardian entry")), key)
+	return
+}
+
+func (db *Database) encodeEntry(id string) (data []byte) {
+	var buffer bytes.Buffer
+	json.NewEncoder(&buffer).Encode(entry{Secret: db.Secrets[id], Fields: db.Fields[id]})
+	return buffer.Bytes()
+}
+
+// pad appends JSON whitespace up to length, decoding ignores it
+func pad(data []byte, length int) (padded []byte) {
+	if len(data) >= length {
+		return data
+	}
+	padded = make([]byte, length)
+	copy(padded, data)
+	for index := len(data); index < length; index++ {
+		padded[index] = ' '
+	}
+	rand.Read(data)
+	return
+}
+
+// seal encrypts the entry padded to length bytes
+func (db *Database) seal(id string, length int) (sealed sealedEntry) {
+	data := pad(db.encodeEntry(id), length)
+	defer rand.Read(data)
+
+	sealed.Ref = newRef()
+	sealed.Size = len(db.Secrets[id])
+	job := crypto.Job{
+		Key:      db.entryKey(sealed.Ref),
+		Data:     data,
+		SaltSize: db.SaltSize,
+		KDF:      crypto.KDFHKDF,
+	}
+	defer rand.Read(job.Key)
+	sealed.payload = job.Encrypt()
+	return
+}
+
+// unseal decrypts the entry into Secrets and Fields, it does nothing for entries already in memory
+func (db *Database) unseal(id string) (err error) {
+	sealed, found := db.sealed[id]
+	if !found {
+		return
+	}
+
+	job := crypto.Job{Key: db.entryKey(sealed.Ref)}
+	defer job.Release()
+	err = job.Decrypt(sealed.payload)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decrypt entry %s: %w", ErrCorrupted, id, err)
+		return
+	}
+
+	var e entry
+	err = json.Unmarshal(job.Data, &e)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decode entry %s: %w", ErrCorrupted, id, err)
+		return
+	}
+
+	db.Secrets[id] = e.Secret
+	if len(e.Fields) > 0 {
+		db.Fields[id] = e.Fields
+	}
+	delete(db.sealed, id)
+	return
+}
+
+// Unseal decrypts every entry, needed before iterating Secrets or Fields directly
+func (db *Database) Unseal() (err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.unsealAll()
+}
+
+func (db *Database) unsealAll() (err error) {
+	for id := range db.sealed {
+		err = db.unseal(id)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Size returns the length of the secret without decrypting it
+func (db *Database) Size(id string) (size int, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if sealed, found := db.sealed[id]; found {
+		return sealed.Size, nil
+	}
+	secret, found := db.Secrets[id]
+	if !found {
+		err = fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	return len(secret), err
+}
+
+// entryLength returns the padded length shared by every entry, so their sizes don't tell them apart
+func (db *Database) entryLength() (length int) {
+	if db.Padding.Scheme == crypto.PaddingNone {
+		return 0
+	}
+	for id := range db.Secrets {
+		data := db.encodeEntry(id)
+		length = max(length, len(data))
+		rand.Read(data)
+	}
+	// Sealed entries fit in their cipher, minus the byte Encrypt always adds
+	for _, sealed := range db.sealed {
+		length = max(length, len(sealed.payload.Cipher)-1)
+	}
+	return db.Padding.Length(length)
+}
+
+// encodeIndex seals the entries in memory and reuses the untouched ones.
+// Sealed entries of a different length are sealed again and decoys pad the number of entries
+func (db *Database) encodeIndex() (data []byte, entries map[string]*crypto.Secret, err error) {
+	length := db.entryLength()
+	if length > 0 {
+		cipherLength := crypto.ChunkSize * (1 + length/crypto.ChunkSize)
+		for id, sealed := range db.sealed {
+			if len(sealed.payload.Cipher) == cipherLength {
+				continue
+			}
+			err = db.unseal(id)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	idx := index{
+		SaltSize:    db.SaltSize,
+		Argon:       db.Argon,
+		Entries:     make(map[string]IndexEntry, len(db.Secrets)+len(db.sealed)),
+		RecoveryKey: db.RecoveryKey,
+		Signers:     db.Signers,
+		Recipients:  append([]string{}, db.Recipients...),
+		Padding:     &db.Padding,
+		Modified:    db.Modified,
+	}
+	entries = make(map[string]*crypto.Secret, len(idx.Entries))
+	for id, sealed := range db.sealed {
+		idx.Entries[id] = sealed.IndexEntry
+		entries[sealed.Ref] = sealed.payload
+	}
+	for id := range db.Secrets {
+		sealed := db.seal(id, length)
+		idx.Entries[id] = sealed.IndexEntry
+		entries[sealed.Ref] = sealed.payload
+	}
+	// Decoys are never referenced by the index
+	for count := len(entries); count < db.Padding.Items(len(entries)); count++ {
+		entries[newRef()] = crypto.Decoy(length, db.SaltSize)
+	}
+
+	var buffer bytes.Buffer
+	json.NewEncoder(&buffer).Encode(idx)
+	data = buffer.Bytes()
+	return pad(data, db.Padding.Length(len(data))), entries, nil
+}
+
+func (db *Database) decodeIndex(data []byte, entries map[string]*crypto.Secret) (err error) {
+	var idx index
+	err = json.Unmarshal(data, &idx)
+	if err != nil {
+		err = fmt.Errorf("failed to decode index: %w", err)
+		return
+	}
+
+	db.SaltSize = idx.SaltSize
+	db.Argon = idx.Argon
+	db.RecoveryKey = idx.RecoveryKey
+	db.Signers = idx.Signers
+	// Older indexes keep the recipients read from the slots
+	if idx.Recipients != nil {
+		db.Recipients = idx.Recipients
+	}
+	if idx.Padding != nil {
+		err = idx.Padding.Check()
+		if err != nil {
+			return
+		}
+		db.Padding = *idx.Padding
+	}
+	db.Modified = idx.Modified
+	db.sealed = make(map[string]sealedEntry, len(idx.Entries))
+	for id, indexEntry := range idx.Entries {
+		payload, found := entries[indexEntry.Ref]
+		if !found || payload == nil {
+			err = fmt.Errorf("%w: %s", ErrMissingEntry, id)
+			return
+		}
+		db.sealed[id] = sealedEntry{IndexEntry: indexEntry, payload: payload}
+	}
+	return
+}
@@ -0,0 +1,235 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/RogueTeam/guardian/crypto"
+)
+
+// Algorithms reported by Inspect
+const (
+	AlgorithmSecret = "aes-256-cbc hmac-sha3-512"
+	AlgorithmX25519 = "x25519 chacha20poly1305"
+)
+
+// SecretInfo is the non-secret metadata of an encrypted part of the file
+type SecretInfo struct {
+	Name      string `json:"name"`
+	Algorithm string `json:"algorithm"`
+	// argon2id or hkdf, empty for x25519 slots
+	KDF          string        `json:"kdf,omitempty"`
+	Argon        *crypto.Argon `json:"argon,omitempty"`
+	KeySaltSize  int           `json:"keySaltSize,omitempty"`
+	HMACSaltSize int           `json:"hmacSaltSize,omitempty"`
+	CipherLength int           `json:"cipherLength"`
+	// Number of parts summarized, the sealed entries share a single line
+	Count     int    `json:"count,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+}
+
+// Inspection describes a database file without decrypting it
+type Inspection struct {
+	Version    int          `json:"version"`
+	Keyfile    bool         `json:"keyfile"`
+	Padding    string       `json:"padding"`
+	Signer     string       `json:"signer,omitempty"`
+	Parts      []SecretInfo `json:"parts"`
+	Weaknesses []string     `json:"weaknesses"`
+}
+
+// Policy is the minimum accepted for the parameters of a database
+type Policy struct {
+	Argon    crypto.Argon
+	SaltSize int
+}
+
+// DefaultPolicy follows the OWASP minimum for argon2id
+func DefaultPolicy() Policy {
+	return Policy{
+		Argon:    crypto.Argon{Time: 2, Memory: 19 * 1024, Threads: 1},
+		SaltSize: 16,
+	}
+}
+
+// Inspect decodes the metadata of the database file, no key is needed
+func Inspect(r io.Reader) (inspection Inspection, err error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("failed to read database: %w", err)
+		return
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	err = json.Unmarshal(contents, &header)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decode header: %w", ErrCorrupted, err)
+		return
+	}
+	inspection.Version = header.Version
+	inspection.Padding = crypto.Padding{}.String()
+
+	switch header.Version {
+	case VersionSecret:
+		var secret crypto.Secret
+		err = json.Unmarshal(contents, &secret)
+		if err == nil {
+			inspection.Parts = append(inspection.Parts, secretInfo("database", &secret, 1))
+		}
+	case VersionEnvelope, VersionIndexed:
+		var envelope Envelope
+		err = json.Unmarshal(contents, &envelope)
+		if err == nil {
+			inspection.inspectEnvelope(&envelope)
+		}
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	return
+}
+
+func (i *Inspection) inspectEnvelope(envelope *Envelope) {
+	i.Keyfile = envelope.Keyfile
+	if envelope.Padding != nil {
+		i.Padding = envelope.Padding.String()
+	}
+	if envelope.Signature != nil {
+		i.Signer = envelope.Signature.Signer
+	}
+
+	for index := range envelope.Slots {
+		slot := &envelope.Slots[index]
+		name := fmt.Sprintf("slot %d %s", index, slot.Type)
+		if slot.Secret != nil {
+			i.Parts = append(i.Parts, secretInfo(name, slot.Secret, 1))
+			continue
+		}
+		i.Parts = append(i.Parts, SecretInfo{
+			Name:         name,
+			Algorithm:    AlgorithmX25519,
+			CipherLength: len(slot.Wrapped),
+			Count:        1,
+			Recipient:    slot.Recipient,
+		})
+	}
+	if envelope.Payload != nil {
+		i.Parts = append(i.Parts, secretInfo("payload", envelope.Payload, 1))
+	}
+
+	// Entries are summarized with the largest one, decoys can't be told apart
+	var largest *crypto.Secret
+	for _, entry := range envelope.Entries {
+		if entry != nil && (largest == nil || len(entry.Cipher) > len(largest.Cipher)) {
+			largest = entry
+		}
+	}
+	if largest != nil {
+		i.Parts = append(i.Parts, secretInfo("entries", largest, len(envelope.Entries)))
+	}
+}
+
+func secretInfo(name string, secret *crypto.Secret, count int) (info SecretInfo) {
+	info = SecretInfo{
+		Name:         name,
+		Algorithm:    AlgorithmSecret,
+		KDF:          "argon2id",
+		KeySaltSize:  len(secret.KeySalt),
+		HMACSaltSize: len(secret.HMACSalt),
+		CipherLength: len(secret.Cipher),
+		Count:        count,
+	}
+	if secret.KDF == crypto.KDFHKDF {
+		info.KDF = crypto.KDFHKDF
+		return
+	}
+	argon := secret.Argon
+	info.Argon = &argon
+	return
+}
+
+// Assess lists the parts of the inspection below the policy
+func (p Policy) Assess(inspection *Inspection) {
+	inspection.Weaknesses = []string{}
+	weak := func(format string, a ...any) {
+		inspection.Weaknesses = append(inspection.Weaknesses, fmt.Sprintf(format, a...))
+	}
+
+	if inspection.Version == VersionSecret {
+		weak("version %d has no envelope, saving it with a newer guardian upgrades it", inspection.Version)
+	}
+	for _, part := range inspection.Parts {
+		if part.Argon != nil {
+			argon := part.Argon
+			if argon.Time < p.Argon.Time {
+				weak("%s: argon time %d below %d", part.Name, argon.Time, p.Argon.Time)
+			}
+			if argon.Memory < p.Argon.Memory {
+				weak("%s: argon memory %d KiB below %d KiB", part.Name, argon.Memory, p.Argon.Memory)
+			}
+			if argon.Threads < p.Argon.Threads {
+				weak("%s: argon threads %d below %d", part.Name, argon.Threads, p.Argon.Threads)
+			}
+		}
+		if part.Algorithm == AlgorithmSecret && min(part.KeySaltSize, part.HMACSaltSize) < p.SaltSize {
+			weak("%s: salt size %d bytes below %d", part.Name, min(part.KeySaltSize, part.HMACSaltSize), p.SaltSize)
+		}
+	}
+}
+
+// Table formats the inspection for terminals
+func (i Inspection) Table() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "version : %d\n", i.Version)
+	fmt.Fprintf(&buf, "keyfile : %t\n", i.Keyfile)
+	fmt.Fprintf(&buf, "padding : %s\n", i.Padding)
+	if i.Signer != "" {
+		fmt.Fprintf(&buf, "signer  : %s\n", i.Signer)
+	}
+
+	rows := [][]string{{"PART", "COUNT", "ALGORITHM", "KDF", "ARGON (T/M/P)", "SALTS", "CIPHER"}}
+	for _, part := range i.Parts {
+		argon, salts := "-", "-"
+		if part.Argon != nil {
+			argon = fmt.Sprintf("%d/%d/%d", part.Argon.Time, part.Argon.Memory, part.Argon.Threads)
+		}
+		if part.Algorithm == AlgorithmSecret {
+			salts = fmt.Sprintf("%d/%d", part.KeySaltSize, part.HMACSaltSize)
+		}
+		kdf := part.KDF
+		if kdf == "" {
+			kdf = "-"
+		}
+		rows = append(rows, []string{part.Name, fmt.Sprint(part.Count), part.Algorithm, kdf, argon, salts, fmt.Sprint(part.CipherLength)})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for column, cell := range row {
+			widths[column] = max(widths[column], len(cell))
+		}
+	}
+	buf.WriteString("\n")
+	for _, row := range rows {
+		var line strings.Builder
+		for column, cell := range row {
+			fmt.Fprintf(&line, "%-*s  ", widths[column], cell)
+		}
+		buf.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+
+	buf.WriteString("\n")
+	if len(i.Weaknesses) == 0 {
+		buf.WriteString("no weaknesses found\n")
+	}
+	for _, weakness := range i.Weaknesses {
+		fmt.Fprintf(&buf, "weak: %s\n", weakness)
+	}
+	return buf.String()
+}
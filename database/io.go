@@ -5,40 +5,167 @@ import (
 	"sort"
 )
 
+// Well known field names
+const (
+	FieldUsername = "username"
+	FieldURL      = "url"
+	FieldNotes    = "notes"
+	FieldTOTP     = "totp"
+	FieldTags     = "tags"
+	// Attachments are stored base64 encoded under this prefix followed by the file name
+	FieldAttachmentPrefix = "attachment/"
+	// Previous versions are stored as history/<n>/<field>, the old secret uses FieldHistorySecret
+	FieldHistoryPrefix = "history/"
+	FieldHistorySecret = "secret"
+)
 
+// Set replaces the secret keeping the fields, a sealed entry that can't be decrypted loses them
 func (db *Database) Set(id string, data string) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.unseal(id)
+	delete(db.sealed, id)
 	db.Secrets[id] = data
 }
 
 func (db *Database) Lookup(id string) (found bool, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
 	_, found = db.Secrets[id]
+	if !found {
+		_, found = db.sealed[id]
+	}
 	return
 }
 
 func (db *Database) Get(id string) (data string, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err = db.unseal(id)
+	if err != nil {
+		return
+	}
 	data, found := db.Secrets[id]
 	if !found {
-		err = fmt.Errorf("no entry found with id: %s", id)
+		err = fmt.Errorf("%w: %s", ErrEntryNotFound, id)
 	}
 	return
 }
 
+// Del removes the entry, sealed entries are removed without decrypting them
 func (db *Database) Del(id string) (err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
 	_, found := db.Secrets[id]
-	if !found {
-		err = fmt.Errorf("no entry found with id: %s", id)
+	_, sealed := db.sealed[id]
+	if !found && !sealed {
+		err = fmt.Errorf("%w: %s", ErrEntryNotFound, id)
 		return
 	}
 
 	delete(db.Secrets, id)
+	delete(db.Fields, id)
+	delete(db.sealed, id)
+	return
+}
+
+// SetField sets a named value of the entry, creating the entry when missing
+func (db *Database) SetField(id, name, value string) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.unseal(id)
+	delete(db.sealed, id)
+	db.setField(id, name, value)
+}
+
+func (db *Database) setField(id, name, value string) {
+	if _, found := db.Secrets[id]; !found {
+		db.Secrets[id] = ""
+	}
+
+	fields, found := db.Fields[id]
+	if !found {
+		fields = make(map[string]string)
+		db.Fields[id] = fields
+	}
+	fields[name] = value
+}
+
+func (db *Database) GetField(id, name string) (value string, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err = db.unseal(id)
+	if err != nil {
+		return
+	}
+	value, found := db.Fields[id][name]
+	if !found {
+		err = fmt.Errorf("no field %s found in entry with id: %s", name, id)
+	}
 	return
 }
 
+// GetFields returns a copy of all the fields of the entry
+func (db *Database) GetFields(id string) (fields map[string]string, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err = db.unseal(id)
+	if err != nil {
+		return
+	}
+	_, found := db.Secrets[id]
+	if !found {
+		err = fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+		return
+	}
+
+	fields = make(map[string]string, len(db.Fields[id]))
+	for name, value := range db.Fields[id] {
+		fields[name] = value
+	}
+	return
+}
+
+func (db *Database) DelField(id, name string) (err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	err = db.unseal(id)
+	if err != nil {
+		return
+	}
+	_, found := db.Fields[id][name]
+	if !found {
+		err = fmt.Errorf("no field %s found in entry with id: %s", name, id)
+		return
+	}
+
+	delete(db.Fields[id], name)
+	if len(db.Fields[id]) == 0 {
+		delete(db.Fields, id)
+	}
+	return
+}
+
+// List returns the sorted ids without decrypting the entries
 func (db *Database) List() (names []string, err error) {
-	names = make([]string, 0, len(db.Secrets))
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	names = make([]string, 0, len(db.Secrets)+len(db.sealed))
 	for key := range db.Secrets {
 		names = append(names, key)
 	}
+	for key := range db.sealed {
+		names = append(names, key)
+	}
 	sort.Strings(names)
 	return
 }
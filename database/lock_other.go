@@ -0,0 +1,15 @@
+//go:build !unix
+
+package database
+
+import "os"
+
+// LockShared is only supported on unix
+func LockShared(file *os.File) (err error) {
+	return
+}
+
+// LockExclusive is only supported on unix
+func LockExclusive(file *os.File) (err error) {
+	return
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Time waited for the lock of another process before failing with ErrLocked
+const lockTimeout = time.Second
+
+// LockShared takes a shared advisory lock of the database file, released when it is closed.
+// Readers share it, only LockExclusive waits for them
+func LockShared(file *os.File) (err error) {
+	return flock(file, unix.LOCK_SH)
+}
+
+// LockExclusive takes the exclusive advisory lock of the database file, needed to save it.
+// Converting a shared lock isn't atomic, a failed conversion may release it
+func LockExclusive(file *os.File) (err error) {
+	return flock(file, unix.LOCK_EX)
+}
+
+// flock retries the lock until lockTimeout, processes holding the lock for longer fail it with ErrLocked
+func flock(file *os.File, how int) (err error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err = unix.Flock(int(file.Fd()), how|unix.LOCK_NB)
+		if !errors.Is(err, unix.EWOULDBLOCK) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		err = fmt.Errorf("%w: %s", ErrLocked, file.Name())
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to lock database file: %w", err)
+	}
+	return
+}
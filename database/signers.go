@@ -0,0 +1,66 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/RogueTeam/guardian/crypto"
+)
+
+// sign signs the envelope contents, everything but the signature itself
+func (e *Envelope) sign(key ed25519.PrivateKey) (err error) {
+	e.Signature = nil
+	message, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	e.Signature = crypto.Sign(key, message)
+	return
+}
+
+func (e *Envelope) verify() (err error) {
+	signature := e.Signature
+	e.Signature = nil
+	defer func() { e.Signature = signature }()
+
+	message, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	return signature.Verify(message)
+}
+
+// Trusted reports whether the opened file was signed by a trusted signer
+func (db *Database) Trusted() bool {
+	return db.Signer != "" && slices.Contains(db.Signers, db.Signer)
+}
+
+// TrustSigner trusts the devices signing with the key of the encoded signer
+func (db *Database) TrustSigner(signer string) (err error) {
+	key, err := crypto.ParseSigner(signer)
+	if err != nil {
+		return
+	}
+	signer = crypto.EncodeSigner(key)
+	if slices.Contains(db.Signers, signer) {
+		err = fmt.Errorf("%w: %s", ErrSignerExists, signer)
+		return
+	}
+
+	db.Signers = append(db.Signers, signer)
+	return
+}
+
+// UntrustSigner removes the signer from the trusted ones
+func (db *Database) UntrustSigner(signer string) (err error) {
+	index := slices.Index(db.Signers, signer)
+	if index < 0 {
+		err = fmt.Errorf("%w: %s", ErrSignerNotFound, signer)
+		return
+	}
+
+	db.Signers = slices.Delete(db.Signers, index, index+1)
+	return
+}
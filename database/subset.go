@@ -0,0 +1,70 @@
+package database
+
+import (
+	"sort"
+	"strings"
+)
+
+// Subset returns a standalone database holding a copy of the entries whose id starts with prefix.
+// The key and crypto settings are not copied, set them before saving
+func (db *Database) Subset(prefix string) (sub *Database, err error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for id := range db.sealed {
+		if strings.HasPrefix(id, prefix) {
+			err = db.unseal(id)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	sub = New()
+	sub.Padding = db.Padding
+	sub.Signers = append([]string(nil), db.Signers...)
+	for id, secret := range db.Secrets {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		sub.Secrets[id] = secret
+		if fields, found := db.Fields[id]; found {
+			sub.Fields[id] = make(map[string]string, len(fields))
+			for name, value := range fields {
+				sub.Fields[id][name] = value
+			}
+		}
+	}
+	return
+}
+
+// Merge copies the entries of other into the database. Existing entries are only
+// replaced when overwrite is set, the ids of the kept ones are returned in skipped
+func (db *Database) Merge(other *Database, overwrite bool) (merged, skipped []string, err error) {
+	err = other.Unseal()
+	if err != nil {
+		return
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	for id, secret := range other.Secrets {
+		_, found := db.Secrets[id]
+		_, sealed := db.sealed[id]
+		if (found || sealed) && !overwrite {
+			skipped = append(skipped, id)
+			continue
+		}
+
+		delete(db.sealed, id)
+		db.Secrets[id] = secret
+		delete(db.Fields, id)
+		for name, value := range other.Fields[id] {
+			db.setField(id, name, value)
+		}
+		merged = append(merged, id)
+	}
+	sort.Strings(merged)
+	sort.Strings(skipped)
+	return
+}
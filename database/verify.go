@@ -0,0 +1,224 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/RogueTeam/guardian/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Report summarizes a verified database, nothing secret is included
+type Report struct {
+	Version int `json:"version"`
+	Entries int `json:"entries"`
+	// Random entries padding the entry count
+	Decoys     int          `json:"decoys"`
+	Fields     int          `json:"fields"`
+	Slots      []string     `json:"slots"`
+	Recipients int          `json:"recipients"`
+	Padding    string       `json:"padding"`
+	Argon      crypto.Argon `json:"argon"`
+	SaltSize   int          `json:"saltSize"`
+	Signer     string       `json:"signer,omitempty"`
+	Trusted    bool         `json:"trusted"`
+	Modified   *time.Time   `json:"modified,omitempty"`
+}
+
+// Verify checks the structure of the database file and decrypts every entry without modifying it.
+// Failures wrap ErrUnsupportedVersion, ErrCorrupted or the error of a wrong key
+func Verify(config Config, r io.Reader) (report Report, err error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("failed to read database: %w", err)
+		return
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	err = json.Unmarshal(contents, &header)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to decode header: %w", ErrCorrupted, err)
+		return
+	}
+	report.Version = header.Version
+
+	var envelope Envelope
+	switch header.Version {
+	case VersionSecret:
+		var secret crypto.Secret
+		err = json.Unmarshal(contents, &secret)
+		if err == nil && secret.Argon != (crypto.Argon{}) {
+			err = checkSecret(&secret, crypto.KDFArgon2id)
+		}
+	case VersionEnvelope, VersionIndexed:
+		err = json.Unmarshal(contents, &envelope)
+		if err == nil {
+			err = checkEnvelope(&envelope)
+		}
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedVersion, header.Version)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+		return
+	}
+
+	db, err := Open(config, bytes.NewReader(contents))
+	if err != nil {
+		return
+	}
+	defer db.Destroy()
+	err = db.Unseal()
+	if err != nil {
+		return
+	}
+	// The padding of the envelope is informational, removing it must not go unnoticed
+	if header.Version == VersionIndexed {
+		var padding crypto.Padding
+		if envelope.Padding != nil {
+			padding = *envelope.Padding
+		}
+		if padding != db.Padding {
+			err = fmt.Errorf("%w: envelope padding %s differs from the payload one %s", ErrCorrupted, padding, db.Padding)
+			return
+		}
+	}
+	// Recipient slots missing from the authenticated list were added without the key
+	for _, slot := range envelope.Slots {
+		if slot.Type == crypto.SlotX25519 && !slices.Contains(db.Recipients, slot.Recipient) {
+			err = fmt.Errorf("%w: recipient slot missing from the payload: %s", ErrCorrupted, slot.Recipient)
+			return
+		}
+	}
+
+	report.Entries = len(db.Secrets)
+	for _, fields := range db.Fields {
+		report.Fields += len(fields)
+	}
+	if header.Version == VersionIndexed {
+		report.Decoys = len(envelope.Entries) - report.Entries
+	}
+	report.Slots = make([]string, 0, len(envelope.Slots))
+	for _, slot := range envelope.Slots {
+		report.Slots = append(report.Slots, slot.Type)
+	}
+	report.Recipients = len(db.Recipients)
+	report.Padding = db.Padding.String()
+	report.Argon = db.Argon
+	report.SaltSize = db.SaltSize
+	report.Signer = db.Signer
+	report.Trusted = db.Trusted()
+	if !db.Modified.IsZero() {
+		modified := db.Modified
+		report.Modified = &modified
+	}
+	return
+}
+
+func checkEnvelope(envelope *Envelope) (err error) {
+	if len(envelope.Slots) == 0 {
+		return errors.New("no slots")
+	}
+	for index, slot := range envelope.Slots {
+		switch slot.Type {
+		case crypto.SlotPassword:
+			err = checkSecret(slot.Secret, crypto.KDFArgon2id)
+		case crypto.SlotRecovery:
+			err = checkSecret(slot.Secret, crypto.KDFHKDF)
+		case crypto.SlotX25519:
+			_, err = crypto.ParseRecipient(slot.Recipient)
+			if err == nil && (len(slot.Ephemeral) != 32 || len(slot.Wrapped) != crypto.KeySize+chacha20poly1305.Overhead) {
+				err = errors.New("invalid wrapped key")
+			}
+		default:
+			err = fmt.Errorf("%w: %s", crypto.ErrUnknownSlotType, slot.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("slot %d: %w", index, err)
+		}
+	}
+
+	err = checkSecret(envelope.Payload, crypto.KDFHKDF)
+	if err != nil {
+		return fmt.Errorf("payload: %w", err)
+	}
+	if envelope.Version == VersionEnvelope && len(envelope.Entries) > 0 {
+		return fmt.Errorf("entries in a version %d database", envelope.Version)
+	}
+	for ref, entry := range envelope.Entries {
+		err = checkSecret(entry, crypto.KDFHKDF)
+		if err != nil {
+			return fmt.Errorf("entry %s: %w", ref, err)
+		}
+	}
+
+	if envelope.Padding != nil {
+		err = checkPadding(envelope)
+	}
+	return
+}
+
+// checkPadding checks the payload and the entries were padded as the envelope says
+func checkPadding(envelope *Envelope) (err error) {
+	padding := *envelope.Padding
+	err = padding.Check()
+	if err != nil {
+		return
+	}
+
+	padded := func(secret *crypto.Secret) bool {
+		length := len(secret.Cipher) - 1
+		return padding.Length(length) == length
+	}
+	if !padded(envelope.Payload) {
+		return fmt.Errorf("payload not padded with %s", padding)
+	}
+	if padding.Items(len(envelope.Entries)) != len(envelope.Entries) {
+		return fmt.Errorf("entry count %d not padded with %s", len(envelope.Entries), padding)
+	}
+	length := -1
+	for ref, entry := range envelope.Entries {
+		if length >= 0 && len(entry.Cipher) != length || !padded(entry) {
+			return fmt.Errorf("entry %s not padded with %s", ref, padding)
+		}
+		length = len(entry.Cipher)
+	}
+	return
+}
+
+// checkSecret checks the sizes of the secret and the sanity of its argon parameters
+func checkSecret(secret *crypto.Secret, kdf string) (err error) {
+	switch {
+	case secret == nil:
+		return errors.New("missing secret")
+	case secret.KDF != kdf:
+		return fmt.Errorf("unexpected kdf %q", secret.KDF)
+	case len(secret.IV) != crypto.IVSize:
+		return fmt.Errorf("invalid IV size %d", len(secret.IV))
+	case len(secret.KeySalt) == 0 || len(secret.HMACSalt) == 0:
+		return errors.New("missing salt")
+	case len(secret.HMAC) != crypto.ChecksumSize:
+		return fmt.Errorf("invalid HMAC size %d", len(secret.HMAC))
+	case len(secret.Cipher) == 0 || len(secret.Cipher)%crypto.ChunkSize != 0:
+		return fmt.Errorf("invalid cipher size %d", len(secret.Cipher))
+	}
+
+	argon := secret.Argon
+	if kdf == crypto.KDFHKDF {
+		if argon != (crypto.Argon{}) {
+			return errors.New("unexpected argon parameters")
+		}
+		return
+	}
+	if argon.Time == 0 || argon.Threads == 0 || argon.Memory < 8*uint32(argon.Threads) {
+		return fmt.Errorf("invalid argon parameters: %+v", argon)
+	}
+	return
+}
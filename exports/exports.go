@@ -0,0 +1,174 @@
+// Package exports writes the guardian database in formats understood by other tools
+package exports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Supported formats
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatDotenv = "dotenv"
+	FormatKDBX   = "kdbx"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Plaintext reports if the format writes the secrets unencrypted
+func Plaintext(format string) bool {
+	return format != FormatKDBX
+}
+
+// Filter selects the exported entries, empty values match everything
+type Filter struct {
+	Prefix string
+	Tag    string
+}
+
+// Ids returns the sorted ids of the entries matching the filter
+func (f Filter) Ids(db *database.Database) (ids []string) {
+	all, _ := db.List()
+	for _, id := range all {
+		if !strings.HasPrefix(id, f.Prefix) {
+			continue
+		}
+		if f.Tag != "" {
+			tags, _ := db.GetField(id, database.FieldTags)
+			if !hasTag(tags, f.Tag) {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	return
+}
+
+// load decrypts the entry, sealed entries are only opened when exported
+func load(db *database.Database, id string) (entry Entry, err error) {
+	entry.Id = id
+	entry.Secret, err = db.Get(id)
+	if err != nil {
+		return
+	}
+	entry.Fields, err = db.GetFields(id)
+	if len(entry.Fields) == 0 {
+		entry.Fields = nil
+	}
+	return
+}
+
+// Tags are separated by commas or semicolons depending on the source
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ';' }) {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry is the JSON representation of an exported entry
+type Entry struct {
+	Id     string            `json:"id"`
+	Secret string            `json:"secret"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func JSON(w io.Writer, db *database.Database, ids []string) (err error) {
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		var entry Entry
+		entry, err = load(db, id)
+		if err != nil {
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(entries)
+	if err != nil {
+		err = fmt.Errorf("failed to encode json: %w", err)
+	}
+	return
+}
+
+// CSV uses the columns of the generic import: id, secret and one column per field name
+func CSV(w io.Writer, db *database.Database, ids []string) (err error) {
+	entries := make([]Entry, 0, len(ids))
+	names := map[string]struct{}{}
+	for _, id := range ids {
+		var entry Entry
+		entry, err = load(db, id)
+		if err != nil {
+			return
+		}
+		entries = append(entries, entry)
+		for name := range entry.Fields {
+			names[name] = struct{}{}
+		}
+	}
+	columns := make([]string, 0, len(names))
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"id", "secret"}, columns...))
+	for _, entry := range entries {
+		record := []string{entry.Id, entry.Secret}
+		for _, column := range columns {
+			record = append(record, entry.Fields[column])
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+
+	err = writer.Error()
+	if err != nil {
+		err = fmt.Errorf("failed to write csv: %w", err)
+	}
+	return
+}
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// EnvName converts an id to a variable name, "aws/prod-key" becomes AWS_PROD_KEY
+func EnvName(id string) (name string) {
+	name = invalidEnvChars.ReplaceAllString(strings.ToUpper(id), "_")
+	name = strings.Trim(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return
+}
+
+// Dotenv writes one variable per entry secret, fields are not exported
+func Dotenv(w io.Writer, db *database.Database, ids []string) (err error) {
+	for _, id := range ids {
+		var secret string
+		secret, err = db.Get(id)
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "%s=%s\n", EnvName(id), strconv.Quote(secret))
+		if err != nil {
+			err = fmt.Errorf("failed to write dotenv: %w", err)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,170 @@
+package exports_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/exports"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+func fixture() *database.Database {
+	db := database.New()
+	db.Set("aws/prod-key", "AKIA\"quoted\"")
+	db.SetField("aws/prod-key", database.FieldTags, "cloud;prod")
+	db.Set("example.com", "password")
+	db.SetField("example.com", database.FieldUsername, "sulcud")
+	db.SetField("example.com", database.FieldURL, "https://example.com")
+	db.Set("work/vpn/office", "current")
+	db.SetField("work/vpn/office", database.FieldTOTP, "otpauth://totp/office")
+	// Lowercase, the CSV import lowercases the header names
+	db.SetField("work/vpn/office", "pin", "1234")
+	db.SetField("work/vpn/office", database.FieldAttachmentPrefix+"config.ovpn", base64.StdEncoding.EncodeToString([]byte("remote vpn")))
+	db.SetField("work/vpn/office", database.FieldHistoryPrefix+"0/"+database.FieldHistorySecret, "old")
+	db.SetField("work/vpn/office", database.FieldHistoryPrefix+"0/"+database.FieldUsername, "old-user")
+	return db
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		Name   string
+		Filter exports.Filter
+		Expect []string
+	}
+
+	tests := []Test{
+		{"All", exports.Filter{}, []string{"aws/prod-key", "example.com", "work/vpn/office"}},
+		{"Prefix", exports.Filter{Prefix: "work/"}, []string{"work/vpn/office"}},
+		{"Tag", exports.Filter{Tag: "PROD"}, []string{"aws/prod-key"}},
+		{"No match", exports.Filter{Prefix: "aws/", Tag: "dev"}, nil},
+	}
+
+	db := fixture()
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			ids := test.Filter.Ids(db)
+			if !reflect.DeepEqual(test.Expect, ids) {
+				t.Fatalf("expecting %v but received: %v", test.Expect, ids)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	db := fixture()
+	ids := exports.Filter{}.Ids(db)
+
+	// Every format but dotenv can be imported back without losing data
+	expect := make([]imports.Entry, 0, len(ids))
+	for _, id := range ids {
+		fields, _ := db.GetFields(id)
+		entry := imports.Entry{Id: id, Secret: []byte(db.Secrets[id]), Fields: make(map[string][]byte, len(fields))}
+		for name, value := range fields {
+			entry.Fields[name] = []byte(value)
+		}
+		expect = append(expect, entry)
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := exports.JSON(&buf, db, ids)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		var entries []exports.Entry
+		err = json.Unmarshal(buf.Bytes(), &entries)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		for index, entry := range entries {
+			fields, _ := db.GetFields(entry.Id)
+			if entry.Id != expect[index].Id || entry.Secret != db.Secrets[entry.Id] || !reflect.DeepEqual(entry.Fields, fields) {
+				t.Fatalf("expecting %+v but received: %+v", expect[index], entry)
+			}
+		}
+	})
+	t.Run("CSV", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := exports.CSV(&buf, db, ids)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		entries, err := imports.CSV(&buf, imports.FormatGeneric, nil)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if !reflect.DeepEqual(expect, entries) {
+			t.Fatalf("expecting %+v but received: %+v", expect, entries)
+		}
+	})
+	t.Run("Dotenv", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := exports.Dotenv(&buf, db, ids)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		expect := "AWS_PROD_KEY=\"AKIA\\\"quoted\\\"\"\nEXAMPLE_COM=\"password\"\nWORK_VPN_OFFICE=\"current\"\n"
+		if buf.String() != expect {
+			t.Fatalf("expecting %q but received: %q", expect, buf.String())
+		}
+	})
+	t.Run("KDBX", func(t *testing.T) {
+		t.Parallel()
+
+		key := kdbx.Key{Password: []byte("contractor")}
+		options := kdbx.Options{Cipher: kdbx.CipherChaCha20, KDF: kdbx.KDFAES, Iterations: 10, Compress: true}
+
+		var buf bytes.Buffer
+		err := exports.KDBX(&buf, db, ids, key, options)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		entries, err := imports.KDBX(&buf, key)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		// Entries of the root group are written before the subgroups
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+		if !reflect.DeepEqual(expect, entries) {
+			t.Fatalf("expecting %+v but received: %+v", expect, entries)
+		}
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"aws/prod-key": "AWS_PROD_KEY",
+		"1password":    "_1PASSWORD",
+		"///":          "_",
+	}
+	for id, expect := range tests {
+		if name := exports.EnvName(id); name != expect {
+			t.Fatalf("expecting %s but received: %s", expect, name)
+		}
+	}
+}
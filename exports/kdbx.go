@@ -0,0 +1,116 @@
+package exports
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+// Reverse of the mapping used by the KDBX import
+var kdbxStrings = map[string]string{
+	database.FieldUsername: kdbx.UserName,
+	database.FieldURL:      kdbx.URL,
+	database.FieldNotes:    kdbx.Notes,
+	database.FieldTOTP:     "otp",
+}
+
+// KDBX writes the entries as a KeePass database, ids are split into groups by "/"
+func KDBX(w io.Writer, db *database.Database, ids []string, key kdbx.Key, options kdbx.Options) (err error) {
+	root := &kdbx.Group{Name: "Root"}
+	for _, id := range ids {
+		group := root
+		segments := strings.Split(id, "/")
+		for _, name := range segments[:len(segments)-1] {
+			group = subgroup(group, name)
+		}
+		var entry Entry
+		entry, err = load(db, id)
+		if err != nil {
+			return
+		}
+		group.Entries = append(group.Entries, kdbxEntry(segments[len(segments)-1], entry.Secret, entry.Fields))
+	}
+
+	err = kdbx.Encode(w, &kdbx.Database{Name: "guardian", Root: root}, key, options)
+	if err != nil {
+		err = fmt.Errorf("failed to encode kdbx: %w", err)
+	}
+	return
+}
+
+func subgroup(parent *kdbx.Group, name string) (group *kdbx.Group) {
+	for _, group = range parent.Groups {
+		if group.Name == name {
+			return
+		}
+	}
+	group = &kdbx.Group{Name: name}
+	parent.Groups = append(parent.Groups, group)
+	return
+}
+
+func kdbxEntry(title, secret string, fields map[string]string) (entry *kdbx.Entry) {
+	entry = &kdbx.Entry{
+		Strings: []kdbx.String{
+			{Key: kdbx.Title, Value: title},
+			{Key: kdbx.Password, Value: secret, Protected: true},
+		},
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	history := map[int]map[string]string{}
+	for _, name := range names {
+		value := fields[name]
+		switch {
+		case name == database.FieldTags:
+			entry.Tags = value
+		case strings.HasPrefix(name, database.FieldAttachmentPrefix):
+			data, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				data = []byte(value)
+			}
+			entry.Attachments = append(entry.Attachments, kdbx.Attachment{Name: strings.TrimPrefix(name, database.FieldAttachmentPrefix), Data: data})
+		case strings.HasPrefix(name, database.FieldHistoryPrefix):
+			index, field, found := strings.Cut(strings.TrimPrefix(name, database.FieldHistoryPrefix), "/")
+			n, err := strconv.Atoi(index)
+			if !found || err != nil {
+				entry.Strings = append(entry.Strings, kdbx.String{Key: name, Value: value})
+				continue
+			}
+			if history[n] == nil {
+				history[n] = map[string]string{}
+			}
+			history[n][field] = value
+		default:
+			key, known := kdbxStrings[name]
+			if !known {
+				key = name
+			}
+			entry.Strings = append(entry.Strings, kdbx.String{Key: key, Value: value, Protected: name == database.FieldTOTP})
+		}
+	}
+
+	indexes := make([]int, 0, len(history))
+	for index := range history {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		old := history[index]
+		secret := old[database.FieldHistorySecret]
+		delete(old, database.FieldHistorySecret)
+		entry.History = append(entry.History, kdbxEntry(title, secret, old))
+	}
+	return
+}
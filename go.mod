@@ -4,6 +4,7 @@ go 1.21.4
 
 require (
 	golang.org/x/crypto v0.16.0
+	golang.org/x/sys v0.15.0
 	golang.org/x/term v0.15.0
 )
 
@@ -86,7 +87,6 @@ require (
 	golang.org/x/mod v0.13.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sync v0.4.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.14.0 // indirect
 	google.golang.org/protobuf v1.30.0 // indirect
@@ -0,0 +1,343 @@
+package imports
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Bitwarden item types
+const (
+	bitwardenLogin = iota + 1
+	bitwardenSecureNote
+	bitwardenCard
+	bitwardenIdentity
+	bitwardenSSHKey
+)
+
+// Bitwarden key derivation functions
+const (
+	bitwardenPBKDF2 = iota
+	bitwardenArgon2id
+)
+
+// Custom fields linked to other item properties have no value
+const bitwardenFieldLinked = 3
+
+// Prefix of AES-256-CBC with HMAC-SHA256 encrypted strings
+const bitwardenEncStringV2 = "2."
+
+var (
+	ErrAccountEncrypted = errors.New("account encrypted exports are not supported, export with a password instead")
+	ErrInvalidPassword  = errors.New("invalid export password")
+	ErrInvalidEncString = errors.New("invalid bitwarden encrypted string")
+	ErrUnsupportedKDF   = errors.New("unsupported bitwarden kdf")
+	ErrPasswordRequired = errors.New("export password required")
+	ErrInvalidBitwarden = errors.New("invalid bitwarden export")
+)
+
+type bitwardenExport struct {
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Salt              string `json:"salt"`
+	KDFType           int    `json:"kdfType"`
+	KDFIterations     int    `json:"kdfIterations"`
+	KDFMemory         int    `json:"kdfMemory"`
+	KDFParallelism    int    `json:"kdfParallelism"`
+	Validation        string `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string `json:"data"`
+
+	Folders []bitwardenFolder `json:"folders"`
+	Items   []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	FolderId string `json:"folderId"`
+	Type     int    `json:"type"`
+	Name     string `json:"name"`
+	Notes    string `json:"notes"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		URIs []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+		Username string            `json:"username"`
+		Password string            `json:"password"`
+		TOTP     string            `json:"totp"`
+		FIDO2    []json.RawMessage `json:"fido2Credentials"`
+	} `json:"login"`
+	Card     map[string]any `json:"card"`
+	Identity map[string]any `json:"identity"`
+	SSHKey   *struct {
+		PrivateKey  string `json:"privateKey"`
+		PublicKey   string `json:"publicKey"`
+		Fingerprint string `json:"keyFingerprint"`
+	} `json:"sshKey"`
+	PasswordHistory []struct {
+		Password string `json:"password"`
+	} `json:"passwordHistory"`
+	CollectionIds []string `json:"collectionIds"`
+}
+
+// Stretched keys used by the encrypted strings
+type bitwardenKey struct {
+	enc, mac []byte
+}
+
+func newBitwardenKey(export *bitwardenExport, password []byte) (key bitwardenKey, err error) {
+	var master []byte
+	switch export.KDFType {
+	case bitwardenPBKDF2:
+		if export.KDFIterations <= 0 {
+			err = fmt.Errorf("%w: invalid pbkdf2 iterations", ErrInvalidBitwarden)
+			return
+		}
+		master = pbkdf2.Key(password, []byte(export.Salt), export.KDFIterations, 32, sha256.New)
+	case bitwardenArgon2id:
+		if export.KDFIterations <= 0 || export.KDFMemory <= 0 || export.KDFParallelism <= 0 {
+			err = fmt.Errorf("%w: invalid argon2id parameters", ErrInvalidBitwarden)
+			return
+		}
+		salt := sha256.Sum256([]byte(export.Salt))
+		master = argon2.IDKey(password, salt[:], uint32(export.KDFIterations), uint32(export.KDFMemory)*1024, uint8(export.KDFParallelism), 32)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedKDF, export.KDFType)
+		return
+	}
+	defer rand.Read(master)
+
+	// Bitwarden only uses the expand step of HKDF
+	key = bitwardenKey{enc: make([]byte, 32), mac: make([]byte, 32)}
+	io.ReadFull(hkdf.Expand(sha256.New, master, []byte("enc")), key.enc)
+	io.ReadFull(hkdf.Expand(sha256.New, master, []byte("mac")), key.mac)
+	return
+}
+
+func (k bitwardenKey) release() {
+	rand.Read(k.enc)
+	rand.Read(k.mac)
+}
+
+// Decrypts strings in the "2.iv|ciphertext|mac" format, AES-256-CBC with HMAC-SHA256
+func (k bitwardenKey) decrypt(encString string) (plain []byte, err error) {
+	if !strings.HasPrefix(encString, bitwardenEncStringV2) {
+		err = fmt.Errorf("%w: unsupported type", ErrInvalidEncString)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(encString, bitwardenEncStringV2), "|")
+	if len(parts) != 3 {
+		err = fmt.Errorf("%w: expecting iv, data and mac", ErrInvalidEncString)
+		return
+	}
+
+	var decoded [3][]byte
+	for index, part := range parts {
+		decoded[index], err = base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidEncString, err)
+			return
+		}
+	}
+	iv, data, sum := decoded[0], decoded[1], decoded[2]
+
+	mac := hmac.New(sha256.New, k.mac)
+	mac.Write(iv)
+	mac.Write(data)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		err = ErrInvalidPassword
+		return
+	}
+
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		err = fmt.Errorf("%w: invalid block size", ErrInvalidEncString)
+		return
+	}
+	block, _ := aes.NewCipher(k.enc)
+	plain = make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		err = fmt.Errorf("%w: invalid padding", ErrInvalidEncString)
+		return
+	}
+	plain = plain[:len(plain)-padding]
+	return
+}
+
+// BitwardenJSON converts a Bitwarden JSON export, password is only called for password protected exports.
+// Data without an equivalent in the database is described in unmapped
+func BitwardenJSON(r io.Reader, password func() []byte) (entries []Entry, unmapped []string, err error) {
+	var export bitwardenExport
+	err = json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidBitwarden, err)
+		return
+	}
+
+	if export.Encrypted {
+		if !export.PasswordProtected {
+			err = ErrAccountEncrypted
+			return
+		}
+
+		secret := password()
+		defer rand.Read(secret)
+		if len(secret) == 0 {
+			err = ErrPasswordRequired
+			return
+		}
+
+		var key bitwardenKey
+		key, err = newBitwardenKey(&export, secret)
+		if err != nil {
+			return
+		}
+		defer key.release()
+
+		_, err = key.decrypt(export.Validation)
+		if err != nil {
+			err = fmt.Errorf("failed to validate key: %w", err)
+			return
+		}
+
+		var plain []byte
+		plain, err = key.decrypt(export.Data)
+		if err != nil {
+			err = fmt.Errorf("failed to decrypt data: %w", err)
+			return
+		}
+		defer rand.Read(plain)
+
+		export = bitwardenExport{}
+		err = json.Unmarshal(plain, &export)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidBitwarden, err)
+			return
+		}
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, folder := range export.Folders {
+		// Nested folders are named with slashes
+		var segments []string
+		for _, segment := range strings.Split(folder.Name, "/") {
+			segments = append(segments, cleanSegment(segment))
+		}
+		folders[folder.Id] = path.Join(segments...)
+	}
+
+	ids := uniqueIds{}
+	for _, item := range export.Items {
+		name := cleanSegment(item.Name)
+		if name == "" {
+			name = "untitled"
+		}
+		entry := Entry{
+			Id:     ids.id(path.Join(folders[item.FolderId], name)),
+			Fields: make(map[string][]byte),
+		}
+		setFields(entry.Fields, map[string]string{database.FieldNotes: item.Notes})
+
+		switch item.Type {
+		case bitwardenLogin:
+			if item.Login == nil {
+				break
+			}
+			entry.Secret = wipeable(item.Login.Password)
+			setFields(entry.Fields, map[string]string{
+				database.FieldUsername: item.Login.Username,
+				database.FieldTOTP:     item.Login.TOTP,
+			})
+			for index, uri := range item.Login.URIs {
+				name := database.FieldURL
+				if index > 0 {
+					name = fmt.Sprintf("%s-%d", database.FieldURL, index+1)
+				}
+				setFields(entry.Fields, map[string]string{name: uri.URI})
+			}
+			if len(item.Login.FIDO2) > 0 {
+				unmapped = append(unmapped, entry.Id+": passkeys")
+			}
+		case bitwardenSecureNote:
+		case bitwardenCard:
+			entry.Secret = wipeable(stringValue(item.Card["number"]))
+			for name, value := range item.Card {
+				if name != "number" {
+					setFields(entry.Fields, map[string]string{"card-" + name: stringValue(value)})
+				}
+			}
+		case bitwardenIdentity:
+			for name, value := range item.Identity {
+				setFields(entry.Fields, map[string]string{"identity-" + name: stringValue(value)})
+			}
+		case bitwardenSSHKey:
+			if item.SSHKey == nil {
+				break
+			}
+			entry.Secret = wipeable(item.SSHKey.PrivateKey)
+			setFields(entry.Fields, map[string]string{
+				"public-key":  item.SSHKey.PublicKey,
+				"fingerprint": item.SSHKey.Fingerprint,
+			})
+		default:
+			unmapped = append(unmapped, fmt.Sprintf("%s: unknown item type %d", entry.Id, item.Type))
+		}
+
+		for _, field := range item.Fields {
+			if field.Type == bitwardenFieldLinked {
+				unmapped = append(unmapped, fmt.Sprintf("%s: linked field %s", entry.Id, field.Name))
+				continue
+			}
+			setFields(entry.Fields, map[string]string{field.Name: field.Value})
+		}
+
+		for index, old := range item.PasswordHistory {
+			entry.Fields[fmt.Sprintf("%s%d/%s", database.FieldHistoryPrefix, index, database.FieldHistorySecret)] = wipeable(old.Password)
+		}
+
+		if len(item.CollectionIds) > 0 {
+			unmapped = append(unmapped, entry.Id+": organization collections")
+		}
+
+		entries = append(entries, entry)
+	}
+	return
+}
+
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
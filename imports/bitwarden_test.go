@@ -0,0 +1,187 @@
+package imports_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/internal/testsuite"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const bitwardenExport = `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work/VPN"}],
+  "items": [
+    {
+      "folderId": "f1", "type": 1, "name": "office", "notes": "rotate monthly",
+      "fields": [{"name": "PIN", "value": "1234", "type": 1}, {"name": "user", "value": null, "type": 3}],
+      "login": {
+        "uris": [{"uri": "https://vpn.example.com"}, {"uri": "https://backup.example.com"}],
+        "username": "sulcud", "password": "current", "totp": "JBSWY3DPEHPK3PXP",
+        "fido2Credentials": [{"credentialId": "id"}]
+      },
+      "passwordHistory": [{"password": "old", "lastUsedDate": "2023-01-01T00:00:00.000Z"}]
+    },
+    {"folderId": null, "type": 2, "name": "recovery", "notes": "codes", "secureNote": {"type": 0}},
+    {"folderId": null, "type": 3, "name": "visa", "card": {"cardholderName": "Sulcud", "number": "4111111111111111", "expYear": "2030", "code": null}}
+  ]
+}`
+
+var bitwardenEntries = []imports.Entry{
+	{Id: "Work/VPN/office", Secret: []byte("current"), Fields: map[string][]byte{
+		database.FieldNotes:      []byte("rotate monthly"),
+		database.FieldUsername:   []byte("sulcud"),
+		database.FieldTOTP:       []byte("JBSWY3DPEHPK3PXP"),
+		database.FieldURL:        []byte("https://vpn.example.com"),
+		database.FieldURL + "-2": []byte("https://backup.example.com"),
+		"PIN":                    []byte("1234"),
+		database.FieldHistoryPrefix + "0/" + database.FieldHistorySecret: []byte("old"),
+	}},
+	{Id: "recovery", Fields: map[string][]byte{database.FieldNotes: []byte("codes")}},
+	{Id: "visa", Secret: []byte("4111111111111111"), Fields: map[string][]byte{
+		"card-cardholderName": []byte("Sulcud"),
+		"card-expYear":        []byte("2030"),
+	}},
+}
+
+var bitwardenUnmapped = []string{"Work/VPN/office: linked field user", "Work/VPN/office: passkeys"}
+
+// Produces a password protected export the same way Bitwarden clients do
+func encryptBitwarden(t *testing.T, password string, kdfType int, plain []byte) []byte {
+	salt := base64.StdEncoding.EncodeToString(testsuite.Random(16))
+
+	var master []byte
+	switch kdfType {
+	case 0:
+		master = pbkdf2.Key([]byte(password), []byte(salt), 10, 32, sha256.New)
+	case 1:
+		hashedSalt := sha256.Sum256([]byte(salt))
+		master = argon2.IDKey([]byte(password), hashedSalt[:], 1, 1024, 1, 32)
+	}
+	encKey, macKey := make([]byte, 32), make([]byte, 32)
+	io.ReadFull(hkdf.Expand(sha256.New, master, []byte("enc")), encKey)
+	io.ReadFull(hkdf.Expand(sha256.New, master, []byte("mac")), macKey)
+
+	encrypt := func(data []byte) string {
+		padding := aes.BlockSize - len(data)%aes.BlockSize
+		data = append(bytes.Clone(data), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+		iv := testsuite.Random(aes.BlockSize)
+		block, _ := aes.NewCipher(encKey)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+		mac := hmac.New(sha256.New, macKey)
+		mac.Write(iv)
+		mac.Write(data)
+		return "2." + base64.StdEncoding.EncodeToString(iv) + "|" + base64.StdEncoding.EncodeToString(data) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	export, err := json.Marshal(map[string]any{
+		"encrypted":                    true,
+		"passwordProtected":            true,
+		"salt":                         salt,
+		"kdfType":                      kdfType,
+		"kdfIterations":                1,
+		"kdfMemory":                    1,
+		"kdfParallelism":               1,
+		"encKeyValidation_DO_NOT_EDIT": encrypt(testsuite.Random(16)),
+		"data":                         encrypt(plain),
+	})
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if kdfType == 0 {
+		// PBKDF2 iterations are fixed above, match them in the export
+		export = bytes.Replace(export, []byte(`"kdfIterations":1`), []byte(`"kdfIterations":10`), 1)
+	}
+	return export
+}
+
+func TestBitwardenJSON(t *testing.T) {
+	t.Parallel()
+
+	password := func(s string) func() []byte {
+		return func() []byte { return []byte(s) }
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name  string
+			Input []byte
+		}
+
+		tests := []Test{
+			{"Unencrypted", []byte(bitwardenExport)},
+			{"PBKDF2 password protected", encryptBitwarden(t, "password", 0, []byte(bitwardenExport))},
+			{"Argon2id password protected", encryptBitwarden(t, "password", 1, []byte(bitwardenExport))},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				entries, unmapped, err := imports.BitwardenJSON(bytes.NewReader(test.Input), password("password"))
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				if !reflect.DeepEqual(bitwardenEntries, entries) {
+					t.Fatalf("expecting %+v but received: %+v", bitwardenEntries, entries)
+				}
+
+				sort.Strings(unmapped)
+				if !reflect.DeepEqual(bitwardenUnmapped, unmapped) {
+					t.Fatalf("expecting %v but received: %v", bitwardenUnmapped, unmapped)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name     string
+			Input    []byte
+			Password string
+			Expect   error
+		}
+
+		tests := []Test{
+			{"Invalid JSON", []byte("{"), "", imports.ErrInvalidBitwarden},
+			{"Account encrypted", []byte(`{"encrypted": true, "passwordProtected": false}`), "", imports.ErrAccountEncrypted},
+			{"Missing password", encryptBitwarden(t, "password", 0, []byte(bitwardenExport)), "", imports.ErrPasswordRequired},
+			{"Wrong password", encryptBitwarden(t, "password", 0, []byte(bitwardenExport)), "invalid", imports.ErrInvalidPassword},
+			{"Unsupported KDF", []byte(`{"encrypted": true, "passwordProtected": true, "kdfType": 7}`), "password", imports.ErrUnsupportedKDF},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, _, err := imports.BitwardenJSON(strings.NewReader(string(test.Input)), password(test.Password))
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Supported CSV formats
+const (
+	FormatBitwarden = "bitwarden"
+	Format1Password = "1password"
+	FormatChrome    = "chrome"
+	FormatFirefox   = "firefox"
+	FormatGeneric   = "generic"
+)
+
+// Columns of the generic format holding the id and the secret, every other column is stored as a field
+const (
+	ColumnId     = "id"
+	ColumnSecret = "secret"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("unknown csv format")
+	ErrMissingColumn  = errors.New("missing csv column")
+	ErrInvalidColumns = errors.New("invalid columns mapping")
+)
+
+// Header of the CSV file, names are case insensitive
+type header map[string]int
+
+func (h header) get(record []string, names ...string) string {
+	for _, name := range names {
+		index, found := h[name]
+		if found && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+	}
+	return ""
+}
+
+func (h header) require(names ...string) (err error) {
+	for _, name := range names {
+		if _, found := h[name]; !found {
+			err = fmt.Errorf("%w: %s", ErrMissingColumn, name)
+			return
+		}
+	}
+	return
+}
+
+type converter func(h header, record []string) (entry Entry, err error)
+
+// ParseColumns parses a generic mapping like "id=Title,secret=Password,username=Login",
+// keys are guardian fields and values the CSV columns
+func ParseColumns(s string) (columns map[string]string, err error) {
+	columns = make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, found := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !found || field == "" || column == "" {
+			err = fmt.Errorf("%w: %s", ErrInvalidColumns, pair)
+			return
+		}
+		columns[field] = column
+	}
+	return
+}
+
+// CSV converts a CSV export, columns is only used by the generic format
+func CSV(r io.Reader, format string, columns map[string]string) (entries []Entry, err error) {
+	var convert converter
+	switch format {
+	case FormatBitwarden:
+		convert = bitwarden
+	case Format1Password:
+		convert = onePassword
+	case FormatChrome:
+		convert = chrome
+	case FormatFirefox:
+		convert = firefox
+	case FormatGeneric:
+		convert, err = generic(columns)
+		if err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+		return
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	names, err := reader.Read()
+	if err != nil {
+		err = fmt.Errorf("failed to read csv header: %w", err)
+		return
+	}
+	h := make(header, len(names))
+	for index, name := range names {
+		// Some exporters prefix the file with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		h[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+
+	ids := uniqueIds{}
+	for line := 2; ; line++ {
+		var record []string
+		record, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read csv record: %w", err)
+			return
+		}
+
+		var entry Entry
+		entry, err = convert(h, record)
+		if err != nil {
+			err = fmt.Errorf("failed to convert csv line %d: %w", line, err)
+			return
+		}
+		if entry.Id == "" {
+			entry.Id = "untitled"
+		}
+		entry.Id = ids.id(entry.Id)
+		entries = append(entries, entry)
+	}
+}
+
+// Only non empty values are stored
+func setFields(fields map[string][]byte, values map[string]string) {
+	for name, value := range values {
+		if value != "" {
+			fields[name] = []byte(value)
+		}
+	}
+}
+
+// Bitwarden: folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp
+func bitwarden(h header, record []string) (entry Entry, err error) {
+	err = h.require("name", "login_password")
+	if err != nil {
+		return
+	}
+
+	entry = Entry{
+		Id:     path.Join(cleanSegment(h.get(record, "folder")), cleanSegment(h.get(record, "name"))),
+		Secret: wipeable(h.get(record, "login_password")),
+		Fields: make(map[string][]byte),
+	}
+	setFields(entry.Fields, map[string]string{
+		database.FieldUsername: h.get(record, "login_username"),
+		database.FieldURL:      h.get(record, "login_uri"),
+		database.FieldNotes:    h.get(record, "notes"),
+		database.FieldTOTP:     h.get(record, "login_totp"),
+	})
+
+	// Custom fields are exported as "name: value" lines
+	for _, line := range strings.Split(h.get(record, "fields"), "\n") {
+		name, value, found := strings.Cut(line, ": ")
+		if found && strings.TrimSpace(name) != "" {
+			entry.Fields[strings.TrimSpace(name)] = wipeable(value)
+		}
+	}
+	return
+}
+
+// 1Password: Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes
+func onePassword(h header, record []string) (entry Entry, err error) {
+	err = h.require("title", "password")
+	if err != nil {
+		return
+	}
+
+	entry = Entry{
+		Id:     cleanSegment(h.get(record, "title")),
+		Secret: wipeable(h.get(record, "password")),
+		Fields: make(map[string][]byte),
+	}
+	setFields(entry.Fields, map[string]string{
+		database.FieldUsername: h.get(record, "username"),
+		database.FieldURL:      h.get(record, "url", "website"),
+		database.FieldNotes:    h.get(record, "notes", "notesplain"),
+		database.FieldTOTP:     h.get(record, "otpauth", "one-time password"),
+		database.FieldTags:     h.get(record, "tags"),
+	})
+	return
+}
+
+// Chrome: name,url,username,password,note
+func chrome(h header, record []string) (entry Entry, err error) {
+	err = h.require("url", "password")
+	if err != nil {
+		return
+	}
+
+	name := h.get(record, "name")
+	if name == "" {
+		name = host(h.get(record, "url"))
+	}
+	entry = Entry{
+		Id:     cleanSegment(name),
+		Secret: wipeable(h.get(record, "password")),
+		Fields: make(map[string][]byte),
+	}
+	setFields(entry.Fields, map[string]string{
+		database.FieldUsername: h.get(record, "username"),
+		database.FieldURL:      h.get(record, "url"),
+		database.FieldNotes:    h.get(record, "note"),
+	})
+	return
+}
+
+// Firefox: url,username,password,httpRealm,formActionOrigin,guid,timeCreated,timeLastUsed,timePasswordChanged
+func firefox(h header, record []string) (entry Entry, err error) {
+	err = h.require("url", "password")
+	if err != nil {
+		return
+	}
+
+	entry = Entry{
+		Id:     cleanSegment(host(h.get(record, "url"))),
+		Secret: wipeable(h.get(record, "password")),
+		Fields: make(map[string][]byte),
+	}
+	setFields(entry.Fields, map[string]string{
+		database.FieldUsername: h.get(record, "username"),
+		database.FieldURL:      h.get(record, "url"),
+	})
+	return
+}
+
+// Generic files use the id and secret columns unless mapped, other columns are stored as fields
+func generic(columns map[string]string) (convert converter, err error) {
+	mapping := map[string]string{ColumnId: ColumnId, ColumnSecret: ColumnSecret}
+	for field, column := range columns {
+		mapping[field] = strings.ToLower(column)
+	}
+
+	convert = func(h header, record []string) (entry Entry, err error) {
+		err = h.require(mapping[ColumnId], mapping[ColumnSecret])
+		if err != nil {
+			return
+		}
+
+		entry = Entry{
+			Id:     h.get(record, mapping[ColumnId]),
+			Secret: wipeable(h.get(record, mapping[ColumnSecret])),
+			Fields: make(map[string][]byte),
+		}
+
+		// Without explicit columns every extra column becomes a field
+		values := make(map[string]string)
+		if len(columns) == 0 {
+			for name := range h {
+				if name != ColumnId && name != ColumnSecret {
+					values[name] = h.get(record, name)
+				}
+			}
+		} else {
+			for field, column := range mapping {
+				if field != ColumnId && field != ColumnSecret {
+					values[field] = h.get(record, column)
+				}
+			}
+		}
+		setFields(entry.Fields, values)
+		return
+	}
+	return
+}
+
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Hostname()
+}
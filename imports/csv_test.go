@@ -0,0 +1,167 @@
+package imports_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+)
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name    string
+			Format  string
+			Columns string
+			Input   string
+			Expect  []imports.Entry
+		}
+
+		tests := []Test{
+			{
+				Name:   "Bitwarden",
+				Format: imports.FormatBitwarden,
+				Input: "\ufefffolder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+					"Work,,login,vpn,\"multi\nline\",\"PIN: 1234\nRegion: eu\",0,https://vpn.example.com,sulcud,password,JBSWY3DPEHPK3PXP\n" +
+					",,login,example.com,,,0,https://example.com,sulcud,secret,\n",
+				Expect: []imports.Entry{
+					{Id: "Work/vpn", Secret: []byte("password"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("sulcud"),
+						database.FieldURL:      []byte("https://vpn.example.com"),
+						database.FieldNotes:    []byte("multi\nline"),
+						database.FieldTOTP:     []byte("JBSWY3DPEHPK3PXP"),
+						"PIN":                  []byte("1234"),
+						"Region":               []byte("eu"),
+					}},
+					{Id: "example.com", Secret: []byte("secret"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("sulcud"),
+						database.FieldURL:      []byte("https://example.com"),
+					}},
+				},
+			},
+			{
+				Name:   "1Password",
+				Format: imports.Format1Password,
+				Input: "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+					"GitHub,https://github.com,sulcud,password,otpauth://totp/github,false,false,dev,\n",
+				Expect: []imports.Entry{
+					{Id: "GitHub", Secret: []byte("password"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("sulcud"),
+						database.FieldURL:      []byte("https://github.com"),
+						database.FieldTOTP:     []byte("otpauth://totp/github"),
+						database.FieldTags:     []byte("dev"),
+					}},
+				},
+			},
+			{
+				Name:   "Chrome duplicated names",
+				Format: imports.FormatChrome,
+				Input: "name,url,username,password,note\n" +
+					"example.com,https://example.com/login,first,one,\n" +
+					"example.com,https://example.com/login,second,two,personal\n",
+				Expect: []imports.Entry{
+					{Id: "example.com", Secret: []byte("one"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("first"),
+						database.FieldURL:      []byte("https://example.com/login"),
+					}},
+					{Id: "example.com-2", Secret: []byte("two"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("second"),
+						database.FieldURL:      []byte("https://example.com/login"),
+						database.FieldNotes:    []byte("personal"),
+					}},
+				},
+			},
+			{
+				Name:   "Firefox",
+				Format: imports.FormatFirefox,
+				Input: `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"` + "\n" +
+					`"https://accounts.example.com:8443","sulcud","password",,"https://accounts.example.com","{guid}","1","1","1"` + "\n",
+				Expect: []imports.Entry{
+					{Id: "accounts.example.com", Secret: []byte("password"), Fields: map[string][]byte{
+						database.FieldUsername: []byte("sulcud"),
+						database.FieldURL:      []byte("https://accounts.example.com:8443"),
+					}},
+				},
+			},
+			{
+				Name:   "Generic default columns",
+				Format: imports.FormatGeneric,
+				Input:  "id,secret,Username\nmail,password,sulcud\n",
+				Expect: []imports.Entry{
+					{Id: "mail", Secret: []byte("password"), Fields: map[string][]byte{"username": []byte("sulcud")}},
+				},
+			},
+			{
+				Name:    "Generic mapped columns",
+				Format:  imports.FormatGeneric,
+				Columns: "id=Title, secret=Pass, username=Login",
+				Input:   "Title,Pass,Login,Ignored\nmail,password,sulcud,value\n",
+				Expect: []imports.Entry{
+					{Id: "mail", Secret: []byte("password"), Fields: map[string][]byte{database.FieldUsername: []byte("sulcud")}},
+				},
+			},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				columns, err := imports.ParseColumns(test.Columns)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				entries, err := imports.CSV(strings.NewReader(test.Input), test.Format, columns)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				if !reflect.DeepEqual(test.Expect, entries) {
+					t.Fatalf("expecting %+v but received: %+v", test.Expect, entries)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name    string
+			Format  string
+			Columns map[string]string
+			Input   string
+			Expect  error
+		}
+
+		tests := []Test{
+			{"Unknown format", "lastpass", nil, "url,password\n", imports.ErrUnknownFormat},
+			{"Missing column", imports.FormatChrome, nil, "name,username\nexample,sulcud\n", imports.ErrMissingColumn},
+			{"Missing mapped column", imports.FormatGeneric, map[string]string{imports.ColumnId: "Title"}, "id,secret\nmail,password\n", imports.ErrMissingColumn},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := imports.CSV(strings.NewReader(test.Input), test.Format, test.Columns)
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+
+		_, err := imports.ParseColumns("id")
+		if !errors.Is(err, imports.ErrInvalidColumns) {
+			t.Fatalf("expecting %v but received: %v", imports.ErrInvalidColumns, err)
+		}
+	})
+}
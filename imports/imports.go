@@ -0,0 +1,137 @@
+// Package imports converts the exports of other password managers into guardian database entries
+package imports
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Policy decides what happens with entries whose id already exists in the database
+type Policy string
+
+const (
+	Skip      Policy = "skip"
+	Overwrite Policy = "overwrite"
+	Rename    Policy = "rename"
+)
+
+var ErrUnknownPolicy = errors.New("unknown duplicates policy")
+
+// Entry is a single secret ready to be stored in the database.
+// The secret and the field values are copies Wipe can overwrite once stored
+type Entry struct {
+	Id     string
+	Secret []byte
+	Fields map[string][]byte
+}
+
+// Wipe overwrites the secrets and the field values of the entries
+func Wipe(entries []Entry) {
+	for _, entry := range entries {
+		rand.Read(entry.Secret)
+		for _, value := range entry.Fields {
+			rand.Read(value)
+		}
+	}
+}
+
+// wipeable copies a parsed value to a slice Wipe can overwrite, empty values are nil
+func wipeable(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return []byte(s)
+}
+
+// Options controls how the entries are applied
+type Options struct {
+	Duplicates Policy
+	// Report the changes without modifying the database
+	DryRun bool
+}
+
+// Report describes the changes applied to the database
+type Report struct {
+	DryRun      bool              `json:"dry_run,omitempty"`
+	Imported    []string          `json:"imported"`
+	Skipped     []string          `json:"skipped,omitempty"`
+	Overwritten []string          `json:"overwritten,omitempty"`
+	Renamed     map[string]string `json:"renamed,omitempty"`
+	// Source data without an equivalent in the database
+	Unmapped []string `json:"unmapped,omitempty"`
+}
+
+// Apply stores the entries in the database resolving existing ids with the duplicates policy, skipping by default
+func Apply(db *database.Database, entries []Entry, options Options) (report Report, err error) {
+	switch options.Duplicates {
+	case "":
+		options.Duplicates = Skip
+	case Skip, Overwrite, Rename:
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownPolicy, options.Duplicates)
+		return
+	}
+
+	report = Report{DryRun: options.DryRun, Imported: []string{}}
+
+	// Ids used by this import, needed to rename without touching the database on dry runs
+	taken := uniqueIds{}
+	exists := func(id string) bool {
+		found, _ := db.Lookup(id)
+		_, used := taken[id]
+		return found || used
+	}
+
+	for _, entry := range entries {
+		id := entry.Id
+		if exists(id) {
+			switch options.Duplicates {
+			case Skip:
+				report.Skipped = append(report.Skipped, id)
+				continue
+			case Overwrite:
+				report.Overwritten = append(report.Overwritten, id)
+				if !options.DryRun {
+					db.Del(id)
+				}
+			case Rename:
+				for n := 2; exists(id); n++ {
+					id = fmt.Sprintf("%s-%d", entry.Id, n)
+				}
+				if report.Renamed == nil {
+					report.Renamed = make(map[string]string)
+				}
+				report.Renamed[entry.Id] = id
+			}
+		}
+		taken[id] = struct{}{}
+		report.Imported = append(report.Imported, id)
+
+		if options.DryRun {
+			continue
+		}
+		db.Set(id, string(entry.Secret))
+		for name, value := range entry.Fields {
+			db.SetField(id, name, string(value))
+		}
+	}
+	return
+}
+
+// Makes ids unique inside a single import by appending a numeric suffix
+type uniqueIds map[string]struct{}
+
+func (u uniqueIds) id(id string) string {
+	unique := id
+	for n := 2; ; n++ {
+		if _, found := u[unique]; !found {
+			break
+		}
+		unique = fmt.Sprintf("%s-%d", id, n)
+	}
+	u[unique] = struct{}{}
+	return unique
+}
@@ -0,0 +1,132 @@
+package imports_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	entries := []imports.Entry{
+		{Id: "example.com", Secret: []byte("imported"), Fields: map[string][]byte{database.FieldUsername: []byte("new")}},
+		{Id: "new.com", Secret: []byte("new"), Fields: map[string][]byte{}},
+	}
+
+	newDB := func() *database.Database {
+		db := database.New()
+		db.Set("example.com", "existing")
+		db.SetField("example.com", database.FieldURL, "https://example.com")
+		db.Set("example.com-2", "existing")
+		return db
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name    string
+			Options imports.Options
+			Report  imports.Report
+			Secrets map[string]string
+		}
+
+		tests := []Test{
+			{
+				Name:    "Skip",
+				Options: imports.Options{Duplicates: imports.Skip},
+				Report:  imports.Report{Imported: []string{"new.com"}, Skipped: []string{"example.com"}},
+				Secrets: map[string]string{"example.com": "existing", "example.com-2": "existing", "new.com": "new"},
+			},
+			{
+				Name:    "Overwrite",
+				Options: imports.Options{Duplicates: imports.Overwrite},
+				Report:  imports.Report{Imported: []string{"example.com", "new.com"}, Overwritten: []string{"example.com"}},
+				Secrets: map[string]string{"example.com": "imported", "example.com-2": "existing", "new.com": "new"},
+			},
+			{
+				Name:    "Rename",
+				Options: imports.Options{Duplicates: imports.Rename},
+				Report:  imports.Report{Imported: []string{"example.com-3", "new.com"}, Renamed: map[string]string{"example.com": "example.com-3"}},
+				Secrets: map[string]string{"example.com": "existing", "example.com-2": "existing", "example.com-3": "imported", "new.com": "new"},
+			},
+			{
+				Name:    "Dry run",
+				Options: imports.Options{Duplicates: imports.Rename, DryRun: true},
+				Report:  imports.Report{DryRun: true, Imported: []string{"example.com-3", "new.com"}, Renamed: map[string]string{"example.com": "example.com-3"}},
+				Secrets: map[string]string{"example.com": "existing", "example.com-2": "existing"},
+			},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				db := newDB()
+				report, err := imports.Apply(db, entries, test.Options)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				if !reflect.DeepEqual(test.Report, report) {
+					t.Fatalf("expecting %+v but received: %+v", test.Report, report)
+				}
+				if !reflect.DeepEqual(test.Secrets, db.Secrets) {
+					t.Fatalf("expecting %v but received: %v", test.Secrets, db.Secrets)
+				}
+			})
+		}
+
+		t.Run("Overwrite replaces fields", func(t *testing.T) {
+			t.Parallel()
+
+			db := newDB()
+			_, err := imports.Apply(db, entries, imports.Options{Duplicates: imports.Overwrite})
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			expect := map[string]string{database.FieldUsername: "new"}
+			fields, err := db.GetFields("example.com")
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if !reflect.DeepEqual(expect, fields) {
+				t.Fatalf("expecting %v but received: %v", expect, fields)
+			}
+		})
+		t.Run("Wipe keeps the stored values", func(t *testing.T) {
+			t.Parallel()
+
+			wiped := []imports.Entry{{Id: "wiped.com", Secret: []byte("secret"), Fields: map[string][]byte{database.FieldTOTP: []byte("seed")}}}
+			db := database.New()
+			_, err := imports.Apply(db, wiped, imports.Options{})
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			imports.Wipe(wiped)
+
+			if string(wiped[0].Secret) == "secret" || string(wiped[0].Fields[database.FieldTOTP]) == "seed" {
+				t.Fatal("expecting entry to be wiped")
+			}
+			secret, _ := db.Get("wiped.com")
+			totp, _ := db.GetField("wiped.com", database.FieldTOTP)
+			if secret != "secret" || totp != "seed" {
+				t.Fatalf("expecting stored values but received: %s %s", secret, totp)
+			}
+		})
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := imports.Apply(newDB(), entries, imports.Options{Duplicates: "merge"})
+		if !errors.Is(err, imports.ErrUnknownPolicy) {
+			t.Fatalf("expecting %v but received: %v", imports.ErrUnknownPolicy, err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package imports
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+// KeePassXC stores the TOTP URI under this custom string
+const kdbxOTP = "otp"
+
+var kdbxFields = map[string]string{
+	kdbx.UserName: database.FieldUsername,
+	kdbx.URL:      database.FieldURL,
+	kdbx.Notes:    database.FieldNotes,
+	kdbxOTP:       database.FieldTOTP,
+}
+
+// KDBX decodes a KeePass KDBX 4 file and converts its entries
+func KDBX(r io.Reader, key kdbx.Key) (entries []Entry, err error) {
+	db, err := kdbx.Decode(r, key)
+	if err != nil {
+		err = fmt.Errorf("failed to decode kdbx: %w", err)
+		return
+	}
+
+	entries = FromKDBX(db)
+	return
+}
+
+// FromKDBX converts the entries of the database, groups below the root are mapped to hierarchical ids
+func FromKDBX(db *kdbx.Database) (entries []Entry) {
+	if db.Root == nil {
+		return
+	}
+
+	ids := uniqueIds{}
+	var walk func(group *kdbx.Group, prefix string)
+	walk = func(group *kdbx.Group, prefix string) {
+		for _, e := range group.Entries {
+			title := e.Get(kdbx.Title)
+			if title == "" {
+				title = "untitled"
+			}
+			entry := Entry{
+				Id:     ids.id(path.Join(prefix, cleanSegment(title))),
+				Secret: wipeable(e.Get(kdbx.Password)),
+				Fields: kdbxEntryFields(e),
+			}
+
+			for index, old := range e.History {
+				historyPrefix := fmt.Sprintf("%s%d/", database.FieldHistoryPrefix, index)
+				entry.Fields[historyPrefix+database.FieldHistorySecret] = wipeable(old.Get(kdbx.Password))
+				for name, value := range kdbxEntryFields(old) {
+					entry.Fields[historyPrefix+name] = value
+				}
+			}
+			entries = append(entries, entry)
+		}
+		for _, child := range group.Groups {
+			walk(child, path.Join(prefix, cleanSegment(child.Name)))
+		}
+	}
+	walk(db.Root, "")
+
+	return
+}
+
+func kdbxEntryFields(e *kdbx.Entry) (fields map[string][]byte) {
+	fields = make(map[string][]byte)
+	for _, s := range e.Strings {
+		switch s.Key {
+		case kdbx.Title, kdbx.Password:
+			continue
+		}
+		if s.Value == "" {
+			continue
+		}
+		name, found := kdbxFields[s.Key]
+		if !found {
+			name = s.Key
+		}
+		fields[name] = []byte(s.Value)
+	}
+	if e.Tags != "" {
+		fields[database.FieldTags] = []byte(e.Tags)
+	}
+	for _, attachment := range e.Attachments {
+		fields[database.FieldAttachmentPrefix+attachment.Name] = []byte(base64.StdEncoding.EncodeToString(attachment.Data))
+	}
+	return
+}
+
+// Group and entry names may contain the id separator
+func cleanSegment(name string) string {
+	return strings.ReplaceAll(strings.TrimSpace(name), "/", "-")
+}
@@ -0,0 +1,145 @@
+package imports_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+func TestKDBX(t *testing.T) {
+	t.Parallel()
+
+	source := &kdbx.Database{
+		Root: &kdbx.Group{
+			Name: "Root",
+			Entries: []*kdbx.Entry{
+				{Strings: []kdbx.String{
+					{Key: kdbx.Title, Value: "example.com"},
+					{Key: kdbx.UserName, Value: "sulcud"},
+					{Key: kdbx.Password, Value: "password", Protected: true},
+					{Key: kdbx.URL, Value: "https://example.com"},
+				}},
+			},
+			Groups: []*kdbx.Group{
+				{
+					Name: "Work/VPN",
+					Entries: []*kdbx.Entry{
+						{
+							Tags: "infra",
+							Strings: []kdbx.String{
+								{Key: kdbx.Title, Value: "office"},
+								{Key: kdbx.Password, Value: "current", Protected: true},
+								{Key: "otp", Value: "otpauth://totp/office?secret=JBSWY3DPEHPK3PXP"},
+								{Key: "PIN", Value: "1234", Protected: true},
+							},
+							Attachments: []kdbx.Attachment{{Name: "config.ovpn", Data: []byte("remote vpn")}},
+							History: []*kdbx.Entry{
+								{Strings: []kdbx.String{
+									{Key: kdbx.Title, Value: "office"},
+									{Key: kdbx.UserName, Value: "old-user"},
+									{Key: kdbx.Password, Value: "old", Protected: true},
+								}},
+							},
+						},
+						{Strings: []kdbx.String{
+							{Key: kdbx.Title, Value: "office"},
+							{Key: kdbx.Password, Value: "duplicated", Protected: true},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	expect := []imports.Entry{
+		{
+			Id:     "example.com",
+			Secret: []byte("password"),
+			Fields: map[string][]byte{
+				database.FieldUsername: []byte("sulcud"),
+				database.FieldURL:      []byte("https://example.com"),
+			},
+		},
+		{
+			Id:     "Work-VPN/office",
+			Secret: []byte("current"),
+			Fields: map[string][]byte{
+				database.FieldTOTP: []byte("otpauth://totp/office?secret=JBSWY3DPEHPK3PXP"),
+				database.FieldTags: []byte("infra"),
+				"PIN":              []byte("1234"),
+				database.FieldAttachmentPrefix + "config.ovpn":                   []byte(base64.StdEncoding.EncodeToString([]byte("remote vpn"))),
+				database.FieldHistoryPrefix + "0/" + database.FieldHistorySecret: []byte("old"),
+				database.FieldHistoryPrefix + "0/" + database.FieldUsername:      []byte("old-user"),
+			},
+		},
+		{
+			Id:     "Work-VPN/office-2",
+			Secret: []byte("duplicated"),
+			Fields: map[string][]byte{},
+		},
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		key := kdbx.Key{Password: []byte("password")}
+		options := kdbx.Options{Cipher: kdbx.CipherChaCha20, KDF: kdbx.KDFAES, Iterations: 10, Compress: true}
+
+		var file bytes.Buffer
+		err := kdbx.Encode(&file, source, key, options)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		entries, err := imports.KDBX(&file, key)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		if !reflect.DeepEqual(expect, entries) {
+			t.Fatalf("expecting %+v but received: %+v", expect, entries)
+		}
+
+		db := database.New()
+		db.Set("example.com", "existing")
+
+		report, err := imports.Apply(db, entries, imports.Options{})
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		if !reflect.DeepEqual(report.Skipped, []string{"example.com"}) {
+			t.Fatalf("expecting example.com to be skipped, but received: %v", report.Skipped)
+		}
+		if len(report.Imported) != 2 {
+			t.Fatalf("expecting 2 imported entries, but received: %v", report.Imported)
+		}
+
+		secret, _ := db.Get("example.com")
+		if secret != "existing" {
+			t.Fatalf("expecting existing entry to be preserved, but received: %s", secret)
+		}
+		pin, err := db.GetField("Work-VPN/office", "PIN")
+		if err != nil || pin != "1234" {
+			t.Fatalf("expecting PIN field, but received: %s %v", pin, err)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		var file bytes.Buffer
+		err := kdbx.Encode(&file, source, kdbx.Key{Password: []byte("password")}, kdbx.Options{Cipher: kdbx.CipherAES256, KDF: kdbx.KDFAES, Iterations: 10})
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+
+		_, err = imports.KDBX(&file, kdbx.Key{Password: []byte("invalid")})
+		if err == nil {
+			t.Fatalf("expecting errors")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package imports
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RogueTeam/guardian/database"
+)
+
+// Extensions removed from the decrypted file names
+var passExtensions = []string{".gpg", ".txt"}
+
+// Common pass field names mapped to the database ones
+var passFields = map[string]string{
+	"user":     database.FieldUsername,
+	"username": database.FieldUsername,
+	"login":    database.FieldUsername,
+	"url":      database.FieldURL,
+	"website":  database.FieldURL,
+	"otp":      database.FieldTOTP,
+	"totp":     database.FieldTOTP,
+	"tags":     database.FieldTags,
+}
+
+// PassTree converts a tree of decrypted password-store files, the first line is the password,
+// "key: value" lines are fields and any other line is kept as notes
+func PassTree(fsys fs.FS) (entries []Entry, unmapped []string, err error) {
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip the store metadata like .git and .gpg-id
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			unmapped = append(unmapped, name+": not a regular file")
+			return nil
+		}
+
+		contents, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer rand.Read(contents)
+
+		if !utf8.Valid(contents) || bytes.IndexByte(contents, 0) >= 0 {
+			unmapped = append(unmapped, name+": binary file, still encrypted?")
+			return nil
+		}
+
+		id := name
+		for _, extension := range passExtensions {
+			id = strings.TrimSuffix(id, extension)
+		}
+		entries = append(entries, passEntry(path.Clean(id), contents))
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to walk pass tree: %w", err)
+	}
+	return
+}
+
+// passEntry copies the values out of contents, which is overwritten once converted
+func passEntry(id string, contents []byte) (entry Entry) {
+	secret, rest, _ := bytes.Cut(contents, []byte("\n"))
+	entry = Entry{
+		Id:     id,
+		Secret: bytes.Clone(bytes.TrimSuffix(secret, []byte("\r"))),
+		Fields: make(map[string][]byte),
+	}
+
+	var notes [][]byte
+	for _, line := range bytes.Split(rest, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if bytes.HasPrefix(line, []byte("otpauth://")) {
+			entry.Fields[database.FieldTOTP] = bytes.Clone(line)
+			continue
+		}
+
+		// Requiring the space keeps lines like plain URLs as notes
+		key, value, found := bytes.Cut(line, []byte(": "))
+		key = bytes.TrimSpace(key)
+		if !found || len(key) == 0 {
+			notes = append(notes, line)
+			continue
+		}
+
+		name, known := passFields[strings.ToLower(string(key))]
+		if !known {
+			name = string(key)
+		}
+		entry.Fields[name] = bytes.Clone(bytes.TrimSpace(value))
+	}
+
+	if joined := bytes.TrimSpace(bytes.Join(notes, []byte("\n"))); len(joined) > 0 {
+		entry.Fields[database.FieldNotes] = joined
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package imports_test
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/imports"
+)
+
+func TestPassTree(t *testing.T) {
+	t.Parallel()
+
+	store := fstest.MapFS{
+		".gpg-id":         {Data: []byte("ABCDEF\n")},
+		".git/config":     {Data: []byte("[core]\n")},
+		"email/work.gpg":  {Data: []byte("password\nlogin: sulcud\nurl: https://mail.example.com\notpauth://totp/mail?secret=JBSWY3DPEHPK3PXP\n")},
+		"email/personal":  {Data: []byte("secret\r\nRecovery code: 1234\r\nhttps://example.com\r\nsecond note\r\n")},
+		"example.com":     {Data: []byte("only-password")},
+		"still/encrypted": {Data: []byte{0x85, 0x02, 0x0c, 0x00, 0xff}},
+	}
+
+	expect := []imports.Entry{
+		{Id: "email/personal", Secret: []byte("secret"), Fields: map[string][]byte{
+			"Recovery code":     []byte("1234"),
+			database.FieldNotes: []byte("https://example.com\nsecond note"),
+		}},
+		{Id: "email/work", Secret: []byte("password"), Fields: map[string][]byte{
+			database.FieldUsername: []byte("sulcud"),
+			database.FieldURL:      []byte("https://mail.example.com"),
+			database.FieldTOTP:     []byte("otpauth://totp/mail?secret=JBSWY3DPEHPK3PXP"),
+		}},
+		{Id: "example.com", Secret: []byte("only-password"), Fields: map[string][]byte{}},
+	}
+
+	entries, unmapped, err := imports.PassTree(store)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	if !reflect.DeepEqual(expect, entries) {
+		t.Fatalf("expecting %+v but received: %+v", expect, entries)
+	}
+
+	if len(unmapped) != 1 || unmapped[0] != "still/encrypted: binary file, still encrypted?" {
+		t.Fatalf("expecting encrypted file to be unmapped, but received: %v", unmapped)
+	}
+}
@@ -3,32 +3,60 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"os"
+	"strings"
 )
 
 type Type uint8
 
 const (
-	TypeString = iota
+	TypeString Type = iota
 	TypeBool
 	TypeInt
+	// time.Duration like 1m30s
+	TypeDuration
+	// float64
+	TypeFloat
+	// []string, flags are repeatable and sources separate the values with commas
+	TypeStrings
+	// String restricted to the Choices of the value
+	TypeEnum
+	// Path of an existing file or directory, stored as a string
+	TypePath
 )
 
 type (
 	Setup    func(ctx *Context, flags map[string]any) (err error)
 	Callback func(ctx *Context, flags map[string]any, args map[string]any) (result any, err error)
 	Defer    func(ctx *Context, result any) (finalResult any, err error)
-	Value    struct {
-		Type        Type
-		Name        string
+	// Source returns the value of a flag configured outside the command line
+	Source func(name string) (value string, found bool)
+	Value  struct {
+		Type Type
+		Name string
+		// Single letter alias of flags, combined with other booleans like -abc
+		Short       rune
 		Description string
 
 		// Reserved for flags
 		Default any
+		// Flags that must be set in the command line or a source
+		Required bool
+
+		// Reserved for arguments, only the trailing ones can be optional
+		Optional bool
+		// The last argument collects zero or more of the remaining ones in a slice of its type
+		Variadic bool
+
+		// Allowed values of TypeEnum
+		Choices []string
+		// Validate is called with every parsed value
+		Validate func(value any) (err error)
 	}
 	Values  []Value
 	Context struct {
 		entries map[any]any
+		sources []Source
 	}
 	Command struct {
 		Name        string
@@ -63,74 +91,123 @@ func (ctx *Context) MustGet(key any) (value any) {
 	return
 }
 
+// AddSource adds a source of flag values, the ones added first take precedence.
+// Flags in the command line override every source and sources override the defaults.
+// Sources added by a Setup apply to the flags of the subcommands
+func (ctx *Context) AddSource(source Source) {
+	ctx.sources = append(ctx.sources, source)
+}
+
+// EnvSource reads the flags from environment variables, -argon-time is PREFIX_ARGON_TIME
+func EnvSource(prefix string) Source {
+	return func(name string) (value string, found bool) {
+		return os.LookupEnv(prefix + "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+	}
+}
+
+// MapSource reads the flags from the map
+func MapSource(values map[string]string) Source {
+	return func(name string) (value string, found bool) {
+		value, found = values[name]
+		return
+	}
+}
+
+// defaults initializes the flags with the sources or their defaults
+func (ctx *Context) defaults(flags map[string]Value, values map[string]any) (err error) {
+	for _, flag := range flags {
+		var (
+			value string
+			found bool
+		)
+		for _, source := range ctx.sources {
+			value, found = source(flag.Name)
+			if found {
+				break
+			}
+		}
+		if !found {
+			if flag.Default != nil {
+				values[flag.Name] = flag.Default
+			}
+			continue
+		}
+
+		// Sources can't repeat a flag, their values are separated with commas
+		parts := []string{value}
+		if flag.Type == TypeStrings {
+			parts = strings.Split(value, ",")
+		}
+		var parsed any
+		for _, part := range parts {
+			parsed, err = parseValue(flag, "flag -"+flag.Name, part)
+			if err != nil {
+				break
+			}
+			if flag.Type == TypeStrings {
+				parsed = appendValue(values[flag.Name], parsed)
+			}
+			values[flag.Name] = parsed
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidSourceValue, err)
+			return
+		}
+	}
+	return
+}
+
 var (
 	ErrUnknownType            = errors.New("unknown type")
 	ErrInvalidNumberOfArgs    = errors.New("invalid number of arguments")
 	ErrArgsNotAllowedInParent = errors.New("arguments not allowed in parent")
 	ErrFlagNotFound           = errors.New("flag not found")
 	ErrIncompleteFlag         = errors.New("incomplete flag")
+	ErrInvalidSourceValue     = errors.New("invalid value configured outside the command line")
+	ErrRequiredFlag           = errors.New("required flag not set")
+	ErrMissingArgument        = errors.New("missing argument")
+	ErrInvalidChoice          = errors.New("invalid choice")
 )
 
 func (c *Command) Run(args []string) (result any, err error) {
-	ctx := NewContext()
+	return c.RunContext(NewContext(), args)
+}
 
+// RunContext runs the command with a context prepared by the caller, usually with sources
+func (c *Command) RunContext(ctx *Context, args []string) (result any, err error) {
 	curr := c.tree()
 
 	ctxArgs := make(map[string]any, len(args))
 	ctxFlags := make(map[string]any, len(args))
+	// Flags set in the command line, repeated ones append to them instead of the defaults
+	explicit := make(map[string]bool, len(args))
 	defers := make([]Defer, 0, len(args))
 
 	// Initialize defaults
-	for _, flag := range curr.Flags {
-		if flag.Default != nil {
-			ctxFlags[flag.Name] = flag.Default
-		}
+	err = ctx.defaults(curr.Flags, ctxFlags)
+	if err != nil {
+		return
 	}
 
+	// Every argument after -- is positional
+	var terminated bool
 	for index := 0; index < len(args); {
 
 		arg := args[index]
 		index++
 
-		switch arg[0] {
-		case '-': // Is flag
-			flag := arg[1:]
-			fDef, found := curr.Flags[flag]
-			if !found {
-				err = fmt.Errorf("%w: -%s", ErrFlagNotFound, flag)
-				return
-			}
-			switch fDef.Type {
-			case TypeString:
-				if index >= len(args) {
-					err = fmt.Errorf("%w: expecting value for -%s", ErrIncompleteFlag, flag)
-					return
-				}
-				ctxFlags[flag] = args[index]
-				index++
-			case TypeBool:
-				ctxFlags[flag] = true
-			case TypeInt:
-				if index >= len(args) {
-					err = fmt.Errorf("%w: expecting value for -%s", ErrIncompleteFlag, flag)
-					return
-				}
-				var i int
-				i, err = strconv.Atoi(args[index])
-				index++
-				if err != nil {
-					err = fmt.Errorf("failed to parse integer for flag -%s: %w", flag, err)
-					return
-				}
-				ctxFlags[flag] = i
-			default:
-				err = fmt.Errorf("%w: %s", ErrUnknownType, fDef.Name)
+		switch {
+		case !terminated && arg == Terminator:
+			terminated = true
+		case !terminated && isFlag(arg): // Is flag
+			index, err = curr.parseFlag(arg, args, index, ctxFlags, explicit)
+			if err != nil {
 				return
 			}
 		default: // Can be a command or subcommand
 			// Check if it is a command
 			sub, found := curr.SubCommands[arg]
-			if found { // Is subcommand
+			if found && !terminated { // Is subcommand
 
 				// If it is help message return inmediatly
 				if sub.Name == HelpCommand && sub.Callback != nil {
@@ -143,6 +220,11 @@ func (c *Command) Run(args []string) (result any, err error) {
 					return
 				}
 
+				err = curr.required(ctxFlags)
+				if err != nil {
+					return
+				}
+
 				// Setup parent
 				if curr.Setup != nil {
 					err = curr.Setup(ctx, ctxFlags)
@@ -160,44 +242,34 @@ func (c *Command) Run(args []string) (result any, err error) {
 				// Clear ctx
 				clear(ctxArgs)
 				clear(ctxFlags)
+				clear(explicit)
 
 				// Make new current
 				curr = sub
 
 				// Initialize defaults
-				for _, flag := range curr.Flags {
-					if flag.Default != nil {
-						ctxFlags[flag.Name] = flag.Default
-					}
-				}
-			} else { // Is argument
-				argIdx := len(ctxArgs)
-				if argIdx >= len(curr.Args) {
-					err = fmt.Errorf("%w: %s: expecting %d: %s", ErrInvalidNumberOfArgs, curr.Name, len(curr.Args), arg)
+				err = ctx.defaults(curr.Flags, ctxFlags)
+				if err != nil {
 					return
 				}
-				argEntry := curr.Args[argIdx]
-				switch argEntry.Type {
-				case TypeString:
-					ctxArgs[argEntry.Name] = arg
-				case TypeBool:
-					ctxArgs[argEntry.Name] = arg == "true"
-				case TypeInt:
-					var i int
-					i, err = strconv.Atoi(arg)
-					if err != nil {
-						err = fmt.Errorf("failed to parse integer for argument %s: %w", argEntry.Name, err)
-						return
-					}
-					ctxArgs[argEntry.Name] = i
-				default:
-					err = fmt.Errorf("%w: %s", ErrUnknownType, argEntry.Name)
+			} else { // Is argument
+				err = curr.parseArg(arg, ctxArgs)
+				if err != nil {
 					return
 				}
 			}
 		}
 	}
 
+	// Check the last command, the flags of the parents were checked before their setup
+	err = curr.required(ctxFlags)
+	if err == nil {
+		err = curr.missing(ctxArgs)
+	}
+	if err != nil {
+		return
+	}
+
 	if curr.Setup != nil {
 		err = curr.Setup(ctx, ctxFlags)
 		if err != nil {
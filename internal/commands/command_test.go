@@ -3,8 +3,11 @@ package commands_test
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/RogueTeam/guardian/internal/commands"
 )
@@ -26,8 +29,8 @@ func TestCommand_Run(t *testing.T) {
 			{
 				Name: "Help message",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeString, "string", "description", "this is a default value"}},
-					Args:  commands.Values{{commands.TypeString, "string", "description", nil}},
+					Flags: commands.Values{{Type: commands.TypeString, Name: "string", Description: "description", Default: "this is a default value"}},
+					Args:  commands.Values{{Type: commands.TypeString, Name: "string", Description: "description"}},
 				},
 				Args:   []string{"help"},
 				Expect: "Usage:",
@@ -35,7 +38,7 @@ func TestCommand_Run(t *testing.T) {
 			{
 				Name: "Simple command with flags in root and arguments in subcommand",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeString, "file", "", nil}},
+					Flags: commands.Values{{Type: commands.TypeString, Name: "file"}},
 					Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
 						ctx.Set("file", flags["file"])
 
@@ -44,8 +47,8 @@ func TestCommand_Run(t *testing.T) {
 					SubCommands: commands.Commands{
 						{
 							Name:  "init",
-							Flags: commands.Values{{commands.TypeBool, "with-db", "", nil}},
-							Args:  commands.Values{{commands.TypeString, "user", "", nil}},
+							Flags: commands.Values{{Type: commands.TypeBool, Name: "with-db"}},
+							Args:  commands.Values{{Type: commands.TypeString, Name: "user"}},
 							Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 								result = fmt.Sprintf("%s && %v && %s", ctx.MustGet("file"), flags["with-db"], args["user"])
 
@@ -63,7 +66,7 @@ func TestCommand_Run(t *testing.T) {
 					SubCommands: commands.Commands{
 						{
 							Name:  "init",
-							Flags: commands.Values{{commands.TypeString, "string", "description", "this is a default value"}},
+							Flags: commands.Values{{Type: commands.TypeString, Name: "string", Description: "description", Default: "this is a default value"}},
 							Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 								result = "init"
 								return
@@ -94,9 +97,9 @@ func TestCommand_Run(t *testing.T) {
 				Name: "All Flags types",
 				Root: commands.Command{
 					Flags: commands.Values{
-						{commands.TypeString, "string", "", nil},
-						{commands.TypeBool, "bool", "", nil},
-						{commands.TypeInt, "int", "", nil},
+						{Type: commands.TypeString, Name: "string"},
+						{Type: commands.TypeBool, Name: "bool"},
+						{Type: commands.TypeInt, Name: "int"},
 					},
 					Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 						result = fmt.Sprintf("%s && %v && %d", flags["string"], flags["bool"], flags["int"])
@@ -111,9 +114,9 @@ func TestCommand_Run(t *testing.T) {
 				Name: "All Args types",
 				Root: commands.Command{
 					Args: commands.Values{
-						{commands.TypeString, "string", "", nil},
-						{commands.TypeBool, "bool", "", nil},
-						{commands.TypeInt, "int", "", nil},
+						{Type: commands.TypeString, Name: "string"},
+						{Type: commands.TypeBool, Name: "bool"},
+						{Type: commands.TypeInt, Name: "int"},
 					},
 					Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
 						result = fmt.Sprintf("%s && %v && %d", args["string"], args["bool"], args["int"])
@@ -127,8 +130,8 @@ func TestCommand_Run(t *testing.T) {
 			{
 				Name: "Defer functions",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeString, "string", "description", "this is a default value"}},
-					Args:  commands.Values{{commands.TypeString, "string", "description", "this is a default value"}},
+					Flags: commands.Values{{Type: commands.TypeString, Name: "string", Description: "description", Default: "this is a default value"}},
+					Args:  commands.Values{{Type: commands.TypeString, Name: "string", Description: "description", Default: "this is a default value"}},
 					Defer: func(ctx *commands.Context, result any) (finalResult any, err error) {
 						result = result.(string) + " last"
 						return result, err
@@ -193,36 +196,36 @@ func TestCommand_Run(t *testing.T) {
 			{
 				Name: "Incomplete String flag",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeString, "string", "", nil}},
+					Flags: commands.Values{{Type: commands.TypeString, Name: "string"}},
 				},
 				Args: []string{"-string"},
 			},
 			{
 				Name: "Incomplete Int flag",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeInt, "int", "", nil}},
+					Flags: commands.Values{{Type: commands.TypeInt, Name: "int"}},
 				},
 				Args: []string{"-int"},
 			},
 			{
 				Name: "Invalid Int flag",
 				Root: commands.Command{
-					Flags: commands.Values{{commands.TypeInt, "int", "", nil}},
+					Flags: commands.Values{{Type: commands.TypeInt, Name: "int"}},
 				},
 				Args: []string{"-int", "sulcud"},
 			},
 			{
 				Name: "Invalid Type flag",
 				Root: commands.Command{
-					Flags: commands.Values{{0xff, "invalid", "", nil}},
+					Flags: commands.Values{{Type: 0xff, Name: "invalid"}},
 				},
 				Args: []string{"-invalid", "sulcud"},
 			},
 			{
 				Name: "Subcommand with parent args",
 				Root: commands.Command{
-					Args:  commands.Values{{commands.TypeString, "user", "", nil}},
-					Flags: commands.Values{{0xff, "invalid", "", nil}},
+					Args:  commands.Values{{Type: commands.TypeString, Name: "user"}},
+					Flags: commands.Values{{Type: 0xff, Name: "invalid"}},
 					SubCommands: commands.Commands{
 						{
 							Name: "init",
@@ -262,14 +265,14 @@ func TestCommand_Run(t *testing.T) {
 			{
 				Name: "Invalid Int arg",
 				Root: commands.Command{
-					Args: commands.Values{{commands.TypeInt, "int", "", nil}},
+					Args: commands.Values{{Type: commands.TypeInt, Name: "int"}},
 				},
 				Args: []string{"sulcud"},
 			},
 			{
 				Name: "Invalid Arg Type",
 				Root: commands.Command{
-					Args: commands.Values{{commands.Type(0xff), "int", "", nil}},
+					Args: commands.Values{{Type: commands.Type(0xff), Name: "int"}},
 				},
 				Args: []string{"sulcud"},
 			},
@@ -337,3 +340,342 @@ func TestCommand_Run(t *testing.T) {
 		}
 	})
 }
+
+// Not parallel, t.Setenv changes the environment of the whole process
+func TestCommand_RunContext(t *testing.T) {
+	t.Setenv("GUARDIAN_COMMANDS_TEST_STRING", "env")
+	t.Setenv("GUARDIAN_COMMANDS_INVALID_INT", "sulcud")
+
+	// Setup of the root adds the profile after the environment
+	root := func() commands.Command {
+		return commands.Command{
+			Name:  "root",
+			Flags: commands.Values{{Type: commands.TypeString, Name: "profile"}},
+			Setup: func(ctx *commands.Context, flags map[string]any) (err error) {
+				if profile, found := flags["profile"]; found {
+					ctx.AddSource(commands.MapSource(map[string]string{"string": profile.(string), "bool": "true", "int": "20"}))
+				}
+				return
+			},
+			SubCommands: commands.Commands{
+				{
+					Name: "get",
+					Flags: commands.Values{
+						{Type: commands.TypeString, Name: "string", Default: "default"},
+						{Type: commands.TypeBool, Name: "bool", Default: false},
+						{Type: commands.TypeInt, Name: "int", Default: 10},
+					},
+					Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+						result = fmt.Sprintf("%s && %v && %d", flags["string"], flags["bool"], flags["int"])
+						return
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		type Test struct {
+			Name    string
+			Sources []commands.Source
+			Args    []string
+			Expect  string
+		}
+		tests := []Test{
+			{Name: "Defaults", Args: []string{"get"}, Expect: "default && false && 10"},
+			{Name: "Profile", Args: []string{"-profile", "profile", "get"}, Expect: "profile && true && 20"},
+			{
+				Name:    "Environment over profile",
+				Sources: []commands.Source{commands.EnvSource("GUARDIAN_COMMANDS_TEST")},
+				Args:    []string{"-profile", "profile", "get"},
+				Expect:  "env && true && 20",
+			},
+			{
+				Name:    "Command line over everything",
+				Sources: []commands.Source{commands.EnvSource("GUARDIAN_COMMANDS_TEST")},
+				Args:    []string{"-profile", "profile", "get", "-string", "flag", "-int", "30"},
+				Expect:  "flag && true && 30",
+			},
+			{
+				Name: "First source wins",
+				Sources: []commands.Source{
+					commands.MapSource(map[string]string{"string": "first"}),
+					commands.MapSource(map[string]string{"string": "second", "int": "40"}),
+				},
+				Args:   []string{"get"},
+				Expect: "first && false && 40",
+			},
+			{
+				Name:    "Sources of parent flags",
+				Sources: []commands.Source{commands.MapSource(map[string]string{"profile": "mapped"})},
+				Args:    []string{"get"},
+				Expect:  "mapped && true && 20",
+			},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				ctx := commands.NewContext()
+				for _, source := range test.Sources {
+					ctx.AddSource(source)
+				}
+				root := root()
+				result, err := root.RunContext(ctx, test.Args)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if result != test.Expect {
+					t.Fatalf("expecting %v but received: %v", test.Expect, result)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		ctx := commands.NewContext()
+		ctx.AddSource(commands.EnvSource("GUARDIAN_COMMANDS_INVALID"))
+		root := root()
+		_, err := root.RunContext(ctx, []string{"get"})
+		if !errors.Is(err, commands.ErrInvalidSourceValue) {
+			t.Fatalf("expecting %v but received: %v", commands.ErrInvalidSourceValue, err)
+		}
+	})
+}
+
+func TestCommand_Flags(t *testing.T) {
+	t.Parallel()
+
+	root := commands.Command{
+		Name: "root",
+		Flags: commands.Values{
+			{Type: commands.TypeString, Name: "string", Short: 's', Default: ""},
+			{Type: commands.TypeBool, Name: "all", Short: 'a', Default: false},
+			{Type: commands.TypeBool, Name: "yes", Short: 'y', Default: false},
+			{Type: commands.TypeInt, Name: "int", Short: 'n', Default: 0},
+		},
+		Args: commands.Values{
+			{Type: commands.TypeString, Name: "value", Optional: true},
+			{Type: commands.TypeInt, Name: "number", Optional: true},
+		},
+		Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+			result = fmt.Sprintf("%s %v %v %d %v %v", flags["string"], flags["all"], flags["yes"], flags["int"], args["value"], args["number"])
+			return
+		},
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Args   []string
+			Expect string
+		}
+		tests := []Test{
+			{Name: "Single dash", Args: []string{"-string", "v", "-all", "-int", "1"}, Expect: `v true false 1 <nil> <nil>`},
+			{Name: "Double dash", Args: []string{"--string", "v", "--all", "--int", "1"}, Expect: `v true false 1 <nil> <nil>`},
+			{Name: "Inlined values", Args: []string{"--string=a=b", "-int=2", "--all=false", "--yes=true"}, Expect: `a=b false true 2 <nil> <nil>`},
+			{Name: "Empty inlined value", Args: []string{"--string=", "-s", "x", "--string="}, Expect: ` false false 0 <nil> <nil>`},
+			{Name: "Shorts", Args: []string{"-s", "v", "-a", "-n", "3"}, Expect: `v true false 3 <nil> <nil>`},
+			{Name: "Combined shorts", Args: []string{"-ay"}, Expect: ` true true 0 <nil> <nil>`},
+			{Name: "Combined shorts with value", Args: []string{"-ays", "v"}, Expect: `v true true 0 <nil> <nil>`},
+			{Name: "Short with inlined value", Args: []string{"-ayn4", "-s=v"}, Expect: `v true true 4 <nil> <nil>`},
+			{Name: "Negative number values", Args: []string{"-n", "-5", "-s", "-abc"}, Expect: `-abc false false -5 <nil> <nil>`},
+			{Name: "Negative number argument", Args: []string{"value", "-10"}, Expect: ` false false 0 value -10`},
+			{Name: "Terminator", Args: []string{"-a", "--", "-abc", "-5"}, Expect: ` true false 0 -abc -5`},
+			{Name: "Terminator as argument", Args: []string{"--", "--"}, Expect: ` false false 0 -- <nil>`},
+			{Name: "Empty argument", Args: []string{""}, Expect: ` false false 0  <nil>`},
+			{Name: "Dash argument", Args: []string{"-"}, Expect: ` false false 0 - <nil>`},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				root := root
+				result, err := root.Run(test.Args)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if result != test.Expect {
+					t.Fatalf("expecting %v but received: %v", test.Expect, result)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Args   []string
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Unknown long flag", Args: []string{"--unknown"}, Expect: commands.ErrFlagNotFound},
+			{Name: "Unknown short flag", Args: []string{"-ax"}, Expect: commands.ErrFlagNotFound},
+			{Name: "Long flag only with double dash", Args: []string{"--a"}, Expect: commands.ErrFlagNotFound},
+			{Name: "Incomplete long flag", Args: []string{"--string"}, Expect: commands.ErrIncompleteFlag},
+			{Name: "Incomplete short flag", Args: []string{"-as"}, Expect: commands.ErrIncompleteFlag},
+			{Name: "Too many arguments", Args: []string{"--", "a", "1", "-c"}, Expect: commands.ErrInvalidNumberOfArgs},
+			{Name: "Invalid boolean", Args: []string{"--all=maybe"}},
+			{Name: "Invalid integer", Args: []string{"-n", "one"}},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				root := root
+				_, err := root.Run(test.Args)
+				if err == nil {
+					t.Fatal("expecting error")
+				}
+				if test.Expect != nil && !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
+
+func TestCommand_Types(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	errOdd := errors.New("odd number")
+
+	root := commands.Command{
+		Name: "root",
+		Flags: commands.Values{
+			{Type: commands.TypeDuration, Name: "timeout", Default: time.Second},
+			{Type: commands.TypeFloat, Name: "ratio", Default: 0.5},
+			{Type: commands.TypeStrings, Name: "tag", Short: 't', Default: []string{"default"}},
+			{Type: commands.TypeEnum, Name: "format", Choices: []string{"json", "csv"}, Default: "json"},
+			{Type: commands.TypePath, Name: "dir", Default: ""},
+			{Type: commands.TypeString, Name: "name", Required: true},
+			{
+				Type:    commands.TypeInt,
+				Name:    "even",
+				Default: 0,
+				Validate: func(value any) (err error) {
+					if value.(int)%2 != 0 {
+						err = errOdd
+					}
+					return
+				},
+			},
+		},
+		Args: commands.Values{
+			{Type: commands.TypeString, Name: "first"},
+			{Type: commands.TypeInt, Name: "rest", Variadic: true},
+		},
+		Callback: func(ctx *commands.Context, flags, args map[string]any) (result any, err error) {
+			result = fmt.Sprintf("%v %v %v %v %v %v %v %v %v",
+				flags["timeout"], flags["ratio"], flags["tag"], flags["format"], flags["dir"], flags["name"], flags["even"], args["first"], args["rest"])
+			return
+		},
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Args   []string
+			Expect string
+		}
+		tests := []Test{
+			{Name: "Defaults", Args: []string{"-name", "n", "a"}, Expect: `1s 0.5 [default] json  n 0 a <nil>`},
+			{Name: "Duration and float", Args: []string{"-name", "n", "-timeout", "1m30s", "-ratio", "0.25", "a"}, Expect: `1m30s 0.25 [default] json  n 0 a <nil>`},
+			{Name: "Repeated strings", Args: []string{"-name", "n", "-tag", "x", "-t", "y", "--tag=z", "a"}, Expect: `1s 0.5 [x y z] json  n 0 a <nil>`},
+			{Name: "Enum", Args: []string{"-name", "n", "-format", "csv", "a"}, Expect: `1s 0.5 [default] csv  n 0 a <nil>`},
+			{Name: "Path", Args: []string{"-name", "n", "-dir", dir, "a"}, Expect: fmt.Sprintf(`1s 0.5 [default] json %s n 0 a <nil>`, dir)},
+			{Name: "Validated", Args: []string{"-name", "n", "-even", "4", "a"}, Expect: `1s 0.5 [default] json  n 4 a <nil>`},
+			{Name: "Variadic", Args: []string{"-name", "n", "a", "1", "2", "-3"}, Expect: `1s 0.5 [default] json  n 0 a [1 2 -3]`},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				root := root
+				result, err := root.Run(test.Args)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if result != test.Expect {
+					t.Fatalf("expecting %v but received: %v", test.Expect, result)
+				}
+			})
+		}
+	})
+	t.Run("Sources", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := commands.NewContext()
+		ctx.AddSource(commands.MapSource(map[string]string{"name": "n", "tag": "x,y"}))
+		root := root
+		result, err := root.RunContext(ctx, []string{"a", "1"})
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		expect := `1s 0.5 [x y] json  n 0 a [1]`
+		if result != expect {
+			t.Fatalf("expecting %v but received: %v", expect, result)
+		}
+	})
+	t.Run("Help", func(t *testing.T) {
+		t.Parallel()
+
+		table := root.Flags.Table("-")
+		for _, expect := range []string{
+			"-timeout : [duration, default 1s]",
+			"-t, -tag : [strings, default [default], repeatable]",
+			"-format  : [enum, default json, one of json, csv]",
+			"-name    : [string, required]",
+		} {
+			if !strings.Contains(table, expect) {
+				t.Fatalf("expecting %q in:\n%s", expect, table)
+			}
+		}
+		table = root.Args.Table("")
+		expect := "rest  : [int, variadic]"
+		if !strings.Contains(table, expect) {
+			t.Fatalf("expecting %q in:\n%s", expect, table)
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Args   []string
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Required flag", Args: []string{"a"}, Expect: commands.ErrRequiredFlag},
+			{Name: "Missing argument", Args: []string{"-name", "n"}, Expect: commands.ErrMissingArgument},
+			{Name: "Invalid choice", Args: []string{"-name", "n", "-format", "xml", "a"}, Expect: commands.ErrInvalidChoice},
+			{Name: "Missing path", Args: []string{"-name", "n", "-dir", filepath.Join(dir, "missing"), "a"}, Expect: os.ErrNotExist},
+			{Name: "Validation", Args: []string{"-name", "n", "-even", "3", "a"}, Expect: errOdd},
+			{Name: "Invalid duration", Args: []string{"-name", "n", "-timeout", "soon", "a"}},
+			{Name: "Invalid float", Args: []string{"-name", "n", "-ratio", "half", "a"}},
+			{Name: "Invalid variadic", Args: []string{"-name", "n", "a", "1", "two"}},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				root := root
+				_, err := root.Run(test.Args)
+				if err == nil {
+					t.Fatal("expecting error")
+				}
+				if test.Expect != nil && !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
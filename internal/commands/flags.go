@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Terminator ends the flags, the arguments after it are positional even when they start with '-'
+const Terminator = "--"
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeDuration:
+		return "duration"
+	case TypeFloat:
+		return "float"
+	case TypeStrings:
+		return "strings"
+	case TypeEnum:
+		return "enum"
+	case TypePath:
+		return "path"
+	}
+	return fmt.Sprintf("Type(%d)", uint8(t))
+}
+
+// isFlag reports whether the argument is a flag, "-" and negative numbers are positional
+func isFlag(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+// parseValue converts a single value to the type of v and validates it, label names v in the errors.
+// TypeStrings values are returned as a slice of one element
+func parseValue(v Value, label, value string) (parsed any, err error) {
+	switch v.Type {
+	case TypeString:
+		parsed = value
+	case TypeBool:
+		parsed, err = strconv.ParseBool(value)
+		if err != nil {
+			err = fmt.Errorf("failed to parse boolean for %s: %w", label, err)
+		}
+	case TypeInt:
+		parsed, err = strconv.Atoi(value)
+		if err != nil {
+			err = fmt.Errorf("failed to parse integer for %s: %w", label, err)
+		}
+	case TypeDuration:
+		parsed, err = time.ParseDuration(value)
+		if err != nil {
+			err = fmt.Errorf("failed to parse duration for %s: %w", label, err)
+		}
+	case TypeFloat:
+		parsed, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			err = fmt.Errorf("failed to parse float for %s: %w", label, err)
+		}
+	case TypeStrings:
+		parsed = []string{value}
+	case TypeEnum:
+		parsed = value
+		if !slices.Contains(v.Choices, value) {
+			err = fmt.Errorf("%w for %s: %s, expecting one of %s", ErrInvalidChoice, label, value, strings.Join(v.Choices, ", "))
+		}
+	case TypePath:
+		parsed = value
+		_, err = os.Stat(value)
+		if err != nil {
+			err = fmt.Errorf("invalid path for %s: %w", label, err)
+		}
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownType, v.Name)
+	}
+	if err != nil {
+		return
+	}
+
+	if v.Validate != nil {
+		err = v.Validate(parsed)
+		if err != nil {
+			err = fmt.Errorf("invalid value for %s: %w", label, err)
+		}
+	}
+	return
+}
+
+// appendValue appends the parsed value to the slice of its type, nil slices are created
+func appendValue(slice, value any) any {
+	switch value := value.(type) {
+	case string:
+		values, _ := slice.([]string)
+		return append(values, value)
+	case []string:
+		values, _ := slice.([]string)
+		return append(values, value...)
+	case bool:
+		values, _ := slice.([]bool)
+		return append(values, value)
+	case int:
+		values, _ := slice.([]int)
+		return append(values, value)
+	case float64:
+		values, _ := slice.([]float64)
+		return append(values, value)
+	case time.Duration:
+		values, _ := slice.([]time.Duration)
+		return append(values, value)
+	}
+	values, _ := slice.([]any)
+	return append(values, value)
+}
+
+// setFlag stores the parsed value, repeated TypeStrings flags collect all their values
+func setFlag(flag Value, parsed any, values map[string]any, explicit map[string]bool) {
+	if flag.Type == TypeStrings && explicit[flag.Name] {
+		parsed = appendValue(values[flag.Name], parsed)
+	}
+	values[flag.Name] = parsed
+	explicit[flag.Name] = true
+}
+
+// parseFlag parses -name, --name, -name=value and --name=value, falling back to the short
+// aliases for single dash flags. The value is read from args[index] when it isn't inlined,
+// next is the index of the following argument
+func (n *node) parseFlag(arg string, args []string, index int, values map[string]any, explicit map[string]bool) (next int, err error) {
+	next = index
+	long := strings.HasPrefix(arg, "--")
+	name, value, inlined := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+
+	flag, found := n.Flags[name]
+	if !found && !long {
+		return n.parseShorts(arg[1:], args, index, values, explicit)
+	}
+	if !found {
+		err = fmt.Errorf("%w: %s", ErrFlagNotFound, arg)
+		return
+	}
+
+	switch {
+	case inlined:
+	case flag.Type == TypeBool:
+		value = "true"
+	case next < len(args):
+		value = args[next]
+		next++
+	default:
+		err = fmt.Errorf("%w: expecting value for -%s", ErrIncompleteFlag, name)
+		return
+	}
+	parsed, err := parseValue(flag, "flag -"+flag.Name, value)
+	if err != nil {
+		return
+	}
+	setFlag(flag, parsed, values, explicit)
+	return
+}
+
+// parseShorts parses combined short aliases like -abc. Booleans are set until a flag with a value,
+// which takes the rest of the argument or the next one
+func (n *node) parseShorts(shorts string, args []string, index int, values map[string]any, explicit map[string]bool) (next int, err error) {
+	next = index
+	for position, short := range shorts {
+		flag, found := n.Shorts[short]
+		if !found {
+			err = fmt.Errorf("%w: -%c", ErrFlagNotFound, short)
+			return
+		}
+		if flag.Type == TypeBool {
+			setFlag(flag, true, values, explicit)
+			continue
+		}
+
+		value := strings.TrimPrefix(shorts[position+utf8.RuneLen(short):], "=")
+		if value == "" {
+			if next >= len(args) {
+				err = fmt.Errorf("%w: expecting value for -%c", ErrIncompleteFlag, short)
+				return
+			}
+			value = args[next]
+			next++
+		}
+		var parsed any
+		parsed, err = parseValue(flag, "flag -"+flag.Name, value)
+		if err != nil {
+			return
+		}
+		setFlag(flag, parsed, values, explicit)
+		return
+	}
+	return
+}
+
+// parseArg stores the next positional argument, the variadic last one collects the remaining
+func (n *node) parseArg(arg string, values map[string]any) (err error) {
+	position := len(values)
+	last := len(n.Args) - 1
+	if position > last && (last < 0 || !n.Args[last].Variadic) {
+		err = fmt.Errorf("%w: %s: expecting %d: %s", ErrInvalidNumberOfArgs, n.Name, len(n.Args), arg)
+		return
+	}
+	position = min(position, last)
+
+	argEntry := n.Args[position]
+	parsed, err := parseValue(argEntry, "argument "+argEntry.Name, arg)
+	if err != nil {
+		return
+	}
+	if argEntry.Variadic {
+		parsed = appendValue(values[argEntry.Name], parsed)
+	}
+	values[argEntry.Name] = parsed
+	return
+}
+
+// required checks the required flags were set
+func (n *node) required(flags map[string]any) (err error) {
+	var required []string
+	for name, flag := range n.Flags {
+		if _, found := flags[name]; flag.Required && !found {
+			required = append(required, "-"+name)
+		}
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		err = fmt.Errorf("%w: %s: %s", ErrRequiredFlag, n.Name, strings.Join(required, ", "))
+	}
+	return
+}
+
+// missing checks the arguments that aren't optional or variadic were set
+func (n *node) missing(args map[string]any) (err error) {
+	for _, arg := range n.Args {
+		if _, found := args[arg.Name]; !found && !arg.Optional && !arg.Variadic {
+			err = fmt.Errorf("%w: %s: expecting %s", ErrMissingArgument, n.Name, arg.Name)
+			return
+		}
+	}
+	return
+}
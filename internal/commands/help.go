@@ -6,16 +6,46 @@ import (
 	"strings"
 )
 
+// details describes the type, default and constraints of the value
+func (v Value) details() string {
+	details := []string{v.Type.String()}
+	if v.Default != nil && v.Default != "" {
+		details = append(details, fmt.Sprintf("default %v", v.Default))
+	}
+	switch {
+	case v.Required:
+		details = append(details, "required")
+	case v.Variadic:
+		details = append(details, "variadic")
+	case v.Optional:
+		details = append(details, "optional")
+	}
+	if v.Type == TypeStrings {
+		details = append(details, "repeatable")
+	}
+	if len(v.Choices) > 0 {
+		details = append(details, "one of "+strings.Join(v.Choices, ", "))
+	}
+	return "[" + strings.Join(details, ", ") + "]"
+}
+
 func (vs Values) Table(prefix string) (s string) {
+	// Short aliases are listed before the name, with the dashes of the prefix
+	names := make([]string, len(vs))
 	var longest int
-	for _, v := range vs {
-		longest = max(longest, len(v.Name))
+	for index, v := range vs {
+		names[index] = v.Name
+		if v.Short != 0 {
+			names[index] = fmt.Sprintf("%c, %s%s", v.Short, strings.TrimLeft(prefix, "\t "), v.Name)
+		}
+		longest = max(longest, len(names[index]))
 	}
 
 	var buf bytes.Buffer
-	for _, v := range vs {
-		blank := strings.Repeat(" ", longest-len(v.Name))
-		fmt.Fprintf(&buf, "%s%s%s : %s\n", prefix, v.Name, blank, v.Description)
+	for index, v := range vs {
+		blank := strings.Repeat(" ", longest-len(names[index]))
+		description := strings.TrimSpace(v.Description + " " + v.details())
+		fmt.Fprintf(&buf, "%s%s%s : %s\n", prefix, names[index], blank, description)
 	}
 
 	s = buf.String()
@@ -5,6 +5,7 @@ type node struct {
 	Description string
 	Args        []Value
 	Flags       map[string]Value
+	Shorts      map[rune]Value
 	Setup       Setup
 	Callback    Callback
 	Defer       Defer
@@ -28,8 +29,12 @@ func (c *Command) tree() (n *node) {
 
 	// Flags
 	n.Flags = make(map[string]Value, len(c.Flags))
+	n.Shorts = make(map[rune]Value, len(c.Flags))
 	for _, flag := range c.Flags {
 		n.Flags[flag.Name] = flag
+		if flag.Short != 0 {
+			n.Shorts[flag.Short] = flag
+		}
 	}
 
 	// Setup
@@ -0,0 +1,86 @@
+// Package secure allocates key material and decrypted buffers outside of the Go heap.
+// Buffers are locked in RAM, excluded from core dumps and surrounded by guard pages
+// where the platform allows it, falling back to ordinary slices otherwise.
+// Values converted to strings, like the entries of database.Database, are copied back to the heap.
+package secure
+
+import "sync"
+
+type Buffer struct {
+	data []byte
+	// Whole mapping including the guard pages, nil for heap buffers
+	region []byte
+	locked bool
+}
+
+var (
+	liveMutex sync.Mutex
+	live      = map[*Buffer]struct{}{}
+)
+
+// New returns a zeroed buffer of size bytes, it must be released with Destroy
+func New(size int) (b *Buffer) {
+	b = allocate(size)
+
+	liveMutex.Lock()
+	defer liveMutex.Unlock()
+	live[b] = struct{}{}
+	return
+}
+
+// Copy returns a new buffer with the contents of src, wiping src is up to the caller
+func Copy(src []byte) (b *Buffer) {
+	b = New(len(src))
+	copy(b.data, src)
+	return
+}
+
+// Bytes returns the contents, they are no longer valid after Destroy
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+func (b *Buffer) Len() int {
+	return len(b.data)
+}
+
+// Locked reports if the buffer can't be swapped to disk
+func (b *Buffer) Locked() bool {
+	return b.locked
+}
+
+// Destroy zeroes and frees the buffer, calling it more than once is safe
+func (b *Buffer) Destroy() {
+	liveMutex.Lock()
+	_, found := live[b]
+	delete(live, b)
+	liveMutex.Unlock()
+	if !found {
+		return
+	}
+
+	Wipe(b.data)
+	release(b)
+	b.data = nil
+	b.region = nil
+	b.locked = false
+}
+
+// DestroyAll destroys every buffer still alive, meant to be called before exiting
+func DestroyAll() {
+	liveMutex.Lock()
+	buffers := make([]*Buffer, 0, len(live))
+	for b := range live {
+		buffers = append(buffers, b)
+	}
+	liveMutex.Unlock()
+
+	for _, b := range buffers {
+		b.Destroy()
+	}
+}
+
+// Wipe zeroes the slice
+func Wipe(b []byte) {
+	clear(b)
+}
@@ -0,0 +1,54 @@
+package secure
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func allocate(size int) (b *Buffer) {
+	if size == 0 {
+		return &Buffer{data: []byte{}}
+	}
+
+	pageSize := os.Getpagesize()
+	pages := (size + pageSize - 1) / pageSize
+	region, err := unix.Mmap(-1, 0, (pages+2)*pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return &Buffer{data: make([]byte, size)}
+	}
+
+	// Any overflow or underflow hits a guard page and crashes instead of leaking
+	unix.Mprotect(region[:pageSize], unix.PROT_NONE)
+	unix.Mprotect(region[len(region)-pageSize:], unix.PROT_NONE)
+
+	inner := region[pageSize : len(region)-pageSize]
+	unix.Madvise(inner, unix.MADV_DONTDUMP)
+	b = &Buffer{
+		// Aligned to the end so overflows reach the guard page right away
+		data:   inner[len(inner)-size:],
+		region: region,
+		// Locking fails when RLIMIT_MEMLOCK is exhausted, the buffer is still usable
+		locked: unix.Mlock(inner) == nil,
+	}
+	return
+}
+
+func release(b *Buffer) {
+	if b.region == nil {
+		return
+	}
+
+	pageSize := os.Getpagesize()
+	inner := b.region[pageSize : len(b.region)-pageSize]
+	Wipe(inner)
+	if b.locked {
+		unix.Munlock(inner)
+	}
+	unix.Munmap(b.region)
+}
+
+// DisableCoreDumps prevents the process from writing its memory to core files
+func DisableCoreDumps() (err error) {
+	return unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0})
+}
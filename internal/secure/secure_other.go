@@ -0,0 +1,15 @@
+//go:build !linux
+
+package secure
+
+// Other platforms use ordinary slices, they are still wiped on Destroy
+func allocate(size int) (b *Buffer) {
+	return &Buffer{data: make([]byte, size)}
+}
+
+func release(b *Buffer) {}
+
+// DisableCoreDumps is only supported on linux
+func DisableCoreDumps() (err error) {
+	return
+}
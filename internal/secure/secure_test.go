@@ -0,0 +1,47 @@
+package secure_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/secure"
+	"github.com/RogueTeam/guardian/internal/testsuite"
+)
+
+func TestBuffer(t *testing.T) {
+	t.Parallel()
+
+	pageSize := os.Getpagesize()
+	for _, size := range []int{0, 1, 32, pageSize - 1, pageSize, pageSize + 1, 3 * pageSize} {
+		size := size
+
+		src := testsuite.Random(size)
+		b := secure.Copy(src)
+		if b.Len() != size {
+			t.Fatalf("expecting %d bytes but received: %d", size, b.Len())
+		}
+		if !bytes.Equal(src, b.Bytes()) {
+			t.Fatal("expecting contents to match")
+		}
+
+		// The whole buffer is writable
+		for index := range b.Bytes() {
+			b.Bytes()[index] = 0xff
+		}
+
+		b.Destroy()
+		b.Destroy()
+		if b.Len() != 0 {
+			t.Fatalf("expecting destroyed buffer to be empty, but has %d bytes", b.Len())
+		}
+	}
+
+	buffers := []*secure.Buffer{secure.New(16), secure.New(pageSize)}
+	secure.DestroyAll()
+	for _, b := range buffers {
+		if b.Len() != 0 {
+			t.Fatalf("expecting destroyed buffer to be empty, but has %d bytes", b.Len())
+		}
+	}
+}
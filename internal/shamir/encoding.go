@@ -0,0 +1,170 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Layout: version | threshold | group (2) | x | y | checksum
+const (
+	encodingVersion = 1
+	headerSize      = 5
+	checksumSize    = 3
+)
+
+var (
+	ErrInvalidEncoding = errors.New("invalid share encoding")
+	ErrChecksum        = errors.New("share checksum mismatch, check for typos")
+	ErrMixedGroups     = errors.New("shares belong to different splits")
+)
+
+// Encoded is a share with the information needed to combine it with its siblings
+type Encoded struct {
+	Share
+	Threshold int
+	// Random value shared by the shares of the same split
+	Group [2]byte
+}
+
+func (e Encoded) Bytes() (data []byte) {
+	data = append([]byte{encodingVersion, byte(e.Threshold), e.Group[0], e.Group[1], e.X}, e.Y...)
+	sum := sha256.Sum256(data)
+	return append(data, sum[:checksumSize]...)
+}
+
+func Decode(data []byte) (e Encoded, err error) {
+	if len(data) < headerSize+1+checksumSize {
+		err = fmt.Errorf("%w: too short", ErrInvalidEncoding)
+		return
+	}
+
+	body, valid := verify(data)
+	// Words pad odd lengths with a zero byte
+	if !valid && len(data)%2 == 0 && data[len(data)-1] == 0 {
+		body, valid = verify(data[:len(data)-1])
+	}
+	if !valid {
+		err = ErrChecksum
+		return
+	}
+	if body[0] != encodingVersion {
+		err = fmt.Errorf("%w: unsupported version %d", ErrInvalidEncoding, body[0])
+		return
+	}
+
+	e = Encoded{
+		Share:     Share{X: body[4], Y: bytes.Clone(body[headerSize:])},
+		Threshold: int(body[1]),
+		Group:     [2]byte{body[2], body[3]},
+	}
+	return
+}
+
+func verify(data []byte) (body []byte, valid bool) {
+	body = data[:len(data)-checksumSize]
+	sum := sha256.Sum256(body)
+	return body, bytes.Equal(sum[:checksumSize], data[len(body):])
+}
+
+// Proquints, every 16 bits become a pronounceable five letter word
+const (
+	consonants = "bdfghjklmnprstvz"
+	vowels     = "aiou"
+)
+
+func Words(data []byte) string {
+	if len(data)%2 != 0 {
+		data = append(bytes.Clone(data), 0)
+	}
+	words := make([]string, 0, len(data)/2)
+	for index := 0; index+1 < len(data); index += 2 {
+		n := uint16(data[index])<<8 | uint16(data[index+1])
+		words = append(words, string([]byte{
+			consonants[n>>12&0xf],
+			vowels[n>>10&0x3],
+			consonants[n>>6&0xf],
+			vowels[n>>4&0x3],
+			consonants[n&0xf],
+		}))
+	}
+	return strings.Join(words, "-")
+}
+
+func ParseWords(s string) (data []byte, err error) {
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == ' ' || r == '\n' || r == '\t' }) {
+		if len(word) != 5 {
+			err = fmt.Errorf("%w: invalid word %s", ErrInvalidEncoding, word)
+			return
+		}
+
+		var n uint16
+		for index, alphabet := range []string{consonants, vowels, consonants, vowels, consonants} {
+			position := strings.IndexByte(alphabet, word[index])
+			if position < 0 {
+				err = fmt.Errorf("%w: invalid word %s", ErrInvalidEncoding, word)
+				return
+			}
+			if len(alphabet) == len(consonants) {
+				n = n<<4 | uint16(position)
+			} else {
+				n = n<<2 | uint16(position)
+			}
+		}
+		data = append(data, byte(n>>8), byte(n))
+	}
+	return
+}
+
+// Text uses uppercase base32, which fits the alphanumeric mode of QR codes
+func Text(data []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(data)
+}
+
+func ParseText(s string) (data []byte, err error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	data, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+	return
+}
+
+// Parse decodes a share written either as words or as text
+func Parse(s string) (e Encoded, err error) {
+	var data []byte
+	if strings.Contains(s, "-") {
+		data, err = ParseWords(strings.ToLower(s))
+	} else {
+		data, err = ParseText(s)
+	}
+	if err != nil {
+		return
+	}
+	return Decode(data)
+}
+
+// CombineEncoded verifies the shares belong to the same split and reconstructs the secret
+func CombineEncoded(encoded []Encoded) (secret []byte, err error) {
+	if len(encoded) == 0 {
+		err = ErrNotEnoughShares
+		return
+	}
+
+	shares := make([]Share, 0, len(encoded))
+	for _, e := range encoded {
+		if e.Group != encoded[0].Group || e.Threshold != encoded[0].Threshold {
+			err = ErrMixedGroups
+			return
+		}
+		shares = append(shares, e.Share)
+	}
+	if len(shares) < encoded[0].Threshold {
+		err = fmt.Errorf("%w: need %d, have %d", ErrNotEnoughShares, encoded[0].Threshold, len(shares))
+		return
+	}
+	return Combine(shares)
+}
@@ -0,0 +1,19 @@
+package shamir
+
+import "testing"
+
+func TestGF256(t *testing.T) {
+	t.Parallel()
+
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			product := mul(byte(a), byte(b))
+			if expect := mulNoTable(byte(a), byte(b)); product != expect {
+				t.Fatalf("expecting %d * %d = %d but received: %d", a, b, expect, product)
+			}
+			if b != 0 && div(product, byte(b)) != byte(a) {
+				t.Fatalf("expecting (%d * %d) / %d = %d", a, b, b, a)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package shamir implements Shamir's secret sharing over GF(256)
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidThreshold = errors.New("threshold must be between 2 and the number of shares")
+	ErrInvalidShares    = errors.New("number of shares must be between 2 and 255")
+	ErrEmptySecret      = errors.New("secret is empty")
+	ErrNotEnoughShares  = errors.New("not enough shares")
+	ErrInconsistent     = errors.New("shares are inconsistent")
+)
+
+// Share is a single point of every polynomial, one per secret byte
+type Share struct {
+	X byte
+	Y []byte
+}
+
+// Split divides the secret into n shares, any threshold of them reconstruct it
+func Split(secret []byte, n, threshold int) (shares []Share, err error) {
+	switch {
+	case len(secret) == 0:
+		err = ErrEmptySecret
+		return
+	case n < 2 || n > 255:
+		err = fmt.Errorf("%w: %d", ErrInvalidShares, n)
+		return
+	case threshold < 2 || threshold > n:
+		err = fmt.Errorf("%w: %d", ErrInvalidThreshold, threshold)
+		return
+	}
+
+	shares = make([]Share, n)
+	for index := range shares {
+		shares[index] = Share{X: byte(index + 1), Y: make([]byte, len(secret))}
+	}
+
+	// Random polynomial per byte with the secret as the constant term
+	coefficients := make([]byte, threshold)
+	defer rand.Read(coefficients)
+	for position, value := range secret {
+		rand.Read(coefficients[1:])
+		coefficients[0] = value
+
+		for index := range shares {
+			shares[index].Y[position] = evaluate(coefficients, shares[index].X)
+		}
+	}
+	return
+}
+
+// Horner's method
+func evaluate(coefficients []byte, x byte) (result byte) {
+	for index := len(coefficients) - 1; index >= 0; index-- {
+		result = add(mul(result, x), coefficients[index])
+	}
+	return
+}
+
+// Combine interpolates the shares at zero, every share is used
+func Combine(shares []Share) (secret []byte, err error) {
+	if len(shares) < 2 {
+		err = ErrNotEnoughShares
+		return
+	}
+
+	seen := map[byte]struct{}{}
+	for _, share := range shares {
+		if _, found := seen[share.X]; found || share.X == 0 || len(share.Y) != len(shares[0].Y) {
+			err = ErrInconsistent
+			return
+		}
+		seen[share.X] = struct{}{}
+	}
+
+	secret = make([]byte, len(shares[0].Y))
+	for i, share := range shares {
+		// Lagrange basis polynomial evaluated at zero
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				basis = mul(basis, div(other.X, add(other.X, share.X)))
+			}
+		}
+		for position := range secret {
+			secret[position] = add(secret[position], mul(share.Y[position], basis))
+		}
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package shamir_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/shamir"
+	"github.com/RogueTeam/guardian/internal/testsuite"
+)
+
+// Every subset of the shares, as bit masks
+func subsets(n int) (masks []int) {
+	for mask := 1; mask < 1<<n; mask++ {
+		masks = append(masks, mask)
+	}
+	return
+}
+
+func pick(shares []shamir.Share, mask int) (picked []shamir.Share) {
+	for index, share := range shares {
+		if mask&(1<<index) != 0 {
+			picked = append(picked, share)
+		}
+	}
+	return
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		for n := 2; n <= 7; n++ {
+			for threshold := 2; threshold <= n; threshold++ {
+				secret := testsuite.Random(32)
+				shares, err := shamir.Split(secret, n, threshold)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				for _, mask := range subsets(n) {
+					picked := pick(shares, mask)
+					if len(picked) < 2 {
+						continue
+					}
+
+					combined, err := shamir.Combine(picked)
+					if err != nil {
+						t.Fatalf("expecting no errors, but received: %v", err)
+					}
+
+					// Fewer shares than the threshold must not reveal the secret
+					recovered := bytes.Equal(secret, combined)
+					if recovered != (len(picked) >= threshold) {
+						t.Fatalf("n=%d threshold=%d shares=%d: expecting recovered=%v", n, threshold, len(picked), !recovered)
+					}
+				}
+			}
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name      string
+			Secret    []byte
+			N         int
+			Threshold int
+			Expect    error
+		}
+
+		tests := []Test{
+			{"Empty secret", nil, 3, 2, shamir.ErrEmptySecret},
+			{"Single share", []byte("secret"), 1, 1, shamir.ErrInvalidShares},
+			{"Too many shares", []byte("secret"), 256, 2, shamir.ErrInvalidShares},
+			{"Threshold of one", []byte("secret"), 3, 1, shamir.ErrInvalidThreshold},
+			{"Threshold above shares", []byte("secret"), 3, 4, shamir.ErrInvalidThreshold},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := shamir.Split(test.Secret, test.N, test.Threshold)
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+
+		shares, _ := shamir.Split([]byte("secret"), 3, 2)
+		_, err := shamir.Combine([]shamir.Share{shares[0], shares[0]})
+		if !errors.Is(err, shamir.ErrInconsistent) {
+			t.Fatalf("expecting %v but received: %v", shamir.ErrInconsistent, err)
+		}
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		for _, size := range []int{31, 32} {
+			secret := testsuite.Random(size)
+			shares, err := shamir.Split(secret, 5, 3)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			var encoded []shamir.Encoded
+			for index, share := range shares {
+				data := shamir.Encoded{Share: share, Threshold: 3, Group: [2]byte{1, 2}}.Bytes()
+
+				// Alternate between both representations
+				text := shamir.Words(data)
+				if index%2 == 1 {
+					text = shamir.Text(data)
+				}
+
+				e, err := shamir.Parse(text)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				encoded = append(encoded, e)
+			}
+
+			for _, mask := range subsets(len(encoded)) {
+				var picked []shamir.Encoded
+				for index, e := range encoded {
+					if mask&(1<<index) != 0 {
+						picked = append(picked, e)
+					}
+				}
+
+				combined, err := shamir.CombineEncoded(picked)
+				if len(picked) < 3 {
+					if !errors.Is(err, shamir.ErrNotEnoughShares) {
+						t.Fatalf("expecting %v but received: %v", shamir.ErrNotEnoughShares, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if !bytes.Equal(secret, combined) {
+					t.Fatal("expecting secret to be recovered")
+				}
+			}
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		shares, _ := shamir.Split(testsuite.Random(32), 3, 2)
+		words := shamir.Words(shamir.Encoded{Share: shares[0], Threshold: 2}.Bytes())
+
+		// Typo in the first word
+		typo := []byte(words)
+		if typo[0] == 'b' {
+			typo[0] = 'd'
+		} else {
+			typo[0] = 'b'
+		}
+		_, err := shamir.Parse(string(typo))
+		if !errors.Is(err, shamir.ErrChecksum) {
+			t.Fatalf("expecting %v but received: %v", shamir.ErrChecksum, err)
+		}
+
+		_, err = shamir.Parse("not-words")
+		if !errors.Is(err, shamir.ErrInvalidEncoding) {
+			t.Fatalf("expecting %v but received: %v", shamir.ErrInvalidEncoding, err)
+		}
+
+		a := shamir.Encoded{Share: shares[0], Threshold: 2, Group: [2]byte{1, 1}}
+		b := shamir.Encoded{Share: shares[1], Threshold: 2, Group: [2]byte{2, 2}}
+		_, err = shamir.CombineEncoded([]shamir.Encoded{a, b})
+		if !errors.Is(err, shamir.ErrMixedGroups) {
+			t.Fatalf("expecting %v but received: %v", shamir.ErrMixedGroups, err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+var ErrNoClipboard = errors.New("no clipboard command found, expecting wl-copy, xclip, xsel or pbcopy")
+
+// Clipboard commands reading the value from stdin, the first one found is used
+var clipboardCommands = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"pbcopy"},
+}
+
+// CopyToClipboard writes the value to the system clipboard
+func CopyToClipboard(value []byte) (err error) {
+	for _, command := range clipboardCommands {
+		_, err = exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = bytes.NewReader(value)
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			err = fmt.Errorf("failed to run clipboard command: %w", err)
+		}
+		return
+	}
+	return ErrNoClipboard
+}
+
+// CopyToClipboardFor copies the value and clears the clipboard once the timeout expires, a zero timeout keeps it
+func CopyToClipboardFor(value []byte, timeout time.Duration) (err error) {
+	err = CopyToClipboard(value)
+	if err != nil || timeout <= 0 {
+		return
+	}
+	time.Sleep(timeout)
+	err = CopyToClipboard(nil)
+	if err != nil {
+		err = fmt.Errorf("failed to clear clipboard: %w", err)
+	}
+	return
+}
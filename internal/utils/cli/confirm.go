@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Confirm asks a yes/no question in the terminal, anything but "y" or "yes" is a no
+func Confirm(question string) bool {
+	input := os.Stdin
+	if !term.IsTerminal(int(input.Fd())) {
+		tty, err := os.Open("/dev/tty")
+		if err == nil {
+			defer tty.Close()
+			input = tty
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Prefixes of the non-interactive key sources
+const (
+	SourceEnv  = "env:"
+	SourceFd   = "fd:"
+	SourceFile = "file:"
+	SourceCmd  = "cmd:"
+)
+
+var (
+	ErrUnknownKeySource = errors.New("unknown key source, expecting env:VAR, fd:N, file:PATH or cmd:COMMAND")
+	ErrEmptyKey         = errors.New("key source returned an empty key")
+)
+
+// ReadKeyFrom reads the key from a non-interactive source, a single trailing newline is removed.
+//   - env:VAR reads the environment variable
+//   - fd:N reads the already open file descriptor until EOF
+//   - file:PATH reads the file
+//   - cmd:COMMAND runs the command with sh -c and reads its stdout
+func ReadKeyFrom(source string) (key []byte, err error) {
+	switch {
+	case strings.HasPrefix(source, SourceEnv):
+		name := strings.TrimPrefix(source, SourceEnv)
+		value, found := os.LookupEnv(name)
+		if !found {
+			err = fmt.Errorf("environment variable %s is not set", name)
+			return
+		}
+		key = []byte(value)
+	case strings.HasPrefix(source, SourceFd):
+		var fd int
+		fd, err = strconv.Atoi(strings.TrimPrefix(source, SourceFd))
+		if err != nil || fd < 0 {
+			err = fmt.Errorf("invalid file descriptor: %s", source)
+			return
+		}
+		file := os.NewFile(uintptr(fd), source)
+		if file == nil {
+			err = fmt.Errorf("invalid file descriptor: %s", source)
+			return
+		}
+		defer file.Close()
+		key, err = io.ReadAll(file)
+		if err != nil {
+			err = fmt.Errorf("failed to read file descriptor: %w", err)
+			return
+		}
+	case strings.HasPrefix(source, SourceFile):
+		key, err = os.ReadFile(strings.TrimPrefix(source, SourceFile))
+		if err != nil {
+			err = fmt.Errorf("failed to read key file: %w", err)
+			return
+		}
+	case strings.HasPrefix(source, SourceCmd):
+		var stdout bytes.Buffer
+		cmd := exec.Command("sh", "-c", strings.TrimPrefix(source, SourceCmd))
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			err = fmt.Errorf("failed to run key command: %w", err)
+			return
+		}
+		key = stdout.Bytes()
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownKeySource, source)
+		return
+	}
+
+	key = trimNewline(key)
+	if len(key) == 0 {
+		err = ErrEmptyKey
+	}
+	return
+}
+
+func trimNewline(key []byte) []byte {
+	key = bytes.TrimSuffix(key, []byte("\n"))
+	return bytes.TrimSuffix(key, []byte("\r"))
+}
@@ -0,0 +1,96 @@
+package cli_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+// Not parallel, t.Setenv changes the environment of the whole process
+func TestReadKeyFrom(t *testing.T) {
+	t.Setenv("GUARDIAN_TEST_KEY", "password")
+	t.Setenv("GUARDIAN_TEST_EMPTY", "")
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	err := os.WriteFile(keyFile, []byte("password\n"), 0o600)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	// Returns a duplicated descriptor with the contents ready to be read
+	fd := func(t *testing.T, contents string) string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		defer r.Close()
+		w.WriteString(contents)
+		w.Close()
+
+		dup, err := syscall.Dup(int(r.Fd()))
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		return "fd:" + strconv.Itoa(dup)
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		type Test struct {
+			Name   string
+			Source func(t *testing.T) string
+		}
+		tests := []Test{
+			{Name: "Env", Source: func(t *testing.T) string { return "env:GUARDIAN_TEST_KEY" }},
+			{Name: "Fd", Source: func(t *testing.T) string { return fd(t, "password\n") }},
+			{Name: "File", Source: func(t *testing.T) string { return "file:" + keyFile }},
+			{Name: "Cmd", Source: func(t *testing.T) string { return "cmd:echo password" }},
+			{Name: "Cmd CRLF", Source: func(t *testing.T) string { return `cmd:printf 'password\r\n'` }},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				key, err := cli.ReadKeyFrom(test.Source(t))
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if string(key) != "password" {
+					t.Fatalf("expecting password but received: %q", key)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		type Test struct {
+			Name   string
+			Source func(t *testing.T) string
+			Expect error
+		}
+		tests := []Test{
+			{Name: "Unknown source", Source: func(t *testing.T) string { return "password" }, Expect: cli.ErrUnknownKeySource},
+			{Name: "Empty env", Source: func(t *testing.T) string { return "env:GUARDIAN_TEST_EMPTY" }, Expect: cli.ErrEmptyKey},
+			{Name: "Missing env", Source: func(t *testing.T) string { return "env:GUARDIAN_TEST_MISSING" }},
+			{Name: "Empty fd", Source: func(t *testing.T) string { return fd(t, "") }, Expect: cli.ErrEmptyKey},
+			{Name: "Invalid fd", Source: func(t *testing.T) string { return "fd:stdin" }},
+			{Name: "Missing file", Source: func(t *testing.T) string { return "file:" + filepath.Join(dir, "missing") }, Expect: os.ErrNotExist},
+			{Name: "Failing cmd", Source: func(t *testing.T) string { return "cmd:exit 1" }},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				_, err := cli.ReadKeyFrom(test.Source(t))
+				if err == nil {
+					t.Fatal("expecting an error")
+				}
+				if test.Expect != nil && !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats of command results
+const (
+	// Strings and numbers raw, everything else as JSON
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+)
+
+var ErrUnknownOutput = errors.New("unknown output, expecting text, json, yaml or table")
+
+// Tabler is implemented by results with their own table layout
+type Tabler interface {
+	Table() string
+}
+
+// Raw results are written as they are in every output format, like the answers of credential helpers
+type Raw string
+
+// ParseOutput validates the output format
+func ParseOutput(s string) (output string, err error) {
+	switch s {
+	case OutputText, OutputJSON, OutputYAML, OutputTable:
+		output = s
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownOutput, s)
+	}
+	return
+}
+
+// WriteOutput writes the result of a command in the output format
+func WriteOutput(w io.Writer, output string, result any) (err error) {
+	if raw, ok := result.(Raw); ok {
+		_, err = io.WriteString(w, string(raw))
+		if err != nil {
+			err = fmt.Errorf("failed to write output: %w", err)
+		}
+		return
+	}
+
+	switch output {
+	case OutputText:
+		switch result := result.(type) {
+		case string, int, float64:
+			_, err = fmt.Fprintf(w, "%v", result)
+		case Tabler:
+			_, err = io.WriteString(w, result.Table())
+		default:
+			err = json.NewEncoder(w).Encode(result)
+		}
+	case OutputJSON:
+		err = json.NewEncoder(w).Encode(result)
+	case OutputYAML:
+		var value any
+		value, err = normalize(result)
+		if err == nil {
+			var buf bytes.Buffer
+			writeYAML(&buf, value, 0)
+			_, err = w.Write(buf.Bytes())
+		}
+	case OutputTable:
+		if tabler, ok := result.(Tabler); ok {
+			_, err = io.WriteString(w, tabler.Table())
+			return
+		}
+		var value any
+		value, err = normalize(result)
+		if err == nil {
+			_, err = io.WriteString(w, table(value))
+		}
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownOutput, output)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write output: %w", err)
+	}
+	return
+}
+
+// normalize converts the result to the values decoded from its JSON encoding
+func normalize(result any) (value any, err error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	return
+}
+
+func sortedKeys(m map[string]any) (keys []string) {
+	keys = make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// Strings needing no quotes, anything else is written as a double quoted string
+var plainYAML = regexp.MustCompile(`^[A-Za-z_./][A-Za-z0-9_./@+-]*( [A-Za-z0-9_./@+-]+)*$`)
+
+func yamlScalar(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		switch strings.ToLower(value) {
+		case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+			return strconv.Quote(value)
+		}
+		if plainYAML.MatchString(value) {
+			return value
+		}
+		return strconv.Quote(value)
+	}
+	return fmt.Sprint(value)
+}
+
+func writeYAML(buf *bytes.Buffer, value any, indent int) {
+	prefix := strings.Repeat(" ", indent)
+	switch value := value.(type) {
+	case map[string]any:
+		if len(value) == 0 {
+			fmt.Fprintf(buf, "%s{}\n", prefix)
+			return
+		}
+		for _, key := range sortedKeys(value) {
+			switch child := value[key].(type) {
+			case map[string]any:
+				if len(child) > 0 {
+					fmt.Fprintf(buf, "%s%s:\n", prefix, yamlScalar(key))
+					writeYAML(buf, child, indent+2)
+					continue
+				}
+				fmt.Fprintf(buf, "%s%s: {}\n", prefix, yamlScalar(key))
+			case []any:
+				if len(child) > 0 {
+					fmt.Fprintf(buf, "%s%s:\n", prefix, yamlScalar(key))
+					writeYAML(buf, child, indent)
+					continue
+				}
+				fmt.Fprintf(buf, "%s%s: []\n", prefix, yamlScalar(key))
+			default:
+				fmt.Fprintf(buf, "%s%s: %s\n", prefix, yamlScalar(key), yamlScalar(child))
+			}
+		}
+	case []any:
+		if len(value) == 0 {
+			fmt.Fprintf(buf, "%s[]\n", prefix)
+			return
+		}
+		for _, item := range value {
+			// Items are written one level deeper, the dash replaces the indentation of the first line
+			var nested bytes.Buffer
+			writeYAML(&nested, item, indent+2)
+			buf.WriteString(prefix + "- ")
+			buf.Write(nested.Bytes()[indent+2:])
+		}
+	default:
+		fmt.Fprintf(buf, "%s%s\n", prefix, yamlScalar(value))
+	}
+}
+
+func cell(value any) string {
+	switch value.(type) {
+	case nil:
+		return "-"
+	case map[string]any, []any:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+// table lays out lists of objects in columns, objects as key value rows and lists one per line
+func table(value any) string {
+	var rows [][]string
+	switch value := value.(type) {
+	case map[string]any:
+		rows = append(rows, []string{"KEY", "VALUE"})
+		for _, key := range sortedKeys(value) {
+			rows = append(rows, []string{key, cell(value[key])})
+		}
+	case []any:
+		columns := map[string]any{}
+		for _, item := range value {
+			object, ok := item.(map[string]any)
+			if !ok {
+				columns = nil
+				break
+			}
+			for key := range object {
+				columns[key] = nil
+			}
+		}
+		if len(columns) == 0 {
+			for _, item := range value {
+				rows = append(rows, []string{cell(item)})
+			}
+			break
+		}
+		header := sortedKeys(columns)
+		rows = append(rows, make([]string, len(header)))
+		for index, key := range header {
+			rows[0][index] = strings.ToUpper(key)
+		}
+		for _, item := range value {
+			row := make([]string, len(header))
+			for index, key := range header {
+				row[index] = cell(item.(map[string]any)[key])
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return cell(value) + "\n"
+	}
+
+	widths := map[int]int{}
+	for _, row := range rows {
+		for column, cell := range row {
+			widths[column] = max(widths[column], len(cell))
+		}
+	}
+	var buf strings.Builder
+	for _, row := range rows {
+		var line strings.Builder
+		for column, cell := range row {
+			fmt.Fprintf(&line, "%-*s  ", widths[column], cell)
+		}
+		buf.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return buf.String()
+}
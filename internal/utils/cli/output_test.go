@@ -0,0 +1,85 @@
+package cli_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/utils/cli"
+)
+
+type tabler struct{}
+
+func (tabler) Table() string { return "custom table\n" }
+
+func TestWriteOutput(t *testing.T) {
+	t.Parallel()
+
+	type entry struct {
+		Name  string   `json:"name"`
+		Count int      `json:"count"`
+		Tags  []string `json:"tags,omitempty"`
+	}
+
+	type Test struct {
+		Name   string
+		Output string
+		Result any
+		Expect string
+	}
+	tests := []Test{
+		{Name: "Text string", Output: cli.OutputText, Result: "secret", Expect: "secret"},
+		{Name: "Text object", Output: cli.OutputText, Result: entry{Name: "a", Count: 1}, Expect: "{\"name\":\"a\",\"count\":1}\n"},
+		{Name: "Text tabler", Output: cli.OutputText, Result: tabler{}, Expect: "custom table\n"},
+		{Name: "JSON string", Output: cli.OutputJSON, Result: "secret", Expect: "\"secret\"\n"},
+		{Name: "JSON list", Output: cli.OutputJSON, Result: []string{"a", "b"}, Expect: "[\"a\",\"b\"]\n"},
+		{Name: "YAML string", Output: cli.OutputYAML, Result: "secret", Expect: "secret\n"},
+		{Name: "YAML quoted", Output: cli.OutputYAML, Result: []string{"true", "-abc", "a: b", ""}, Expect: "- \"true\"\n- \"-abc\"\n- \"a: b\"\n- \"\"\n"},
+		{
+			Name:   "YAML objects",
+			Output: cli.OutputYAML,
+			Result: []entry{{Name: "a", Count: 1, Tags: []string{"x", "y"}}, {Name: "b"}},
+			Expect: "- count: 1\n  name: a\n  tags:\n  - x\n  - \"y\"\n- count: 0\n  name: b\n",
+		},
+		{Name: "YAML nested", Output: cli.OutputYAML, Result: map[string]any{"a": map[string]any{"b": nil}, "c": []any{}}, Expect: "a:\n  b: null\nc: []\n"},
+		{Name: "Table list", Output: cli.OutputTable, Result: []string{"a", "b"}, Expect: "a\nb\n"},
+		{Name: "JSON raw", Output: cli.OutputJSON, Result: cli.Raw("password=secret\n"), Expect: "password=secret\n"},
+		{Name: "YAML raw", Output: cli.OutputYAML, Result: cli.Raw("{\"Secret\":\"s\"}\n"), Expect: "{\"Secret\":\"s\"}\n"},
+		{Name: "Table object", Output: cli.OutputTable, Result: entry{Name: "example", Count: 10}, Expect: "KEY    VALUE\ncount  10\nname   example\n"},
+		{
+			Name:   "Table objects",
+			Output: cli.OutputTable,
+			Result: []entry{{Name: "example", Count: 1, Tags: []string{"x"}}, {Name: "b", Count: 20}},
+			Expect: "COUNT  NAME     TAGS\n1      example  [\"x\"]\n20     b        -\n",
+		},
+		{Name: "Table tabler", Output: cli.OutputTable, Result: tabler{}, Expect: "custom table\n"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := cli.WriteOutput(&buf, test.Output, test.Result)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			if buf.String() != test.Expect {
+				t.Fatalf("expecting %q but received: %q", test.Expect, buf.String())
+			}
+		})
+	}
+
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := cli.ParseOutput("xml")
+		if !errors.Is(err, cli.ErrUnknownOutput) {
+			t.Fatalf("expecting %v but received: %v", cli.ErrUnknownOutput, err)
+		}
+		err = cli.WriteOutput(&bytes.Buffer{}, "xml", nil)
+		if !errors.Is(err, cli.ErrUnknownOutput) {
+			t.Fatalf("expecting %v but received: %v", cli.ErrUnknownOutput, err)
+		}
+	})
+}
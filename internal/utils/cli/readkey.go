@@ -1,21 +1,41 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	"golang.org/x/term"
 )
 
-func ReadKey(prompt bool) []byte {
+var ErrNoTerminal = errors.New("no terminal available to read the secret, use -key-from instead")
+
+func ReadKey(prompt bool) (key []byte, err error) {
+	return ReadSecret("Master key", prompt)
+}
+
+// ReadSecret reads a hidden value from the terminal, printing label as prompt.
+// When stdin is not a terminal, like when used as a git credential helper, the
+// controlling terminal is used instead
+func ReadSecret(label string, prompt bool) (secret []byte, err error) {
+	input := os.Stdin
+	if !term.IsTerminal(int(input.Fd())) {
+		tty, openErr := os.Open("/dev/tty")
+		if openErr != nil {
+			err = ErrNoTerminal
+			return
+		}
+		defer tty.Close()
+		input = tty
+	}
+
 	if prompt {
-		fmt.Fprint(os.Stderr, "Master key: ")
+		fmt.Fprintf(os.Stderr, "%s: ", label)
 		defer fmt.Fprintln(os.Stderr, "")
 	}
-	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	secret, err = term.ReadPassword(int(input.Fd()))
 	if err != nil {
-		log.Fatal(fmt.Errorf("failed to read password"))
+		err = fmt.Errorf("failed to read %s: %w", label, err)
 	}
-	return password
+	return
 }
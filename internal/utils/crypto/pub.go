@@ -1,11 +1,68 @@
 package crypto
 
 import (
+	stdcrypto "crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+)
+
+const (
+	KeyEd25519 = "ed25519"
+	KeyRSA     = "rsa"
+	KeyECDSA   = "ecdsa"
+)
+
+const (
+	DefaultRSABits   = 4096
+	DefaultECDSABits = 256
+)
+
+var (
+	ErrUnknownKeyType = errors.New("unknown key type")
+	ErrInvalidKeyBits = errors.New("invalid key bits")
 )
 
 func NewPrivKey() (key ed25519.PrivateKey, err error) {
 	_, key, err = ed25519.GenerateKey(rand.Reader)
 	return
 }
+
+// NewKey generates a private key of the requested type.
+// bits is ignored for ed25519, when zero the type default is used
+func NewKey(keyType string, bits int) (key stdcrypto.Signer, err error) {
+	switch keyType {
+	case KeyEd25519:
+		key, err = NewPrivKey()
+	case KeyRSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits < 2048 {
+			err = fmt.Errorf("%w: rsa requires at least 2048: %d", ErrInvalidKeyBits, bits)
+			return
+		}
+		key, err = rsa.GenerateKey(rand.Reader, bits)
+	case KeyECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			err = fmt.Errorf("%w: ecdsa supports 256, 384 or 521: %d", ErrInvalidKeyBits, bits)
+			return
+		}
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownKeyType, keyType)
+	}
+	return
+}
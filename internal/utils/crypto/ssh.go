@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrPassphraseRequired = errors.New("passphrase required")
+
+// MarshalSSHKey encodes the private key in the OpenSSH PEM format.
+// An empty passphrase leaves the key unencrypted
+func MarshalSSHKey(key any, comment string, passphrase []byte) (data []byte, err error) {
+	var block *pem.Block
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, comment)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to marshal private key: %w", err)
+		return
+	}
+
+	data = pem.EncodeToMemory(block)
+	return
+}
+
+// ParseSSHKey decodes a PEM encoded private key (OpenSSH, PKCS#1, PKCS#8 or SEC1).
+// When the key is encrypted and no passphrase is provided ErrPassphraseRequired is returned
+func ParseSSHKey(data, passphrase []byte) (key any, err error) {
+	if len(passphrase) > 0 {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	} else {
+		key, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			err = ErrPassphraseRequired
+			return
+		}
+		err = fmt.Errorf("failed to parse private key: %w", err)
+		return
+	}
+	return
+}
+
+// AuthorizedKey returns the authorized_keys line of the public part of the key
+func AuthorizedKey(key any, comment string) (line string, err error) {
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		err = fmt.Errorf("failed to prepare signer: %w", err)
+		return
+	}
+
+	authorized := bytes.TrimSpace(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	if comment != "" {
+		authorized = append(authorized, ' ')
+		authorized = append(authorized, comment...)
+	}
+
+	line = string(authorized) + "\n"
+	return
+}
+
+// SSHComment returns the comment of an authorized_keys line, empty when it has none
+func SSHComment(authorized string) (comment string) {
+	_, comment, _, _, _ = ssh.ParseAuthorizedKey([]byte(authorized))
+	return
+}
@@ -0,0 +1,124 @@
+package crypto_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/utils/crypto"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name       string
+			Type       string
+			Bits       int
+			Passphrase []byte
+		}
+
+		tests := []Test{
+			{"Ed25519", crypto.KeyEd25519, 0, nil},
+			{"Ed25519 with passphrase", crypto.KeyEd25519, 0, []byte("passphrase")},
+			{"RSA", crypto.KeyRSA, 2048, nil},
+			{"ECDSA", crypto.KeyECDSA, 0, nil},
+			{"ECDSA 384 with passphrase", crypto.KeyECDSA, 384, []byte("passphrase")},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				key, err := crypto.NewKey(test.Type, test.Bits)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				data, err := crypto.MarshalSSHKey(key, "user@host", test.Passphrase)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				parsed, err := crypto.ParseSSHKey(data, test.Passphrase)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				expect, err := crypto.AuthorizedKey(key, "user@host")
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				obtained, err := crypto.AuthorizedKey(parsed, "user@host")
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if expect != obtained {
+					t.Fatalf("expecting %s but received: %s", expect, obtained)
+				}
+
+				_, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(obtained))
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+				if comment != "user@host" {
+					t.Fatalf("expecting comment user@host but received: %s", comment)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("Unknown type", func(t *testing.T) {
+			_, err := crypto.NewKey("dsa", 0)
+			if !errors.Is(err, crypto.ErrUnknownKeyType) {
+				t.Fatalf("expecting unknown key type error, but received: %v", err)
+			}
+		})
+
+		t.Run("Invalid bits", func(t *testing.T) {
+			_, err := crypto.NewKey(crypto.KeyECDSA, 128)
+			if !errors.Is(err, crypto.ErrInvalidKeyBits) {
+				t.Fatalf("expecting invalid bits error, but received: %v", err)
+			}
+		})
+
+		t.Run("Missing passphrase", func(t *testing.T) {
+			key, err := crypto.NewKey(crypto.KeyEd25519, 0)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			data, err := crypto.MarshalSSHKey(key, "", []byte("passphrase"))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			_, err = crypto.ParseSSHKey(data, nil)
+			if !errors.Is(err, crypto.ErrPassphraseRequired) {
+				t.Fatalf("expecting passphrase required error, but received: %v", err)
+			}
+		})
+
+		t.Run("Wrong passphrase", func(t *testing.T) {
+			key, err := crypto.NewKey(crypto.KeyEd25519, 0)
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+			data, err := crypto.MarshalSSHKey(key, "", []byte("passphrase"))
+			if err != nil {
+				t.Fatalf("expecting no errors, but received: %v", err)
+			}
+
+			_, err = crypto.ParseSSHKey(data, []byte("invalid"))
+			if err == nil || strings.Contains(err.Error(), "passphrase required") {
+				t.Fatalf("expecting decryption error, but received: %v", err)
+			}
+		})
+	})
+}
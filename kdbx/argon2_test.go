@@ -0,0 +1,61 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/RogueTeam/guardian/internal/testsuite"
+	"golang.org/x/crypto/argon2"
+)
+
+func Test_argon2Key(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RFC 9106 vectors", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name   string
+			Mode   int
+			Expect string
+		}
+
+		tests := []Test{
+			{"Argon2d", argon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+			{"Argon2i", argon2i, "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8"},
+			{"Argon2id", argon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+		}
+
+		password := bytes.Repeat([]byte{0x01}, 32)
+		salt := bytes.Repeat([]byte{0x02}, 16)
+		secret := bytes.Repeat([]byte{0x03}, 8)
+		data := bytes.Repeat([]byte{0x04}, 12)
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				key := argon2Key(test.Mode, password, salt, secret, data, 3, 32, 4, 32)
+				if hex.EncodeToString(key) != test.Expect {
+					t.Fatalf("expecting %s but received: %x", test.Expect, key)
+				}
+			})
+		}
+	})
+
+	t.Run("Matches x/crypto", func(t *testing.T) {
+		t.Parallel()
+
+		password := testsuite.Random(16)
+		salt := testsuite.Random(16)
+
+		if !bytes.Equal(argon2.Key(password, salt, 2, 64, 2, 80), argon2Key(argon2i, password, salt, nil, nil, 2, 64, 2, 80)) {
+			t.Fatal("argon2i doesn't match")
+		}
+		if !bytes.Equal(argon2.IDKey(password, salt, 2, 64, 2, 32), argon2Key(argon2id, password, salt, nil, nil, 2, 64, 2, 32)) {
+			t.Fatal("argon2id doesn't match")
+		}
+	})
+}
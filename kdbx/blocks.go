@@ -0,0 +1,84 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const blockSize = 1024 * 1024
+
+func blockHMAC(hmacKey []byte, index uint64, data []byte) []byte {
+	var prefix [12]byte
+	binary.LittleEndian.PutUint64(prefix[:8], index)
+	binary.LittleEndian.PutUint32(prefix[8:], uint32(len(data)))
+
+	mac := hmac.New(sha256.New, blockHMACKey(hmacKey, index))
+	mac.Write(prefix[:])
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Reads and verifies the HMAC block stream returning the concatenated cipher
+func readBlocks(r io.Reader, hmacKey []byte) (cipher []byte, err error) {
+	var buf bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var sum [sha256.Size]byte
+		_, err = io.ReadFull(r, sum[:])
+		if err != nil {
+			err = fmt.Errorf("%w: truncated block %d", ErrCorrupted, index)
+			return
+		}
+
+		var size int32
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err != nil || size < 0 {
+			err = fmt.Errorf("%w: invalid size of block %d", ErrCorrupted, index)
+			return
+		}
+
+		data := make([]byte, size)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			err = fmt.Errorf("%w: truncated block %d", ErrCorrupted, index)
+			return
+		}
+
+		if !hmac.Equal(sum[:], blockHMAC(hmacKey, index, data)) {
+			err = fmt.Errorf("%w: invalid HMAC of block %d", ErrCorrupted, index)
+			return
+		}
+
+		if size == 0 {
+			cipher = buf.Bytes()
+			return
+		}
+		buf.Write(data)
+	}
+}
+
+func writeBlocks(w io.Writer, hmacKey []byte, cipher []byte) (err error) {
+	index := uint64(0)
+	for {
+		data := cipher[:min(blockSize, len(cipher))]
+		cipher = cipher[len(data):]
+
+		var buf bytes.Buffer
+		buf.Write(blockHMAC(hmacKey, index, data))
+		binary.Write(&buf, binary.LittleEndian, int32(len(data)))
+		buf.Write(data)
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			return
+		}
+
+		// The stream finishes with an empty block
+		if len(data) == 0 {
+			return
+		}
+		index++
+	}
+}
@@ -0,0 +1,255 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Outer header fields
+const (
+	headerEnd              = 0
+	headerCipherID         = 2
+	headerCompressionFlags = 3
+	headerMasterSeed       = 4
+	headerEncryptionIV     = 7
+	headerKdfParameters    = 11
+)
+
+// Inner header fields
+const (
+	innerEnd       = 0
+	innerStreamID  = 1
+	innerStreamKey = 2
+	innerBinary    = 3
+)
+
+const (
+	compressionNone = 0
+	compressionGzip = 1
+)
+
+type header struct {
+	Cipher      []byte
+	Compression uint32
+	MasterSeed  []byte
+	IV          []byte
+	KDF         variantDictionary
+	// Raw bytes of the header, used for the checksum and HMAC
+	Raw []byte
+}
+
+func readField(r io.Reader) (id byte, data []byte, err error) {
+	var idBuf [1]byte
+	_, err = io.ReadFull(r, idBuf[:])
+	if err != nil {
+		return
+	}
+	id = idBuf[0]
+
+	var size uint32
+	err = binary.Read(r, binary.LittleEndian, &size)
+	if err != nil {
+		return
+	}
+	if size > maxFieldSize {
+		err = fmt.Errorf("%w: field %d too big: %d", ErrInvalidHeader, id, size)
+		return
+	}
+
+	data = make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	return
+}
+
+func writeField(w io.Writer, id byte, data []byte) {
+	w.Write([]byte{id})
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+}
+
+func readHeader(r io.Reader) (h *header, err error) {
+	var raw bytes.Buffer
+	tee := io.TeeReader(r, &raw)
+
+	var signature [3]uint32
+	err = binary.Read(tee, binary.LittleEndian, &signature)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		return
+	}
+	if signature[0] != signature1 || signature[1] != signature2 {
+		err = ErrInvalidSignature
+		return
+	}
+	if major := signature[2] >> 16; major != versionMajor {
+		err = fmt.Errorf("%w: %d.%d", ErrUnsupportedVersion, major, signature[2]&0xFFFF)
+		return
+	}
+
+	h = new(header)
+	for {
+		var (
+			id   byte
+			data []byte
+		)
+		id, data, err = readField(tee)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidHeader, err)
+			return
+		}
+
+		switch id {
+		case headerEnd:
+			h.Raw = raw.Bytes()
+			return
+		case headerCipherID:
+			h.Cipher = data
+		case headerCompressionFlags:
+			if len(data) != 4 {
+				err = fmt.Errorf("%w: invalid compression flags", ErrInvalidHeader)
+				return
+			}
+			h.Compression = binary.LittleEndian.Uint32(data)
+		case headerMasterSeed:
+			h.MasterSeed = data
+		case headerEncryptionIV:
+			h.IV = data
+		case headerKdfParameters:
+			h.KDF, err = readVariantDictionary(data)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *header) bytes() (raw []byte) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint32{signature1, signature2, versionMajor<<16 | versionMinor})
+
+	compression := make([]byte, 4)
+	binary.LittleEndian.PutUint32(compression, h.Compression)
+
+	writeField(&buf, headerCipherID, h.Cipher)
+	writeField(&buf, headerCompressionFlags, compression)
+	writeField(&buf, headerMasterSeed, h.MasterSeed)
+	writeField(&buf, headerEncryptionIV, h.IV)
+	writeField(&buf, headerKdfParameters, h.KDF.bytes())
+	writeField(&buf, headerEnd, []byte("\r\n\r\n"))
+
+	return buf.Bytes()
+}
+
+// Variant dictionary value types
+const (
+	variantEnd       = 0x00
+	variantUint32    = 0x04
+	variantUint64    = 0x05
+	variantBool      = 0x08
+	variantInt32     = 0x0C
+	variantInt64     = 0x0D
+	variantString    = 0x18
+	variantByteArray = 0x42
+)
+
+const variantVersion = 0x0100
+
+type variantItem struct {
+	Type  byte
+	Key   string
+	Value []byte
+}
+
+// Ordered list of typed values used by the KDF parameters
+type variantDictionary []variantItem
+
+func readVariantDictionary(data []byte) (vd variantDictionary, err error) {
+	r := bytes.NewReader(data)
+
+	var version uint16
+	err = binary.Read(r, binary.LittleEndian, &version)
+	if err != nil || version>>8 != variantVersion>>8 {
+		err = fmt.Errorf("%w: unsupported variant dictionary version: %x", ErrInvalidHeader, version)
+		return
+	}
+
+	for {
+		var item variantItem
+		item.Type, err = r.ReadByte()
+		if err != nil {
+			err = fmt.Errorf("%w: truncated variant dictionary", ErrInvalidHeader)
+			return
+		}
+		if item.Type == variantEnd {
+			return
+		}
+
+		var key []byte
+		for _, target := range []*[]byte{&key, &item.Value} {
+			var size int32
+			err = binary.Read(r, binary.LittleEndian, &size)
+			if err != nil || size < 0 || int(size) > r.Len() {
+				err = fmt.Errorf("%w: truncated variant dictionary", ErrInvalidHeader)
+				return
+			}
+			*target = make([]byte, size)
+			r.Read(*target)
+		}
+		item.Key = string(key)
+		vd = append(vd, item)
+	}
+}
+
+func (vd variantDictionary) bytes() (data []byte) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(variantVersion))
+	for _, item := range vd {
+		buf.WriteByte(item.Type)
+		binary.Write(&buf, binary.LittleEndian, int32(len(item.Key)))
+		buf.WriteString(item.Key)
+		binary.Write(&buf, binary.LittleEndian, int32(len(item.Value)))
+		buf.Write(item.Value)
+	}
+	buf.WriteByte(variantEnd)
+	return buf.Bytes()
+}
+
+func (vd variantDictionary) get(key string) (value []byte, found bool) {
+	for _, item := range vd {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return
+}
+
+func (vd variantDictionary) uint(key string) (value uint64, found bool) {
+	data, found := vd.get(key)
+	switch len(data) {
+	case 4:
+		value = uint64(binary.LittleEndian.Uint32(data))
+	case 8:
+		value = binary.LittleEndian.Uint64(data)
+	default:
+		found = false
+	}
+	return
+}
+
+func (vd *variantDictionary) setBytes(key string, value []byte) {
+	*vd = append(*vd, variantItem{variantByteArray, key, value})
+}
+
+func (vd *variantDictionary) setUint32(key string, value uint32) {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, value)
+	*vd = append(*vd, variantItem{variantUint32, key, data})
+}
+
+func (vd *variantDictionary) setUint64(key string, value uint64) {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, value)
+	*vd = append(*vd, variantItem{variantUint64, key, data})
+}
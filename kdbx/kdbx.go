@@ -0,0 +1,529 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases using only the
+// standard library and x/crypto
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20"
+	"golang.org/x/crypto/twofish"
+)
+
+const (
+	signature1   = 0x9AA2D903
+	signature2   = 0xB54BFB67
+	versionMajor = 4
+	versionMinor = 0
+	maxFieldSize = 1024 * 1024
+)
+
+// Cipher and KDF identifiers
+var (
+	CipherAES256   = []byte{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	CipherChaCha20 = []byte{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	CipherTwofish  = []byte{0xad, 0x68, 0xf2, 0x9f, 0x57, 0x6f, 0x4b, 0xb9, 0xa3, 0x6a, 0xd4, 0x7a, 0xf9, 0x65, 0x34, 0x6c}
+	KDFAES         = []byte{0xc9, 0xd9, 0xf3, 0x9a, 0x62, 0x8a, 0x44, 0x60, 0xbf, 0x74, 0x0d, 0x08, 0xc1, 0x8a, 0x4f, 0xea}
+	KDFArgon2d     = []byte{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0a, 0x0c}
+	KDFArgon2id    = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+)
+
+// Inner random stream identifiers
+const (
+	streamSalsa20  = 2
+	streamChaCha20 = 3
+)
+
+var (
+	ErrInvalidSignature   = errors.New("not a KDBX file")
+	ErrUnsupportedVersion = errors.New("unsupported KDBX version")
+	ErrUnsupportedCipher  = errors.New("unsupported cipher")
+	ErrUnsupportedKDF     = errors.New("unsupported key derivation function")
+	ErrUnsupportedStream  = errors.New("unsupported inner random stream")
+	ErrInvalidHeader      = errors.New("invalid header")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrCorrupted          = errors.New("corrupted file")
+	ErrInvalidContent     = errors.New("invalid content")
+)
+
+type (
+	Database struct {
+		Name string
+		Root *Group
+	}
+	Group struct {
+		UUID    string
+		Name    string
+		Notes   string
+		Groups  []*Group
+		Entries []*Entry
+	}
+	Entry struct {
+		UUID        string
+		Tags        string
+		Modified    time.Time
+		Strings     []String
+		Attachments []Attachment
+		// Previous versions of the entry, oldest first
+		History []*Entry
+	}
+	String struct {
+		Key       string
+		Value     string
+		Protected bool
+	}
+	Attachment struct {
+		Name string
+		Data []byte
+	}
+)
+
+// Well known entry strings
+const (
+	Title    = "Title"
+	UserName = "UserName"
+	Password = "Password"
+	URL      = "URL"
+	Notes    = "Notes"
+)
+
+// Get returns the value of the string with the given key
+func (e *Entry) Get(key string) (value string) {
+	for _, field := range e.Strings {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return
+}
+
+type Options struct {
+	Cipher []byte
+	KDF    []byte
+	// Argon2 iterations or AES-KDF rounds
+	Iterations uint64
+	// Argon2 memory in bytes
+	Memory      uint64
+	Parallelism uint32
+	Compress    bool
+}
+
+// DefaultOptions mirror the KeePassXC defaults
+func DefaultOptions() Options {
+	return Options{
+		Cipher:      CipherAES256,
+		KDF:         KDFArgon2d,
+		Iterations:  10,
+		Memory:      64 * 1024 * 1024,
+		Parallelism: 2,
+		Compress:    true,
+	}
+}
+
+func payloadCipher(id, key, iv []byte) (block cipher.Block, err error) {
+	switch {
+	case bytes.Equal(id, CipherAES256):
+		block, err = aes.NewCipher(key)
+	case bytes.Equal(id, CipherTwofish):
+		block, err = twofish.NewCipher(key)
+	default:
+		err = fmt.Errorf("%w: %x", ErrUnsupportedCipher, id)
+		return
+	}
+	if err == nil && len(iv) != block.BlockSize() {
+		err = fmt.Errorf("%w: invalid IV size: %d", ErrInvalidHeader, len(iv))
+	}
+	return
+}
+
+func decryptPayload(id, key, iv, data []byte) (plain []byte, err error) {
+	if bytes.Equal(id, CipherChaCha20) {
+		var stream *chacha20.Cipher
+		stream, err = chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidHeader, err)
+			return
+		}
+		plain = make([]byte, len(data))
+		stream.XORKeyStream(plain, data)
+		return
+	}
+
+	block, err := payloadCipher(id, key, iv)
+	if err != nil {
+		return
+	}
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		err = fmt.Errorf("%w: invalid cipher length", ErrCorrupted)
+		return
+	}
+	plain = make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	// PKCS#7
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		err = fmt.Errorf("%w: invalid padding", ErrCorrupted)
+		return
+	}
+	plain = plain[:len(plain)-padding]
+	return
+}
+
+func encryptPayload(id, key, iv, plain []byte) (data []byte, err error) {
+	if bytes.Equal(id, CipherChaCha20) {
+		var stream *chacha20.Cipher
+		stream, err = chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return
+		}
+		data = make([]byte, len(plain))
+		stream.XORKeyStream(data, plain)
+		return
+	}
+
+	block, err := payloadCipher(id, key, iv)
+	if err != nil {
+		return
+	}
+	padding := block.BlockSize() - len(plain)%block.BlockSize()
+	data = make([]byte, len(plain)+padding)
+	copy(data, plain)
+	copy(data[len(plain):], bytes.Repeat([]byte{byte(padding)}, padding))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return
+}
+
+// Keystream used to protect values inside the XML
+func protectedKeystream(id uint32, key []byte, length int) (keystream []byte, err error) {
+	keystream = make([]byte, length)
+	switch id {
+	case streamChaCha20:
+		hash := sha512.Sum512(key)
+		var stream *chacha20.Cipher
+		stream, err = chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:44])
+		if err != nil {
+			return
+		}
+		stream.XORKeyStream(keystream, keystream)
+	case streamSalsa20:
+		hash := sha256.Sum256(key)
+		nonce := []byte{0xE8, 0x30, 0x09, 0x4B, 0x97, 0x20, 0x5D, 0x2A}
+		salsa20.XORKeyStream(keystream, keystream, nonce, &hash)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupportedStream, id)
+	}
+	return
+}
+
+func xorBytes(data, keystream []byte) {
+	for i := range data {
+		data[i] ^= keystream[i]
+	}
+}
+
+// Decode reads a KDBX 4 database
+func Decode(r io.Reader, key Key) (db *Database, err error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return
+	}
+	if len(h.MasterSeed) != 32 {
+		err = fmt.Errorf("%w: invalid master seed", ErrInvalidHeader)
+		return
+	}
+
+	var checksums [2 * sha256.Size]byte
+	_, err = io.ReadFull(r, checksums[:])
+	if err != nil {
+		err = fmt.Errorf("%w: missing header checksums", ErrCorrupted)
+		return
+	}
+	if sum := sha256.Sum256(h.Raw); !hmac.Equal(sum[:], checksums[:sha256.Size]) {
+		err = fmt.Errorf("%w: header checksum mismatch", ErrCorrupted)
+		return
+	}
+
+	// Keys
+	composite, err := key.composite()
+	if err != nil {
+		return
+	}
+	transformed, err := transformKey(composite, h.KDF)
+	if err != nil {
+		return
+	}
+	cipherKey, hmacKey := masterKeys(h.MasterSeed, transformed)
+
+	headerMAC := hmac.New(sha256.New, blockHMACKey(hmacKey, ^uint64(0)))
+	headerMAC.Write(h.Raw)
+	if !hmac.Equal(headerMAC.Sum(nil), checksums[sha256.Size:]) {
+		err = ErrInvalidCredentials
+		return
+	}
+
+	// Payload
+	data, err := readBlocks(r, hmacKey)
+	if err != nil {
+		return
+	}
+	plain, err := decryptPayload(h.Cipher, cipherKey, h.IV, data)
+	if err != nil {
+		return
+	}
+	if h.Compression == compressionGzip {
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(plain))
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+			return
+		}
+		plain, err = io.ReadAll(gz)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorrupted, err)
+			return
+		}
+	}
+
+	// Inner header
+	payload := bytes.NewReader(plain)
+	var (
+		streamID  uint32
+		streamKey []byte
+		binaries  [][]byte
+	)
+	for done := false; !done; {
+		var (
+			id    byte
+			field []byte
+		)
+		id, field, err = readField(payload)
+		if err != nil {
+			err = fmt.Errorf("%w: inner header: %w", ErrCorrupted, err)
+			return
+		}
+		switch id {
+		case innerEnd:
+			done = true
+		case innerStreamID:
+			if len(field) != 4 {
+				err = fmt.Errorf("%w: invalid inner random stream id", ErrCorrupted)
+				return
+			}
+			streamID = binary.LittleEndian.Uint32(field)
+		case innerStreamKey:
+			streamKey = field
+		case innerBinary:
+			if len(field) == 0 {
+				err = fmt.Errorf("%w: empty binary", ErrCorrupted)
+				return
+			}
+			binaries = append(binaries, field[1:])
+		}
+	}
+
+	// XML
+	var root node
+	err = xml.NewDecoder(payload).Decode(&root)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidContent, err)
+		return
+	}
+
+	var protected []*node
+	var protectedLength int
+	err = root.walk(func(n *node) error {
+		if !n.protected() {
+			return nil
+		}
+		raw, err := base64.StdEncoding.DecodeString(n.Content)
+		if err != nil {
+			return fmt.Errorf("%w: invalid protected value: %w", ErrInvalidContent, err)
+		}
+		n.Content = string(raw)
+		protected = append(protected, n)
+		protectedLength += len(raw)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	keystream, err := protectedKeystream(streamID, streamKey, protectedLength)
+	if err != nil && len(protected) > 0 {
+		return
+	}
+	err = nil
+	for _, n := range protected {
+		value := []byte(n.Content)
+		xorBytes(value, keystream)
+		keystream = keystream[len(value):]
+		n.Content = string(value)
+	}
+
+	rootGroup := root.child("Root").child("Group")
+	if rootGroup == nil {
+		err = fmt.Errorf("%w: missing root group", ErrInvalidContent)
+		return
+	}
+
+	db = &Database{
+		Name: root.child("Meta").text("DatabaseName"),
+		Root: new(Group),
+	}
+	err = db.Root.fromNode(rootGroup, binaries)
+	return
+}
+
+// Encode writes the database in the KDBX 4 format
+func Encode(w io.Writer, db *Database, key Key, options Options) (err error) {
+	h := &header{
+		Cipher:     options.Cipher,
+		MasterSeed: make([]byte, 32),
+	}
+	rand.Read(h.MasterSeed)
+	if options.Compress {
+		h.Compression = compressionGzip
+	}
+
+	if bytes.Equal(options.Cipher, CipherChaCha20) {
+		h.IV = make([]byte, chacha20.NonceSize)
+	} else {
+		h.IV = make([]byte, aes.BlockSize)
+	}
+	rand.Read(h.IV)
+
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	h.KDF.setBytes("$UUID", options.KDF)
+	switch {
+	case bytes.Equal(options.KDF, KDFAES):
+		h.KDF.setUint64("R", options.Iterations)
+		h.KDF.setBytes("S", salt)
+	case bytes.Equal(options.KDF, KDFArgon2d), bytes.Equal(options.KDF, KDFArgon2id):
+		h.KDF.setUint32("V", argon2Version)
+		h.KDF.setBytes("S", salt)
+		h.KDF.setUint64("I", options.Iterations)
+		h.KDF.setUint64("M", options.Memory)
+		h.KDF.setUint32("P", options.Parallelism)
+	default:
+		err = fmt.Errorf("%w: %x", ErrUnsupportedKDF, options.KDF)
+		return
+	}
+	h.Raw = h.bytes()
+
+	// Keys
+	composite, err := key.composite()
+	if err != nil {
+		return
+	}
+	transformed, err := transformKey(composite, h.KDF)
+	if err != nil {
+		return
+	}
+	cipherKey, hmacKey := masterKeys(h.MasterSeed, transformed)
+
+	// XML
+	var binaries [][]byte
+	group := db.Root
+	if group == nil {
+		group = &Group{Name: db.Name}
+	}
+	root := newNode("KeePassFile", "",
+		newNode("Meta", "",
+			newNode("Generator", "guardian"),
+			newNode("DatabaseName", db.Name),
+		),
+		newNode("Root", "", group.toNode(&binaries)),
+	)
+
+	streamKey := make([]byte, 64)
+	rand.Read(streamKey)
+	var protected []*node
+	var protectedLength int
+	root.walk(func(n *node) error {
+		if n.protected() {
+			protected = append(protected, n)
+			protectedLength += len(n.Content)
+		}
+		return nil
+	})
+	keystream, _ := protectedKeystream(streamChaCha20, streamKey, protectedLength)
+	for _, n := range protected {
+		value := []byte(n.Content)
+		xorBytes(value, keystream)
+		keystream = keystream[len(value):]
+		n.Content = base64.StdEncoding.EncodeToString(value)
+	}
+
+	// Inner header and XML
+	var plain bytes.Buffer
+	streamID := make([]byte, 4)
+	binary.LittleEndian.PutUint32(streamID, streamChaCha20)
+	writeField(&plain, innerStreamID, streamID)
+	writeField(&plain, innerStreamKey, streamKey)
+	for _, data := range binaries {
+		writeField(&plain, innerBinary, append([]byte{0}, data...))
+	}
+	writeField(&plain, innerEnd, nil)
+	plain.WriteString(xml.Header)
+	err = xml.NewEncoder(&plain).Encode(root)
+	if err != nil {
+		err = fmt.Errorf("failed to encode XML: %w", err)
+		return
+	}
+
+	payload := plain.Bytes()
+	if options.Compress {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(payload)
+		gz.Close()
+		payload = compressed.Bytes()
+	}
+
+	data, err := encryptPayload(h.Cipher, cipherKey, h.IV, payload)
+	if err != nil {
+		return
+	}
+
+	// Output
+	headerSum := sha256.Sum256(h.Raw)
+	headerMAC := hmac.New(sha256.New, blockHMACKey(hmacKey, ^uint64(0)))
+	headerMAC.Write(h.Raw)
+
+	var out bytes.Buffer
+	out.Write(h.Raw)
+	out.Write(headerSum[:])
+	out.Write(headerMAC.Sum(nil))
+	err = writeBlocks(&out, hmacKey, data)
+	if err != nil {
+		return
+	}
+
+	_, err = w.Write(out.Bytes())
+	return
+}
+
+func uuidOrRandom(uuid string) string {
+	if uuid != "" {
+		return uuid
+	}
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return base64.StdEncoding.EncodeToString(raw)
+}
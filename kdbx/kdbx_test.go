@@ -0,0 +1,206 @@
+package kdbx_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RogueTeam/guardian/internal/testsuite"
+	"github.com/RogueTeam/guardian/kdbx"
+)
+
+func uuid() string {
+	return base64.StdEncoding.EncodeToString(testsuite.Random(16))
+}
+
+// Fixture covering nested groups, protected values, attachments and history
+func fixture() *kdbx.Database {
+	modified := time.Date(2023, 12, 24, 10, 30, 0, 0, time.UTC)
+	attachment := testsuite.Random(512)
+
+	return &kdbx.Database{
+		Name: "Passwords",
+		Root: &kdbx.Group{
+			UUID: uuid(),
+			Name: "Root",
+			Entries: []*kdbx.Entry{
+				{
+					UUID:     uuid(),
+					Modified: modified,
+					Strings: []kdbx.String{
+						{Key: kdbx.Title, Value: "example.com"},
+						{Key: kdbx.UserName, Value: "sulcud"},
+						{Key: kdbx.Password, Value: "password", Protected: true},
+					},
+				},
+			},
+			Groups: []*kdbx.Group{
+				{
+					UUID:  uuid(),
+					Name:  "Work",
+					Notes: "work accounts",
+					Entries: []*kdbx.Entry{
+						{
+							UUID:     uuid(),
+							Tags:     "vpn;infra",
+							Modified: modified,
+							Strings: []kdbx.String{
+								{Key: kdbx.Title, Value: "vpn"},
+								{Key: kdbx.Password, Value: "current", Protected: true},
+								{Key: "PIN", Value: "1234", Protected: true},
+								{Key: kdbx.Notes, Value: "multi\nline"},
+							},
+							Attachments: []kdbx.Attachment{{Name: "config.ovpn", Data: attachment}},
+							History: []*kdbx.Entry{
+								{
+									UUID:     uuid(),
+									Modified: modified.Add(-time.Hour),
+									Strings: []kdbx.String{
+										{Key: kdbx.Title, Value: "vpn"},
+										{Key: kdbx.Password, Value: "old", Protected: true},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestKDBX(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Succeed", func(t *testing.T) {
+		t.Parallel()
+
+		type Test struct {
+			Name string
+			Key  kdbx.Key
+			kdbx.Options
+		}
+
+		password := kdbx.Key{Password: []byte("password")}
+		tests := []Test{
+			{"AES Argon2d gzip", password, kdbx.Options{kdbx.CipherAES256, kdbx.KDFArgon2d, 2, 64 * 1024, 2, true}},
+			{"ChaCha20 Argon2id", password, kdbx.Options{kdbx.CipherChaCha20, kdbx.KDFArgon2id, 2, 64 * 1024, 1, false}},
+			{"Twofish AES-KDF", password, kdbx.Options{kdbx.CipherTwofish, kdbx.KDFAES, 1000, 0, 0, true}},
+			{"Password and keyfile", kdbx.Key{Password: []byte("password"), Keyfile: testsuite.Random(128)}, kdbx.Options{kdbx.CipherAES256, kdbx.KDFAES, 10, 0, 0, true}},
+			{"Hex keyfile only", kdbx.Key{Keyfile: []byte("00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff")}, kdbx.Options{kdbx.CipherAES256, kdbx.KDFAES, 10, 0, 0, true}},
+			{"XML keyfile", kdbx.Key{Password: []byte("password"), Keyfile: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="A65E2B2E">
+	0011 2233 4455 6677 8899 AABB CCDD EEFF
+	0011 2233 4455 6677 8899 AABB CCDD EEFF
+</Data></Key></KeyFile>`)}, kdbx.Options{kdbx.CipherChaCha20, kdbx.KDFAES, 10, 0, 0, false}},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				expect := fixture()
+
+				var file bytes.Buffer
+				err := kdbx.Encode(&file, expect, test.Key, test.Options)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				obtained, err := kdbx.Decode(bytes.NewReader(file.Bytes()), test.Key)
+				if err != nil {
+					t.Fatalf("expecting no errors, but received: %v", err)
+				}
+
+				if !reflect.DeepEqual(expect, obtained) {
+					t.Fatalf("expecting %+v but received: %+v", expect, obtained)
+				}
+			})
+		}
+	})
+	t.Run("Fail", func(t *testing.T) {
+		t.Parallel()
+
+		key := kdbx.Key{Password: []byte("password")}
+		options := kdbx.Options{kdbx.CipherAES256, kdbx.KDFArgon2d, 1, 64 * 1024, 1, true}
+
+		var file bytes.Buffer
+		err := kdbx.Encode(&file, fixture(), key, options)
+		if err != nil {
+			t.Fatalf("expecting no errors, but received: %v", err)
+		}
+		valid := file.Bytes()
+
+		type Test struct {
+			Name   string
+			Data   func() []byte
+			Key    kdbx.Key
+			Expect error
+		}
+
+		tests := []Test{
+			{
+				Name:   "Invalid signature",
+				Data:   func() []byte { return []byte("not a keepass database") },
+				Key:    key,
+				Expect: kdbx.ErrInvalidSignature,
+			},
+			{
+				Name: "Unsupported version",
+				Data: func() []byte {
+					data := bytes.Clone(valid)
+					data[10] = 3
+					return data
+				},
+				Key:    key,
+				Expect: kdbx.ErrUnsupportedVersion,
+			},
+			{
+				Name:   "Wrong password",
+				Data:   func() []byte { return valid },
+				Key:    kdbx.Key{Password: []byte("invalid")},
+				Expect: kdbx.ErrInvalidCredentials,
+			},
+			{
+				Name:   "Missing keyfile",
+				Data:   func() []byte { return valid },
+				Key:    kdbx.Key{Password: []byte("password"), Keyfile: []byte("keyfile")},
+				Expect: kdbx.ErrInvalidCredentials,
+			},
+			{
+				Name: "Corrupted block",
+				Data: func() []byte {
+					data := bytes.Clone(valid)
+					data[len(data)-64] ^= 0xff
+					return data
+				},
+				Key:    key,
+				Expect: kdbx.ErrCorrupted,
+			},
+			{
+				Name: "Truncated",
+				Data: func() []byte {
+					return valid[:len(valid)-16]
+				},
+				Key:    key,
+				Expect: kdbx.ErrCorrupted,
+			},
+		}
+
+		for _, test := range tests {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := kdbx.Decode(bytes.NewReader(test.Data()), test.Key)
+				if !errors.Is(err, test.Expect) {
+					t.Fatalf("expecting %v but received: %v", test.Expect, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Key holds the credentials protecting the database
+type Key struct {
+	Password []byte
+	// Contents of the key file, if any
+	Keyfile []byte
+}
+
+func keyfileHash(data []byte) (hash []byte, err error) {
+	// XML key files, version 1.0 stores base64 and 2.0 hex
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<?xml")) {
+		var file struct {
+			Meta struct {
+				Version string `xml:"Version"`
+			} `xml:"Meta"`
+			Key struct {
+				Data string `xml:"Data"`
+			} `xml:"Key"`
+		}
+		err = xml.Unmarshal(data, &file)
+		if err != nil {
+			err = fmt.Errorf("failed to decode XML key file: %w", err)
+			return
+		}
+
+		keyData := strings.Join(strings.Fields(file.Key.Data), "")
+		if strings.HasPrefix(file.Meta.Version, "2.") {
+			hash, err = hex.DecodeString(keyData)
+		} else {
+			hash, err = base64.StdEncoding.DecodeString(keyData)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to decode XML key file data: %w", err)
+		}
+		return
+	}
+
+	switch len(data) {
+	case 32:
+		hash = data
+		return
+	case 64:
+		hash, err = hex.DecodeString(string(data))
+		if err == nil {
+			return
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	hash, err = sum[:], nil
+	return
+}
+
+// Composite key as described by KeePass: SHA256(SHA256(password) || keyfile)
+func (k Key) composite() (key []byte, err error) {
+	composite := sha256.New()
+	if k.Password != nil {
+		passwordHash := sha256.Sum256(k.Password)
+		composite.Write(passwordHash[:])
+	}
+	if len(k.Keyfile) > 0 {
+		var hash []byte
+		hash, err = keyfileHash(k.Keyfile)
+		if err != nil {
+			return
+		}
+		composite.Write(hash)
+	}
+
+	key = composite.Sum(nil)
+	return
+}
+
+func transformKey(composite []byte, kdf variantDictionary) (transformed []byte, err error) {
+	uuid, _ := kdf.get("$UUID")
+	salt, _ := kdf.get("S")
+
+	switch {
+	case bytes.Equal(uuid, KDFAES):
+		rounds, found := kdf.uint("R")
+		if !found || len(salt) != 32 {
+			err = fmt.Errorf("%w: invalid AES-KDF parameters", ErrInvalidHeader)
+			return
+		}
+
+		block, _ := aes.NewCipher(salt)
+		key := make([]byte, len(composite))
+		copy(key, composite)
+		for round := uint64(0); round < rounds; round++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+		sum := sha256.Sum256(key)
+		transformed = sum[:]
+	case bytes.Equal(uuid, KDFArgon2d), bytes.Equal(uuid, KDFArgon2id):
+		iterations, okI := kdf.uint("I")
+		memory, okM := kdf.uint("M")
+		parallelism, okP := kdf.uint("P")
+		if !okI || !okM || !okP || iterations == 0 || parallelism == 0 || memory < 1024 || len(salt) == 0 {
+			err = fmt.Errorf("%w: invalid argon2 parameters", ErrInvalidHeader)
+			return
+		}
+		if version, found := kdf.uint("V"); found && version != argon2Version {
+			err = fmt.Errorf("%w: unsupported argon2 version: %x", ErrUnsupportedKDF, version)
+			return
+		}
+
+		mode := argon2d
+		if bytes.Equal(uuid, KDFArgon2id) {
+			mode = argon2id
+		}
+		secret, _ := kdf.get("K")
+		data, _ := kdf.get("A")
+		transformed = argon2Key(mode, composite, salt, secret, data, uint32(iterations), uint32(memory/1024), uint32(parallelism), 32)
+	default:
+		err = fmt.Errorf("%w: %x", ErrUnsupportedKDF, uuid)
+	}
+	return
+}
+
+// Returns the payload encryption key and the base HMAC key
+func masterKeys(masterSeed, transformed []byte) (cipherKey, hmacKey []byte) {
+	cipherHash := sha256.New()
+	cipherHash.Write(masterSeed)
+	cipherHash.Write(transformed)
+	cipherKey = cipherHash.Sum(nil)
+
+	hmacHash := sha512.New()
+	hmacHash.Write(masterSeed)
+	hmacHash.Write(transformed)
+	hmacHash.Write([]byte{0x01})
+	hmacKey = hmacHash.Sum(nil)
+	return
+}
+
+func blockHMACKey(hmacKey []byte, index uint64) (key []byte) {
+	var indexBuf [8]byte
+	binary.LittleEndian.PutUint64(indexBuf[:], index)
+
+	hash := sha512.New()
+	hash.Write(indexBuf[:])
+	hash.Write(hmacKey)
+	return hash.Sum(nil)
+}
@@ -0,0 +1,214 @@
+package kdbx
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generic XML tree, preserving the document order needed by the protected stream
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",chardata"`
+	Nodes   []*node    `xml:",any"`
+}
+
+func newNode(name string, content string, children ...*node) *node {
+	return &node{XMLName: xml.Name{Local: name}, Content: content, Nodes: children}
+}
+
+func (n *node) child(name string) *node {
+	if n == nil {
+		return nil
+	}
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *node) text(name string) string {
+	child := n.child(name)
+	if child == nil {
+		return ""
+	}
+	return child.Content
+}
+
+func (n *node) attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Walks the tree in document order
+func (n *node) walk(fn func(n *node) error) (err error) {
+	err = fn(n)
+	if err != nil {
+		return
+	}
+	for _, child := range n.Nodes {
+		err = child.walk(fn)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (n *node) protected() bool {
+	return n.XMLName.Local == "Value" && strings.EqualFold(n.attr("Protected"), "True")
+}
+
+// KDBX 4 stores times as base64 encoded seconds since year 1
+var epoch = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func parseTime(s string) (t time.Time) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err == nil && len(raw) == 8 {
+		seconds := int64(binary.LittleEndian.Uint64(raw))
+		return time.Unix(epoch.Unix()+seconds, 0).UTC()
+	}
+	t, _ = time.Parse(time.RFC3339, s)
+	return t
+}
+
+func formatTime(t time.Time) string {
+	seconds := t.Unix() - epoch.Unix()
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, uint64(seconds))
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func (e *Entry) fromNode(n *node, binaries [][]byte) (err error) {
+	e.UUID = n.text("UUID")
+	e.Tags = n.text("Tags")
+	if times := n.child("Times"); times != nil {
+		e.Modified = parseTime(times.text("LastModificationTime"))
+	}
+
+	for _, child := range n.Nodes {
+		switch child.XMLName.Local {
+		case "String":
+			value := child.child("Value")
+			field := String{Key: child.text("Key")}
+			if value != nil {
+				field.Value = value.Content
+				field.Protected = value.protected()
+			}
+			e.Strings = append(e.Strings, field)
+		case "Binary":
+			value := child.child("Value")
+			if value == nil {
+				continue
+			}
+			var ref int
+			ref, err = strconv.Atoi(value.attr("Ref"))
+			if err != nil || ref < 0 || ref >= len(binaries) {
+				err = fmt.Errorf("%w: invalid binary reference: %s", ErrInvalidContent, value.attr("Ref"))
+				return
+			}
+			e.Attachments = append(e.Attachments, Attachment{Name: child.text("Key"), Data: binaries[ref]})
+		case "History":
+			for _, version := range child.Nodes {
+				if version.XMLName.Local != "Entry" {
+					continue
+				}
+				old := new(Entry)
+				err = old.fromNode(version, binaries)
+				if err != nil {
+					return
+				}
+				e.History = append(e.History, old)
+			}
+		}
+	}
+	return
+}
+
+func (g *Group) fromNode(n *node, binaries [][]byte) (err error) {
+	g.UUID = n.text("UUID")
+	g.Name = n.text("Name")
+	g.Notes = n.text("Notes")
+
+	for _, child := range n.Nodes {
+		switch child.XMLName.Local {
+		case "Entry":
+			entry := new(Entry)
+			err = entry.fromNode(child, binaries)
+			if err != nil {
+				return
+			}
+			g.Entries = append(g.Entries, entry)
+		case "Group":
+			group := new(Group)
+			err = group.fromNode(child, binaries)
+			if err != nil {
+				return
+			}
+			g.Groups = append(g.Groups, group)
+		}
+	}
+	return
+}
+
+func (e *Entry) toNode(binaries *[][]byte) (n *node) {
+	n = newNode("Entry", "",
+		newNode("UUID", uuidOrRandom(e.UUID)),
+		newNode("Tags", e.Tags),
+		newNode("Times", "",
+			newNode("LastModificationTime", formatTime(e.Modified)),
+			newNode("CreationTime", formatTime(e.Modified)),
+			newNode("LastAccessTime", formatTime(e.Modified)),
+		),
+	)
+
+	for _, field := range e.Strings {
+		value := newNode("Value", field.Value)
+		if field.Protected {
+			value.Attrs = []xml.Attr{{Name: xml.Name{Local: "Protected"}, Value: "True"}}
+		}
+		n.Nodes = append(n.Nodes, newNode("String", "", newNode("Key", field.Key), value))
+	}
+
+	for _, attachment := range e.Attachments {
+		value := newNode("Value", "")
+		value.Attrs = []xml.Attr{{Name: xml.Name{Local: "Ref"}, Value: strconv.Itoa(len(*binaries))}}
+		*binaries = append(*binaries, attachment.Data)
+		n.Nodes = append(n.Nodes, newNode("Binary", "", newNode("Key", attachment.Name), value))
+	}
+
+	if len(e.History) > 0 {
+		history := newNode("History", "")
+		for _, old := range e.History {
+			history.Nodes = append(history.Nodes, old.toNode(binaries))
+		}
+		n.Nodes = append(n.Nodes, history)
+	}
+	return n
+}
+
+func (g *Group) toNode(binaries *[][]byte) (n *node) {
+	n = newNode("Group", "",
+		newNode("UUID", uuidOrRandom(g.UUID)),
+		newNode("Name", g.Name),
+		newNode("Notes", g.Notes),
+	)
+	for _, entry := range g.Entries {
+		n.Nodes = append(n.Nodes, entry.toNode(binaries))
+	}
+	for _, group := range g.Groups {
+		n.Nodes = append(n.Nodes, group.toNode(binaries))
+	}
+	return n
+}
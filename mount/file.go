@@ -29,12 +29,13 @@ func (f *File) Attr(ctx context.Context, atr *fuse.Attr) (err error) {
 	atr.Uid = uint32(os.Getuid())
 	atr.Gid = uint32(os.Getgid())
 	atr.Mode = 0o600
-	sData, err := f.Database.Get(f.Name)
+	// The size is indexed, listing a directory doesn't decrypt the entries
+	size, err := f.Database.Size(f.Name)
 	if err != nil {
 		err = fmt.Errorf("failed to read secret: %w", err)
 		return
 	}
-	atr.Size = uint64(len(sData))
+	atr.Size = uint64(size)
 	return
 }
 
@@ -51,11 +52,6 @@ func (f *File) ReadAll(ctx context.Context) (data []byte, err error) {
 
 func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (h fs.Handle, err error) {
 	sData, _ := f.Database.Get(f.Name)
-	h = &Handle{
-		Name:     f.Name,
-		File:     f.File,
-		Buffer:   []byte(sData),
-		Database: f.Database,
-	}
+	h = newHandle(f.Name, sData, f.File, f.Database)
 	return
 }
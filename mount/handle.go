@@ -1,6 +1,7 @@
 package mount
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/internal/secure"
 )
 
 type Handle struct {
@@ -17,6 +19,16 @@ type Handle struct {
 	Buffer   []byte
 	File     IO
 	Database *database.Database
+	// Locked memory backing Buffer
+	plain *secure.Buffer
+}
+
+func newHandle(name string, contents string, file IO, db *database.Database) (h *Handle) {
+	h = &Handle{Name: name, File: file, Database: db}
+	h.plain = secure.New(len(contents))
+	h.Buffer = h.plain.Bytes()
+	copy(h.Buffer, contents)
+	return
 }
 
 var (
@@ -30,11 +42,16 @@ func (h *Handle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.W
 	log.Println("Writing")
 	grow := req.Offset + int64(len(req.Data))
 	if int64(len(h.Buffer)) < grow {
-		newBuffer := make([]byte, grow)
-		copy(newBuffer, h.Buffer)
-		h.Buffer = newBuffer
+		plain := secure.New(int(grow))
+		copy(plain.Bytes(), h.Buffer)
+		if h.plain != nil {
+			h.plain.Destroy()
+		}
+		h.plain = plain
+		h.Buffer = plain.Bytes()
 	}
 	resp.Size = copy(h.Buffer[req.Offset:], req.Data)
+	// The database keeps its entries as heap strings, Buffer is the only locked copy
 	h.Database.Set(h.Name, string(h.Buffer))
 	return
 }
@@ -55,21 +72,46 @@ func (h *Handle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.Rea
 func (h *Handle) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error) {
 	log.Println("Releasing")
 	h.Database.Set(h.Name, string(h.Buffer))
+	if h.plain != nil {
+		h.plain.Destroy()
+		h.plain = nil
+		h.Buffer = nil
+	}
 	if h.File == nil {
 		return
 	}
 
 	log.Println("Saving changes")
-	h.File.Seek(0, 0)
-	err = h.Database.Save(h.File)
+	if file, ok := h.File.(*database.File); ok {
+		// Readers of other processes are waited for, the file is only replaced once saved
+		err = file.Save(h.Database)
+	} else {
+		err = save(h.File, h.Database)
+	}
 	if err != nil {
 		err = fmt.Errorf("failed to save changes in DB: %w", err)
 		return
 	}
-	err = h.File.Sync()
+	return
+}
+
+// save encodes the database before overwriting the file, a failed encoding leaves it untouched
+func save(file IO, db *database.Database) (err error) {
+	var buffer bytes.Buffer
+	err = db.Save(&buffer)
 	if err != nil {
-		err = fmt.Errorf("failed to sync changes: %w", err)
 		return
 	}
+
+	_, err = file.Seek(0, 0)
+	if err == nil {
+		err = file.Truncate(0)
+	}
+	if err == nil {
+		_, err = file.Write(buffer.Bytes())
+	}
+	if err == nil {
+		err = file.Sync()
+	}
 	return
 }
@@ -0,0 +1,88 @@
+package mount_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/RogueTeam/guardian/crypto"
+	"github.com/RogueTeam/guardian/database"
+	"github.com/RogueTeam/guardian/mount"
+)
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+
+	db := database.New()
+	db.Set("secret", "value")
+
+	file := &mount.File{Name: "secret", Database: db}
+	node, err := file.Open(context.Background(), &fuse.OpenRequest{}, &fuse.OpenResponse{})
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	h := node.(*mount.Handle)
+
+	// Writing past the end grows the locked buffer
+	err = h.Write(context.Background(), &fuse.WriteRequest{Offset: 5, Data: []byte(" updated")}, &fuse.WriteResponse{})
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+
+	var read fuse.ReadResponse
+	err = h.Read(context.Background(), &fuse.ReadRequest{Size: 64}, &read)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if string(read.Data) != "value updated" {
+		t.Fatalf("expecting value updated but received: %s", read.Data)
+	}
+
+	err = h.Release(context.Background(), &fuse.ReleaseRequest{})
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if secret, _ := db.Get("secret"); secret != "value updated" {
+		t.Fatalf("expecting value updated but received: %s", secret)
+	}
+	if h.Buffer != nil {
+		t.Fatal("expecting buffer to be released")
+	}
+}
+
+func TestHandle_Release(t *testing.T) {
+	t.Parallel()
+
+	filepath := path.Join(t.TempDir(), "guardian.json")
+	err := os.WriteFile(filepath, []byte("previous"), 0o600)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	file, err := os.OpenFile(filepath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	defer file.Close()
+
+	// The recipient can't be parsed, saving fails before writing
+	db := database.New()
+	db.Key = []byte("password")
+	db.Argon = crypto.Argon{Time: 1, Memory: 64, Threads: 1}
+	db.SaltSize = 16
+	db.Recipients = []string{"invalid"}
+
+	h := &mount.Handle{Name: "secret", File: file, Database: db}
+	err = h.Release(context.Background(), &fuse.ReleaseRequest{})
+	if err == nil {
+		t.Fatal("expecting error")
+	}
+	contents, err := os.ReadFile(filepath)
+	if err != nil {
+		t.Fatalf("expecting no errors, but received: %v", err)
+	}
+	if string(contents) != "previous" {
+		t.Fatalf("expecting previous but received: %s", contents)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 type IO interface {
 	io.WriteSeeker
+	Truncate(size int64) (err error)
 	Sync() (err error)
 }
 